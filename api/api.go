@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -15,9 +16,13 @@ import (
 	"github.com/SaidovZohid/certalert.info/api/handlers"
 	h "github.com/SaidovZohid/certalert.info/api/handlers"
 	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/email"
 	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/pkg/sms"
 	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/pkg/utils"
 	"github.com/SaidovZohid/certalert.info/storage"
+	"github.com/SaidovZohid/certalert.info/storage/models"
 )
 
 type RoutetOptions struct {
@@ -25,6 +30,10 @@ type RoutetOptions struct {
 	Log      logger.Logger
 	Strg     storage.StorageI
 	InMemory storage.InMemoryStorageI
+	Mailer   email.Mailer
+	SMS      sms.Provider // nil when SMS is off
+	// background work of the handlers, like sending emails, that shutdown waits for
+	Pending *sync.WaitGroup
 }
 
 func New(opt *RoutetOptions) *fiber.App {
@@ -99,6 +108,8 @@ func New(opt *RoutetOptions) *fiber.App {
 			return fmt.Sprintf(`<td class="px-4 py-2 font-bold text-green-600 domain-status">%v</td>`, ssl.StatusHealthy)
 		case ssl.StatusInvalid:
 			return fmt.Sprintf(`<td class="px-4 py-2 font-bold text-yellow-600 domain-status">%v</td>`, ssl.StatusInvalid)
+		case ssl.StatusRevoked:
+			return fmt.Sprintf(`<td class="px-4 py-2 font-bold text-red-600 domain-status">%v</td>`, ssl.StatusRevoked)
 		case ssl.StatusOffline:
 			return fmt.Sprintf(`<td class="px-4 py-2 font-bold text-gray-400 domain-status">%v</td>`, ssl.StatusOffline)
 		case ssl.StatusUnResponsive:
@@ -119,6 +130,8 @@ func New(opt *RoutetOptions) *fiber.App {
 			return ssl.StatusExpired
 		} else if *domainName == ssl.StatusInvalid {
 			return ssl.StatusInvalid
+		} else if *domainName == ssl.StatusRevoked {
+			return ssl.StatusRevoked
 		} else if *domainName == ssl.StatusOffline {
 			return ssl.StatusOffline
 		} else if *domainName == ssl.StatusUnResponsive {
@@ -152,6 +165,19 @@ func New(opt *RoutetOptions) *fiber.App {
 		return strings.Split(timeFormatted, "+")[0]
 	})
 
+	engine.AddFunc("timeInZone", func(tm interface{}, timeZone string) string {
+		t, ok := tm.(*time.Time)
+		if !ok || t == nil {
+			return "unavailable"
+		}
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			loc = time.UTC
+		}
+		timeFormatted := t.In(loc).Format(time.RFC1123)
+		return strings.Split(timeFormatted, "+")[0]
+	})
+
 	engine.AddFunc("ipAddress", func(ip interface{}) string {
 		if ip == nil {
 			return "unavailable"
@@ -173,6 +199,52 @@ func New(opt *RoutetOptions) *fiber.App {
 		return *extKeyUsages.(*string)
 	})
 
+	engine.AddFunc("fieldTitle", func(field string) string {
+		return ssl.FieldTitle(field)
+	})
+
+	engine.AddFunc("alertTypeTitle", func(tp string) string {
+		return utils.AlertTypeTitle(tp)
+	})
+
+	engine.AddFunc("problemTitle", func(problem string) string {
+		return utils.ProblemTitle(problem)
+	})
+
+	engine.AddFunc("integrationTitle", func(kind string) string {
+		return utils.IntegrationTitle(kind)
+	})
+
+	engine.AddFunc("webhookEventType", func(tp string) string {
+		return utils.WebhookEventType(tp)
+	})
+
+	engine.AddFunc("severityLevels", func(kind string) []string {
+		return utils.IntegrationSeverityLevels(kind)
+	})
+
+	engine.AddFunc("integrationSeverity", func(integration *models.Integration, severity string) string {
+		return utils.IntegrationSeverity(integration, severity)
+	})
+
+	engine.AddFunc("titleTemplate", func(integration *models.Integration) string {
+		title, _ := utils.ChannelTemplates(integration)
+		return title
+	})
+
+	engine.AddFunc("messageTemplate", func(integration *models.Integration) string {
+		_, message := utils.ChannelTemplates(integration)
+		return message
+	})
+
+	engine.AddFunc("integrationName", func(id *int64, integrations []*models.Integration) string {
+		return utils.IntegrationName(id, integrations)
+	})
+
+	engine.AddFunc("routingDestinations", func(rule *models.RoutingRule, integrations []*models.Integration) string {
+		return strings.Join(utils.RoutingDestinations(rule, integrations), ", ")
+	})
+
 	app.Static("/static", "./static")
 
 	handlers := h.New(&h.HandlerV1Options{
@@ -182,6 +254,9 @@ func New(opt *RoutetOptions) *fiber.App {
 		InMemory:              opt.InMemory,
 		Tokens:                make(map[string]handlers.TokenDataValidAndToken, 0),
 		ForgotPasswordUserReq: make(map[string]string, 0),
+		Pending:               opt.Pending,
+		Mailer:                opt.Mailer,
+		SMS:                   opt.SMS,
 	})
 	app.Get("/", handlers.HandleGetLandingPage)
 	app.Get("/test", func(c *fiber.Ctx) error {
@@ -219,6 +294,51 @@ func New(opt *RoutetOptions) *fiber.App {
 	// app.Get("/domains/check", handlers.AuthMiddleware, handlers.HandleCheckDomains)
 	app.Get("/domains/more/:id", handlers.AuthMiddleware, handlers.HandleDomainInfoShowPage)
 	app.Get("/domains/pem/:id", handlers.AuthMiddleware, handlers.HandleShowEncodedPEM)
+	app.Get("/domains/changes/:id", handlers.AuthMiddleware, handlers.HandleDomainChangesPage)
+	app.Post("/domains/schedule/:id", handlers.AuthMiddleware, handlers.HandleUpdateDomainSchedule)
+	app.Post("/domains/reminders/:id", handlers.AuthMiddleware, handlers.HandleUpdateDomainReminderDays)
+	app.Post("/domains/tags/:id", handlers.AuthMiddleware, handlers.HandleUpdateDomainTags)
+	app.Post("/domains/snooze/:id", handlers.AuthMiddleware, handlers.HandleUpdateDomainSnooze)
+
+	app.Get("/notifications", handlers.AuthMiddleware, handlers.HandleNotificationsPage)
+	app.Post("/notifications/email", handlers.AuthMiddleware, handlers.HandleUpdateEmailAlert)
+	app.Post("/notifications/change-fields", handlers.AuthMiddleware, handlers.HandleUpdateChangeAlertFields)
+	app.Post("/notifications/reminders", handlers.AuthMiddleware, handlers.HandleUpdateReminderDays)
+	app.Post("/notifications/quiet-hours", handlers.AuthMiddleware, handlers.HandleUpdateQuietHours)
+	app.Post("/notifications/alerts/:id/retry", handlers.AuthMiddleware, handlers.HandleRetryDeadAlert)
+	app.Get("/unsubscribe/:token", handlers.HandleUnsubscribePage)
+	app.Post("/unsubscribe/:token", handlers.HandleUnsubscribe)
+	app.Get("/incidents", handlers.AuthMiddleware, handlers.HandleIncidentsPage)
+	app.Post("/incidents/:id/ack", handlers.AuthMiddleware, handlers.HandleAcknowledgeIncident)
+	app.Post("/incidents/:id/snooze", handlers.AuthMiddleware, handlers.HandleSnoozeIncident)
+	app.Post("/incidents/:id/resolve", handlers.AuthMiddleware, handlers.HandleResolveIncident)
+	app.Get("/incidents/ack/:token", handlers.HandleAcknowledgeIncidentByTokenPage)
+	app.Post("/incidents/ack/:token", handlers.HandleAcknowledgeIncidentByToken)
+	app.Get("/escalations", handlers.AuthMiddleware, handlers.HandleEscalationsPage)
+	app.Post("/escalations", handlers.AuthMiddleware, handlers.HandleCreateEscalationPolicy)
+	app.Post("/escalations/:id/delete", handlers.AuthMiddleware, handlers.HandleDeleteEscalationPolicy)
+	app.Post("/escalations/:id/steps", handlers.AuthMiddleware, handlers.HandleAddEscalationStep)
+	app.Post("/escalations/steps/:id/delete", handlers.AuthMiddleware, handlers.HandleDeleteEscalationStep)
+	app.Get("/routing", handlers.AuthMiddleware, handlers.HandleRoutingPage)
+	app.Post("/routing", handlers.AuthMiddleware, handlers.HandleCreateRoutingRule)
+	app.Post("/routing/:id/move", handlers.AuthMiddleware, handlers.HandleMoveRoutingRule)
+	app.Post("/routing/:id/delete", handlers.AuthMiddleware, handlers.HandleDeleteRoutingRule)
+	app.Get("/maintenance", handlers.AuthMiddleware, handlers.HandleMaintenancePage)
+	app.Post("/maintenance", handlers.AuthMiddleware, handlers.HandleCreateMaintenanceWindow)
+	app.Post("/maintenance/:id/delete", handlers.AuthMiddleware, handlers.HandleDeleteMaintenanceWindow)
+	app.Get("/integrations", handlers.AuthMiddleware, handlers.HandleIntegrationsPage)
+	app.Get("/integrations/slack/install", handlers.AuthMiddleware, handlers.HandleSlackInstall)
+	app.Get("/integrations/slack/callback", handlers.AuthMiddleware, handlers.HandleSlackCallback)
+	app.Post("/integrations/sms/code", handlers.AuthMiddleware, handlers.HandleSendSMSCode)
+	app.Post("/integrations/sms/verify", handlers.AuthMiddleware, handlers.HandleVerifySMSCode)
+	app.Post("/integrations/:kind", handlers.AuthMiddleware, handlers.HandleConnectIntegration)
+	app.Post("/integrations/:kind/alerts", handlers.AuthMiddleware, handlers.HandleUpdateIntegrationAlerts)
+	app.Post("/integrations/:id/test", handlers.AuthMiddleware, handlers.HandleTestIntegration)
+	app.Post("/integrations/:id/delete", handlers.AuthMiddleware, handlers.HandleDeleteIntegration)
+	app.Post("/integrations/:id/severities", handlers.AuthMiddleware, handlers.HandleUpdateIntegrationSeverities)
+	app.Post("/integrations/:id/templates", handlers.AuthMiddleware, handlers.HandleUpdateIntegrationTemplates)
+	app.Get("/integrations/:id/deliveries", handlers.AuthMiddleware, handlers.HandleWebhookDeliveriesPage)
+	app.Post("/integrations/:id/deliveries/:alert/redeliver", handlers.AuthMiddleware, handlers.HandleRedeliverWebhookEvent)
 
 	app.Get("/account", handlers.AuthMiddleware, handlers.HandleAccountPage)
 	app.Get("/account/delete", handlers.AuthMiddleware, handlers.HandleDeleteAccount)
@@ -92,8 +92,10 @@ func (h *handlerV1) HandleChangeEmail(c *fiber.Ctx) error {
 		})
 	}
 
+	h.pending.Add(1)
 	go func() {
-		if err := email.SendEmail(h.cfg, &email.SendEmailRequest{
+		defer h.pending.Done()
+		if err := email.SendEmail(context.Background(), h.mailer, &email.SendEmailRequest{
 			To:   []string{req.Email},
 			Type: email.ChangeEmail,
 			Body: map[string]string{
@@ -70,8 +70,10 @@ func (h *handlerV1) HandeSignupUser(c *fiber.Ctx) error {
 		})
 	}
 
+	h.pending.Add(1)
 	go func() {
-		if err := email.SendEmail(h.cfg, &email.SendEmailRequest{
+		defer h.pending.Done()
+		if err := email.SendEmail(context.Background(), h.mailer, &email.SendEmailRequest{
 			To:   []string{req.Email},
 			Type: email.VerificationEmail,
 			Body: map[string]string{
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/sujit-baniya/flash"
+
+	apiModels "github.com/SaidovZohid/certalert.info/api/models"
+	"github.com/SaidovZohid/certalert.info/pkg/utils"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// longest delay of an escalation step, in hours
+const maxEscalationDelayHours = 30 * 24
+
+func (h *handlerV1) HandleEscalationsPage(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	bind := fiber.Map{}
+	bind["user"] = payload
+
+	policies, err := h.strg.Escalations().GetEscalationPoliciesByUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	bind["policies"] = policies
+	bind["channels"] = utils.EscalationChannels
+
+	integrations, err := h.strg.Integrations().GetIntegrationsByUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	bind["integrations"] = integrations
+
+	return c.Render("escalations/index", bind)
+}
+
+// HandleCreateEscalationPolicy creates a policy attached to the given domains of the user and to the given tags
+func (h *handlerV1) HandleCreateEscalationPolicy(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	var req apiModels.CreateEscalationPolicyReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please fill in the name, the domains or the tags of the policy.",
+		}).Redirect("/escalations")
+	}
+
+	policy := &models.EscalationPolicy{
+		UserID:  payload.UserID,
+		Name:    strings.TrimSpace(req.Name),
+		Domains: make([]string, 0),
+		Tags:    utils.ParseTags(req.Tags),
+	}
+	if policy.Name == "" {
+		return flash.WithData(c, fiber.Map{
+			"error": "Please give the policy a name.",
+		}).Redirect("/escalations")
+	}
+
+	tracked, err := h.strg.Domain().GetDomainsWithUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Split(req.Domains, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, domain := range tracked {
+			if domain.DomainName == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return flash.WithData(c, fiber.Map{
+				"error": "You do not track " + name + ".",
+			}).Redirect("/escalations")
+		}
+		policy.Domains = append(policy.Domains, name)
+	}
+	if len(policy.Domains) == 0 && len(policy.Tags) == 0 {
+		return flash.WithData(c, fiber.Map{
+			"error": "Please attach the policy to at least one domain or tag.",
+		}).Redirect("/escalations")
+	}
+
+	if err := h.strg.Escalations().CreateEscalationPolicy(context.Background(), policy); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/escalations")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Escalation policy is created, add its steps now.",
+	}).Redirect("/escalations")
+}
+
+func (h *handlerV1) HandleDeleteEscalationPolicy(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Escalation policy is not found.",
+		}).Redirect("/escalations")
+	}
+
+	if err := h.strg.Escalations().DeleteEscalationPolicy(context.Background(), payload.UserID, int64(id)); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/escalations")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Escalation policy is deleted.",
+	}).Redirect("/escalations")
+}
+
+// HandleAddEscalationStep appends a step to the end of the policy
+func (h *handlerV1) HandleAddEscalationStep(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Escalation policy is not found.",
+		}).Redirect("/escalations")
+	}
+
+	var req apiModels.AddEscalationStepReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please fill in the delay, the channel and the recipients of the step.",
+		}).Redirect("/escalations")
+	}
+
+	if req.DelayHours < 0 || req.DelayHours > maxEscalationDelayHours {
+		return flash.WithData(c, fiber.Map{
+			"error": "The delay of a step can be up to 30 days.",
+		}).Redirect("/escalations")
+	}
+	step := &models.EscalationStep{
+		PolicyID:   int64(id),
+		Delay:      req.DelayHours * 60,
+		Channel:    req.Channel,
+		Recipients: make([]string, 0),
+	}
+	if req.Channel == utils.EscalationToIntegration {
+		integration, err := h.strg.Integrations().GetIntegrationByID(context.Background(), payload.UserID, req.IntegrationID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return flash.WithData(c, fiber.Map{
+				"error": "Please pick one of your integrations.",
+			}).Redirect("/escalations")
+		}
+		if err != nil {
+			h.log.Error(err)
+			return flash.WithData(c, fiber.Map{
+				"error": "An error occurred. Please try again later or contact support if the issue persists.",
+			}).Redirect("/escalations")
+		}
+		step.Channel = integration.Kind
+		step.IntegrationID = &integration.ID
+	} else {
+		step.Recipients, err = utils.ParseEscalationRecipients(req.Channel, req.Recipients)
+		if err != nil {
+			return flash.WithData(c, fiber.Map{
+				"error": err.Error(),
+			}).Redirect("/escalations")
+		}
+	}
+
+	err = h.strg.Escalations().AddEscalationStep(context.Background(), payload.UserID, step)
+	if err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/escalations")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Escalation step is added.",
+	}).Redirect("/escalations")
+}
+
+func (h *handlerV1) HandleDeleteEscalationStep(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Escalation step is not found.",
+		}).Redirect("/escalations")
+	}
+
+	if err := h.strg.Escalations().DeleteEscalationStep(context.Background(), payload.UserID, int64(id)); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/escalations")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Escalation step is deleted.",
+	}).Redirect("/escalations")
+}
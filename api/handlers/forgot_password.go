@@ -65,8 +65,10 @@ func (h *handlerV1) HandleForgotPassword(c *fiber.Ctx) error {
 	}
 
 	fullname := user.FirstName + " " + user.LastName
+	h.pending.Add(1)
 	go func() {
-		if err := email.SendEmail(h.cfg, &email.SendEmailRequest{
+		defer h.pending.Done()
+		if err := email.SendEmail(context.Background(), h.mailer, &email.SendEmailRequest{
 			To:   []string{req.Email},
 			Type: email.ForgotPasswordEmail,
 			Body: map[string]string{
@@ -19,7 +19,9 @@ import (
 	"github.com/mssola/useragent"
 
 	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/email"
 	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/pkg/sms"
 	"github.com/SaidovZohid/certalert.info/pkg/ssl"
 	"github.com/SaidovZohid/certalert.info/pkg/utils"
 	"github.com/SaidovZohid/certalert.info/storage"
@@ -33,6 +35,9 @@ type handlerV1 struct {
 	inMemory              storage.InMemoryStorageI
 	tokens                map[string]TokenDataValidAndToken
 	forgotPasswordUserReq map[string]string
+	pending               *sync.WaitGroup
+	mailer                email.Mailer
+	sms                   sms.Provider
 }
 
 type HandlerV1Options struct {
@@ -42,6 +47,10 @@ type HandlerV1Options struct {
 	InMemory              storage.InMemoryStorageI
 	Tokens                map[string]TokenDataValidAndToken
 	ForgotPasswordUserReq map[string]string
+	// emails sent in the background, waited for on shutdown
+	Pending *sync.WaitGroup
+	Mailer  email.Mailer
+	SMS     sms.Provider // nil when SMS is off
 }
 
 func New(options *HandlerV1Options) *handlerV1 {
@@ -52,6 +61,9 @@ func New(options *HandlerV1Options) *handlerV1 {
 		inMemory:              options.InMemory,
 		tokens:                options.Tokens,
 		forgotPasswordUserReq: options.ForgotPasswordUserReq,
+		pending:               options.Pending,
+		mailer:                options.Mailer,
+		sms:                   options.SMS,
 	}
 }
 
@@ -208,6 +220,7 @@ type TrackDomainAdd struct {
 	UserID  int64
 	Log     *logger.Logger
 	Strg    storage.StorageI
+	Cfg     *config.Config
 }
 
 // func getCurrentTimeInTimeZone(timezone string) (time.Time, error) {
@@ -269,7 +282,9 @@ func TrackDomainsAdded(t *TrackDomainAdd) error {
 				return
 			}
 
-			_ = domainInfo
+			if err := utils.QueueDomainAdded(context.Background(), t.Strg, t.Cfg, domainInfo); err != nil {
+				t.Log.Error(err)
+			}
 		}(domain)
 	}
 
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/sujit-baniya/flash"
+
+	apiModels "github.com/SaidovZohid/certalert.info/api/models"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+var snoozeOptions = []*apiModels.SnoozeOption{
+	{Hours: 1, Title: "1 hour"},
+	{Hours: 4, Title: "4 hours"},
+	{Hours: 24, Title: "1 day"},
+	{Hours: 72, Title: "3 days"},
+	{Hours: 168, Title: "1 week"},
+}
+
+func (h *handlerV1) HandleIncidentsPage(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	bind := fiber.Map{}
+	bind["user"] = payload
+
+	incidents, err := h.strg.Incidents().GetIncidentsByUserID(context.Background(), payload.UserID, 100)
+	if err != nil {
+		return err
+	}
+	bind["incidents"] = incidents
+	bind["snoozeOptions"] = snoozeOptions
+
+	ses, err := h.strg.Session().GetSessionInfoByID(context.Background(), payload.Id.String())
+	if err != nil {
+		return err
+	}
+	bind["locationTimeZone"] = ses.Timezone
+
+	return c.Render("incidents/index", bind)
+}
+
+// HandleAcknowledgeIncident stops the reminders of the incident
+func (h *handlerV1) HandleAcknowledgeIncident(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Incident is not found.",
+		}).Redirect("/incidents")
+	}
+
+	if err := h.strg.Incidents().AcknowledgeIncident(context.Background(), payload.UserID, int64(id)); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/incidents")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Incident is acknowledged, its reminders are stopped.",
+	}).Redirect("/incidents")
+}
+
+// HandleSnoozeIncident pauses the reminders of the incident for the chosen time
+func (h *handlerV1) HandleSnoozeIncident(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Incident is not found.",
+		}).Redirect("/incidents")
+	}
+
+	var req apiModels.SnoozeIncidentReq
+	if err := c.BodyParser(&req); err != nil || !isSnoozeOption(req.Hours) {
+		return flash.WithData(c, fiber.Map{
+			"error": "Please choose the snooze time from the list.",
+		}).Redirect("/incidents")
+	}
+
+	until := time.Now().Add(time.Duration(req.Hours) * time.Hour)
+	if err := h.strg.Incidents().SnoozeIncident(context.Background(), payload.UserID, int64(id), until); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/incidents")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Incident is snoozed.",
+	}).Redirect("/incidents")
+}
+
+// HandleResolveIncident closes the incident by hand. A problem that is still there opens a new incident on the next poll.
+func (h *handlerV1) HandleResolveIncident(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Incident is not found.",
+		}).Redirect("/incidents")
+	}
+
+	if err := h.strg.Incidents().ResolveIncident(context.Background(), payload.UserID, int64(id)); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/incidents")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Incident is resolved.",
+	}).Redirect("/incidents")
+}
+
+// HandleAcknowledgeIncidentByTokenPage shows the incident of the link in an alert email with a button that acknowledges it,
+// no login needed. Opening the link changes nothing, mail scanners open every link of an email before the user does.
+func (h *handlerV1) HandleAcknowledgeIncidentByTokenPage(c *fiber.Ctx) error {
+	incident, err := h.strg.Incidents().GetIncidentByAckToken(context.Background(), c.Params("token"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Render("incidents/acknowledged", fiber.Map{
+			"error": "The link is not valid. The incident may have been removed.",
+		})
+	}
+	if err != nil {
+		h.log.Error(err)
+		return c.Render("incidents/acknowledged", fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		})
+	}
+
+	return c.Render("incidents/acknowledged", fiber.Map{
+		"incident": incident,
+		"token":    c.Params("token"),
+		"confirm":  incident.Status == models.IncidentOpen || incident.Status == models.IncidentSnoozed,
+	})
+}
+
+// HandleAcknowledgeIncidentByToken acknowledges the incident from the button of the acknowledge page, no login needed
+func (h *handlerV1) HandleAcknowledgeIncidentByToken(c *fiber.Ctx) error {
+	incident, err := h.strg.Incidents().AcknowledgeIncidentByToken(context.Background(), c.Params("token"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Render("incidents/acknowledged", fiber.Map{
+			"error": "The link is not valid. The incident may have been removed.",
+		})
+	}
+	if err != nil {
+		h.log.Error(err)
+		return c.Render("incidents/acknowledged", fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		})
+	}
+
+	return c.Render("incidents/acknowledged", fiber.Map{
+		"incident": incident,
+	})
+}
+
+func isSnoozeOption(hours int) bool {
+	for _, option := range snoozeOptions {
+		if option.Hours == hours {
+			return true
+		}
+	}
+	return false
+}
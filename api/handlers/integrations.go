@@ -0,0 +1,527 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/sujit-baniya/flash"
+
+	apiModels "github.com/SaidovZohid/certalert.info/api/models"
+	"github.com/SaidovZohid/certalert.info/pkg/slack"
+	"github.com/SaidovZohid/certalert.info/pkg/utils"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+const (
+	// deliveries shown on the deliveries page of a webhook
+	webhookDeliveriesLimit = 50
+	// how long the user has to finish the install of the slack app
+	slackInstallStateTime = 10 * time.Minute
+	// how long a test message may take
+	integrationTestTimeout = 15 * time.Second
+)
+
+func (h *handlerV1) HandleIntegrationsPage(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	bind := fiber.Map{}
+	bind["user"] = payload
+
+	integrations, err := h.strg.Integrations().GetIntegrationsByUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	bind["integrations"] = integrations
+
+	notification, err := h.strg.Notifications().GetNotificationRowByUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	bind["notification"] = notification
+	bind["severities"] = models.Severities
+	bind["slackInstall"] = h.cfg.Slack.Conf.ClientID != ""
+
+	bind["smsAvailable"] = h.sms != nil
+	if h.sms != nil {
+		user, err := h.strg.User().GetUserByID(context.Background(), payload.UserID)
+		if err != nil {
+			return err
+		}
+		bind["smsCap"] = utils.SMSMonthlyCap(h.cfg, user)
+		sent, err := h.strg.SMS().GetSMSSentThisMonth(context.Background(), payload.UserID)
+		if err != nil {
+			return err
+		}
+		bind["smsSent"] = sent
+		if verification, err := h.smsVerification(payload.UserID); err == nil {
+			bind["smsPendingPhone"] = verification.Phone
+		}
+	}
+
+	ses, err := h.strg.Session().GetSessionInfoByID(context.Background(), payload.Id.String())
+	if err != nil {
+		return err
+	}
+	bind["locationTimeZone"] = ses.Timezone
+
+	return c.Render("integrations/index", bind)
+}
+
+// HandleConnectIntegration connects the webhook of a slack, a discord or a teams channel, an endpoint of the user's own,
+// a pagerduty service, an opsgenie team or a push channel, and turns on the alerts of its kind. Phones are connected
+// with the code texted to them instead.
+func (h *handlerV1) HandleConnectIntegration(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	if c.Params("kind") == models.IntegrationSMS {
+		return flash.WithData(c, fiber.Map{
+			"error": "Phones are connected with the code texted to them.",
+		}).Redirect("/integrations")
+	}
+
+	var req apiModels.IntegrationConnectReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please fill in the details of the connection.",
+		}).Redirect("/integrations")
+	}
+
+	integration := &models.Integration{
+		UserID: payload.UserID,
+		Kind:   c.Params("kind"),
+		Name:   strings.TrimSpace(req.Name),
+		Config: models.IntegrationConfig{
+			WebhookURL: strings.TrimSpace(req.WebhookURL),
+			RoutingKey: strings.TrimSpace(req.RoutingKey),
+			APIKey:     strings.TrimSpace(req.APIKey),
+			Region:     req.Region,
+			ServerURL:  strings.TrimSpace(req.ServerURL),
+			Topic:      strings.TrimSpace(req.Topic),
+			Token:      strings.TrimSpace(req.Token),
+			UserKey:    strings.TrimSpace(req.UserKey),
+			RoomID:     strings.TrimSpace(req.RoomID),
+		},
+	}
+	if integration.Name == "" {
+		integration.Name = utils.IntegrationTitle(integration.Kind)
+	}
+	if integration.Kind == models.IntegrationWebhook {
+		// the endpoint checks the events are ours with it, it is shown on the deliveries page
+		integration.Config.Secret = utils.NewWebhookSecret()
+	}
+
+	return h.connectIntegration(c, integration)
+}
+
+// HandleSlackInstall sends the user to slack to install the app to a workspace and pick the channel of the alerts
+func (h *handlerV1) HandleSlackInstall(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	if h.cfg.Slack.Conf.ClientID == "" {
+		return flash.WithData(c, fiber.Map{
+			"error": "Slack app is not configured, please paste the webhook url of the channel instead.",
+		}).Redirect("/integrations")
+	}
+
+	state := utils.GenerateRandomString(32)
+	if err := h.inMemory.Set("slack_install_"+state, strconv.FormatInt(payload.UserID, 10), slackInstallStateTime); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+
+	return c.Redirect(h.cfg.Slack.Conf.AuthCodeURL(state), 307)
+}
+
+// HandleSlackCallback connects the incoming webhook slack created for the channel the user picked
+func (h *handlerV1) HandleSlackCallback(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	key := "slack_install_" + c.Query("state")
+	val, err := h.inMemory.Get(key)
+	if err != nil || val != strconv.FormatInt(payload.UserID, 10) {
+		return flash.WithData(c, fiber.Map{
+			"error": "Slack install has expired, please try again.",
+		}).Redirect("/integrations")
+	}
+	_ = h.inMemory.Del(key)
+
+	if c.Query("error") != "" {
+		return flash.WithData(c, fiber.Map{
+			"error": "Slack install was canceled.",
+		}).Redirect("/integrations")
+	}
+
+	token, err := h.cfg.Slack.Conf.Exchange(context.Background(), c.Query("code"))
+	if err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Slack did not confirm the install, please try again.",
+		}).Redirect("/integrations")
+	}
+	install, err := slack.InstallFromToken(token)
+	if err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Slack did not give a channel for the alerts, please try again.",
+		}).Redirect("/integrations")
+	}
+
+	name := strings.TrimSpace(install.TeamName + " " + install.Channel)
+	if name == "" {
+		name = utils.IntegrationTitle(models.IntegrationSlack)
+	}
+	return h.connectIntegration(c, &models.Integration{
+		UserID: payload.UserID,
+		Kind:   models.IntegrationSlack,
+		Name:   name,
+		Config: models.IntegrationConfig{
+			WebhookURL: install.WebhookURL,
+			TeamID:     install.TeamID,
+			TeamName:   install.TeamName,
+			Channel:    install.Channel,
+		},
+	})
+}
+
+// connectIntegration saves the integration and turns on the alerts of its kind
+func (h *handlerV1) connectIntegration(c *fiber.Ctx, integration *models.Integration) error {
+	if err := utils.ValidateIntegration(integration); err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": err.Error(),
+		}).Redirect("/integrations")
+	}
+
+	if err := h.strg.Integrations().CreateIntegration(context.Background(), integration); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+	if err := h.strg.Notifications().UpdateTheAlertIntegrations(context.Background(), integration.UserID, utils.IntegrationAlertColumns[integration.Kind], true); err != nil {
+		h.log.Error(err)
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": integration.Name + " is connected. Send a test message to check it.",
+	}).Redirect("/integrations")
+}
+
+// HandleTestIntegration sends a test message to the integration right away
+func (h *handlerV1) HandleTestIntegration(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Integration is not found.",
+		}).Redirect("/integrations")
+	}
+	integration, err := h.strg.Integrations().GetIntegrationByID(context.Background(), payload.UserID, int64(id))
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Integration is not found.",
+		}).Redirect("/integrations")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), integrationTestTimeout)
+	defer cancel()
+	if integration.Kind == models.IntegrationSMS {
+		err = utils.SendSMSTest(ctx, h.strg, h.cfg, h.sms, integration)
+	} else {
+		err = utils.SendIntegrationTest(ctx, h.cfg, integration)
+	}
+	if err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Test message to " + integration.Name + " failed: " + err.Error(),
+		}).Redirect("/integrations")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Test message is sent to " + integration.Name + ".",
+	}).Redirect("/integrations")
+}
+
+// HandleDeleteIntegration disconnects the integration, the alerts of its kind are turned off with the last one
+func (h *handlerV1) HandleDeleteIntegration(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Integration is not found.",
+		}).Redirect("/integrations")
+	}
+	integration, err := h.strg.Integrations().GetIntegrationByID(context.Background(), payload.UserID, int64(id))
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Integration is not found.",
+		}).Redirect("/integrations")
+	}
+
+	if err := h.strg.Integrations().DeleteIntegration(context.Background(), payload.UserID, integration.ID); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+
+	integrations, err := h.strg.Integrations().GetIntegrationsByUserID(context.Background(), payload.UserID)
+	if err != nil {
+		h.log.Error(err)
+	} else if !hasIntegration(integrations, integration.Kind) {
+		if err := h.strg.Notifications().UpdateTheAlertIntegrations(context.Background(), payload.UserID, utils.IntegrationAlertColumns[integration.Kind], false); err != nil {
+			h.log.Error(err)
+		}
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": integration.Name + " is disconnected.",
+	}).Redirect("/integrations")
+}
+
+// HandleUpdateIntegrationAlerts turns the alerts of an integration kind on or off, keeping its connections
+func (h *handlerV1) HandleUpdateIntegrationAlerts(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	column, ok := utils.IntegrationAlertColumns[c.Params("kind")]
+	if !ok {
+		return flash.WithData(c, fiber.Map{
+			"error": "Integration is not found.",
+		}).Redirect("/integrations")
+	}
+
+	var req apiModels.IntegrationAlertReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+
+	if err := h.strg.Notifications().UpdateTheAlertIntegrations(context.Background(), payload.UserID, column, req.Enabled); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+
+	message := "Alerts are turned off."
+	if req.Enabled {
+		message = "Alerts are turned on."
+	}
+	return flash.WithData(c, fiber.Map{
+		"success": message,
+	}).Redirect("/integrations")
+}
+
+// HandleWebhookDeliveriesPage shows the signing secret of the webhook and its recent deliveries
+func (h *handlerV1) HandleWebhookDeliveriesPage(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	integration, err := h.webhookIntegration(c, payload.UserID)
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Webhook is not found.",
+		}).Redirect("/integrations")
+	}
+
+	bind := fiber.Map{}
+	bind["user"] = payload
+	bind["integration"] = integration
+
+	deliveries, err := h.strg.Alerts().GetAlertDeliveriesByIntegrationID(context.Background(), payload.UserID, integration.ID, webhookDeliveriesLimit)
+	if err != nil {
+		return err
+	}
+	bind["deliveries"] = deliveries
+
+	ses, err := h.strg.Session().GetSessionInfoByID(context.Background(), payload.Id.String())
+	if err != nil {
+		return err
+	}
+	bind["locationTimeZone"] = ses.Timezone
+
+	return c.Render("integrations/deliveries", bind)
+}
+
+// HandleRedeliverWebhookEvent queues the event of the alert to the webhook again, with the same idempotency key
+func (h *handlerV1) HandleRedeliverWebhookEvent(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	integration, err := h.webhookIntegration(c, payload.UserID)
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Webhook is not found.",
+		}).Redirect("/integrations")
+	}
+	page := "/integrations/" + strconv.FormatInt(integration.ID, 10) + "/deliveries"
+
+	alertID, err := c.ParamsInt("alert")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Delivery is not found.",
+		}).Redirect(page)
+	}
+	err = h.strg.Alerts().RedeliverAlert(context.Background(), payload.UserID, integration.ID, int64(alertID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return flash.WithData(c, fiber.Map{
+			"error": "Delivery is not found.",
+		}).Redirect(page)
+	}
+	if err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect(page)
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Event is queued for redelivery.",
+	}).Redirect(page)
+}
+
+// webhookIntegration returns the webhook of the user in the id parameter
+func (h *handlerV1) webhookIntegration(c *fiber.Ctx, userID int64) (*models.Integration, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, err
+	}
+	integration, err := h.strg.Integrations().GetIntegrationByID(context.Background(), userID, int64(id))
+	if err != nil {
+		return nil, err
+	}
+	if integration.Kind != models.IntegrationWebhook {
+		return nil, fmt.Errorf("integration %d is not a webhook", integration.ID)
+	}
+	return integration, nil
+}
+
+// HandleUpdateIntegrationSeverities saves which severity of the incident tool each severity of the alerts is sent with
+func (h *handlerV1) HandleUpdateIntegrationSeverities(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Integration is not found.",
+		}).Redirect("/integrations")
+	}
+	integration, err := h.strg.Integrations().GetIntegrationByID(context.Background(), payload.UserID, int64(id))
+	if err != nil || utils.IntegrationSeverityLevels(integration.Kind) == nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Integration is not found.",
+		}).Redirect("/integrations")
+	}
+
+	var req apiModels.IntegrationSeveritiesReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+
+	severities := map[string]string{
+		models.SeverityCritical: req.Critical,
+		models.SeverityError:    req.Error,
+		models.SeverityWarning:  req.Warning,
+		models.SeverityInfo:     req.Info,
+	}
+	for _, severity := range models.Severities {
+		if !utils.ValidSeverityLevel(integration.Kind, severities[severity]) {
+			return flash.WithData(c, fiber.Map{
+				"error": "Pick a " + utils.IntegrationTitle(integration.Kind) + " severity for " + severity + " alerts.",
+			}).Redirect("/integrations")
+		}
+	}
+	integration.Config.Severities = severities
+
+	if err := h.strg.Integrations().UpdateIntegrationConfig(context.Background(), integration); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Severities of " + integration.Name + " are saved.",
+	}).Redirect("/integrations")
+}
+
+// HandleUpdateIntegrationTemplates saves the templates of the message of a push channel
+func (h *handlerV1) HandleUpdateIntegrationTemplates(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Integration is not found.",
+		}).Redirect("/integrations")
+	}
+	integration, err := h.strg.Integrations().GetIntegrationByID(context.Background(), payload.UserID, int64(id))
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Integration is not found.",
+		}).Redirect("/integrations")
+	}
+	defaultTitle, defaultMessage := utils.ChannelTemplates(&models.Integration{Kind: integration.Kind})
+	if defaultMessage == "" {
+		return flash.WithData(c, fiber.Map{
+			"error": "Integration is not found.",
+		}).Redirect("/integrations")
+	}
+
+	var req apiModels.IntegrationTemplatesReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+	title := strings.TrimSpace(strings.ReplaceAll(req.TitleTemplate, "\r\n", "\n"))
+	message := strings.TrimSpace(strings.ReplaceAll(req.MessageTemplate, "\r\n", "\n"))
+	if err := utils.ValidateChannelTemplates(title, message); err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Templates are not saved, " + err.Error(),
+		}).Redirect("/integrations")
+	}
+
+	// the default is kept unset, so the connection follows it when it changes
+	if title == defaultTitle {
+		title = ""
+	}
+	if message == defaultMessage {
+		message = ""
+	}
+	integration.Config.TitleTemplate = title
+	integration.Config.MessageTemplate = message
+	if err := h.strg.Integrations().UpdateIntegrationConfig(context.Background(), integration); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Templates of " + integration.Name + " are saved. Send a test message to see them.",
+	}).Redirect("/integrations")
+}
+
+func hasIntegration(integrations []*models.Integration, kind string) bool {
+	for _, integration := range integrations {
+		if integration.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
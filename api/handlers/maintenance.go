@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sujit-baniya/flash"
+
+	apiModels "github.com/SaidovZohid/certalert.info/api/models"
+	"github.com/SaidovZohid/certalert.info/pkg/utils"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// layout of the datetime-local inputs of the maintenance window form
+const maintenanceTimeLayout = "2006-01-02T15:04"
+
+func (h *handlerV1) HandleMaintenancePage(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	bind := fiber.Map{}
+	bind["user"] = payload
+
+	windows, err := h.strg.Maintenance().GetMaintenanceWindowsByUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	bind["windows"] = windows
+
+	ses, err := h.strg.Session().GetSessionInfoByID(context.Background(), payload.Id.String())
+	if err != nil {
+		return err
+	}
+	bind["locationTimeZone"] = ses.Timezone
+
+	return c.Render("maintenance/index", bind)
+}
+
+// HandleCreateMaintenanceWindow creates a one-off or a recurring window for the given domains of the user and the given tags
+func (h *handlerV1) HandleCreateMaintenanceWindow(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	var req apiModels.CreateMaintenanceWindowReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please fill in the name, the domains or the tags and the time of the window.",
+		}).Redirect("/maintenance")
+	}
+
+	window := &models.MaintenanceWindow{
+		UserID:   payload.UserID,
+		Name:     strings.TrimSpace(req.Name),
+		Domains:  make([]string, 0),
+		Tags:     utils.ParseTags(req.Tags),
+		Timezone: strings.TrimSpace(req.Timezone),
+	}
+	if window.Name == "" {
+		return flash.WithData(c, fiber.Map{
+			"error": "Please give the maintenance window a name.",
+		}).Redirect("/maintenance")
+	}
+	loc, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Unknown timezone " + window.Timezone + ".",
+		}).Redirect("/maintenance")
+	}
+
+	tracked, err := h.strg.Domain().GetDomainsWithUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Split(req.Domains, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, domain := range tracked {
+			if domain.DomainName == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return flash.WithData(c, fiber.Map{
+				"error": "You do not track " + name + ".",
+			}).Redirect("/maintenance")
+		}
+		window.Domains = append(window.Domains, name)
+	}
+
+	if req.Kind == "recurring" {
+		expr := strings.TrimSpace(req.Cron)
+		window.Cron = &expr
+		window.Duration = &req.Duration
+	} else {
+		// the times are read in the window's timezone and kept in UTC
+		startsAt, err := time.ParseInLocation(maintenanceTimeLayout, req.StartsAt, loc)
+		if err != nil {
+			return flash.WithData(c, fiber.Map{
+				"error": "Please enter when the window starts.",
+			}).Redirect("/maintenance")
+		}
+		endsAt, err := time.ParseInLocation(maintenanceTimeLayout, req.EndsAt, loc)
+		if err != nil {
+			return flash.WithData(c, fiber.Map{
+				"error": "Please enter when the window ends.",
+			}).Redirect("/maintenance")
+		}
+		startsAt, endsAt = startsAt.UTC(), endsAt.UTC()
+		window.StartsAt, window.EndsAt = &startsAt, &endsAt
+	}
+
+	if err := utils.ValidateMaintenanceWindow(window); err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": err.Error(),
+		}).Redirect("/maintenance")
+	}
+
+	if err := h.strg.Maintenance().CreateMaintenanceWindow(context.Background(), window); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/maintenance")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Maintenance window is created.",
+	}).Redirect("/maintenance")
+}
+
+func (h *handlerV1) HandleDeleteMaintenanceWindow(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Maintenance window is not found.",
+		}).Redirect("/maintenance")
+	}
+
+	if err := h.strg.Maintenance().DeleteMaintenanceWindow(context.Background(), payload.UserID, int64(id)); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/maintenance")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Maintenance window is deleted.",
+	}).Redirect("/maintenance")
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sujit-baniya/flash"
+
+	"github.com/SaidovZohid/certalert.info/api/models"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/pkg/utils"
+)
+
+func (h *handlerV1) HandleNotificationsPage(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	bind := fiber.Map{}
+	bind["user"] = payload
+
+	notification, err := h.strg.Notifications().GetNotificationRowByUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	bind["notification"] = notification
+
+	changeFields := make([]*models.ChangeFieldOption, 0, len(ssl.ChangeFields))
+	for _, field := range ssl.ChangeFields {
+		option := models.ChangeFieldOption{
+			Field: field,
+			Title: ssl.FieldTitle(field),
+		}
+		for _, v := range notification.ChangeAlertFields {
+			if v == field {
+				option.Checked = true
+				break
+			}
+		}
+		changeFields = append(changeFields, &option)
+	}
+	bind["changeFields"] = changeFields
+	bind["reminderDays"] = reminderDayOptions(notification.ReminderDays)
+	bind["quietHoursStart"], bind["quietHoursEnd"] = "22:00", "07:00"
+	if notification.QuietHoursStart != nil && notification.QuietHoursEnd != nil {
+		bind["quietHours"] = true
+		bind["quietHoursStart"] = utils.FormatClock(*notification.QuietHoursStart)
+		bind["quietHoursEnd"] = utils.FormatClock(*notification.QuietHoursEnd)
+	}
+
+	deliveries, err := h.strg.Alerts().GetAlertDeliveriesByUserID(context.Background(), payload.UserID, 50)
+	if err != nil {
+		return err
+	}
+	bind["deliveries"] = deliveries
+
+	suppressed, err := h.strg.Alerts().GetSuppressedAlertsByUserID(context.Background(), payload.UserID, 50)
+	if err != nil {
+		return err
+	}
+	bind["suppressed"] = suppressed
+
+	ses, err := h.strg.Session().GetSessionInfoByID(context.Background(), payload.Id.String())
+	if err != nil {
+		return err
+	}
+	bind["locationTimeZone"] = ses.Timezone
+
+	return c.Render("notifications/index", bind)
+}
+
+// HandleRetryDeadAlert gives an alert that ran out of delivery attempts a new round of attempts
+func (h *handlerV1) HandleRetryDeadAlert(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Alert is not found.",
+		}).Redirect("/notifications")
+	}
+
+	if err := h.strg.Alerts().RequeueDeadAlert(context.Background(), payload.UserID, int64(id)); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/notifications")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "The alert will be delivered again.",
+	}).Redirect("/notifications")
+}
+
+// HandleUpdateChangeAlertFields saves which certificate field changes are worth a change alert
+func (h *handlerV1) HandleUpdateChangeAlertFields(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	var req models.ChangeAlertFieldsReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please choose the certificate fields from the list.",
+		}).Redirect("/notifications")
+	}
+
+	fields := make([]string, 0, len(req.Fields))
+	for _, field := range ssl.ChangeFields {
+		for _, v := range req.Fields {
+			if v == field {
+				fields = append(fields, field)
+				break
+			}
+		}
+	}
+
+	if err := h.strg.Notifications().UpdateChangeAlertFields(context.Background(), payload.UserID, fields); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/notifications")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Change alert fields are saved.",
+	}).Redirect("/notifications")
+}
+
+// HandleUpdateReminderDays saves the days before expiry the user is reminded at
+func (h *handlerV1) HandleUpdateReminderDays(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	var req models.ReminderDaysReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please choose the reminder days from the list.",
+		}).Redirect("/notifications")
+	}
+
+	if err := h.strg.Notifications().UpdateReminderDays(context.Background(), payload.UserID, utils.ValidReminderDays(req.Days)); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/notifications")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Reminder schedule is saved.",
+	}).Redirect("/notifications")
+}
+
+// HandleUpdateQuietHours saves the timezone the user's alerts are timed in and the quiet hours that hold back the alerts that are not urgent
+func (h *handlerV1) HandleUpdateQuietHours(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	var req models.QuietHoursReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please fill in the timezone and the quiet hours.",
+		}).Redirect("/notifications")
+	}
+
+	timezone := strings.TrimSpace(req.Timezone)
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" {
+		return flash.WithData(c, fiber.Map{
+			"error": "Unknown timezone " + timezone + ".",
+		}).Redirect("/notifications")
+	}
+
+	var start, end *int
+	if req.Enabled {
+		from, err := utils.ParseClock(req.Start)
+		if err != nil {
+			return flash.WithData(c, fiber.Map{
+				"error": "Quiet hours start " + err.Error(),
+			}).Redirect("/notifications")
+		}
+		to, err := utils.ParseClock(req.End)
+		if err != nil {
+			return flash.WithData(c, fiber.Map{
+				"error": "Quiet hours end " + err.Error(),
+			}).Redirect("/notifications")
+		}
+		if from == to {
+			return flash.WithData(c, fiber.Map{
+				"error": "Quiet hours must end at another time than they start.",
+			}).Redirect("/notifications")
+		}
+		start, end = &from, &to
+	}
+
+	if err := h.strg.Notifications().UpdateQuietHours(context.Background(), payload.UserID, timezone, start, end); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/notifications")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Quiet hours are saved.",
+	}).Redirect("/notifications")
+}
+
+// reminderDayOptions lists the reminder day checkboxes, the days of the schedule checked
+func reminderDayOptions(schedule []int) []*models.ReminderDayOption {
+	options := make([]*models.ReminderDayOption, 0, len(utils.ReminderDayOptions))
+	for _, days := range utils.ReminderDayOptions {
+		option := models.ReminderDayOption{
+			Days: days,
+		}
+		for _, v := range schedule {
+			if v == days {
+				option.Checked = true
+				break
+			}
+		}
+		options = append(options, &option)
+	}
+	return options
+}
+
+// HandleUpdateEmailAlert turns the alert emails of the user on or off
+func (h *handlerV1) HandleUpdateEmailAlert(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	var req models.EmailAlertReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/notifications")
+	}
+
+	if err := h.strg.Notifications().UpdateTheAlertIntegrations(context.Background(), payload.UserID, "email_alert", req.Enabled); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/notifications")
+	}
+
+	message := "Alert emails are turned off."
+	if req.Enabled {
+		message = "Alert emails are turned on."
+	}
+	return flash.WithData(c, fiber.Map{
+		"success": message,
+	}).Redirect("/notifications")
+}
+
+// HandleUnsubscribePage shows the unsubscribe link of an alert email with a button that posts back to it, no login needed.
+// Opening the link changes nothing, mail scanners open every link of an email before the user does.
+func (h *handlerV1) HandleUnsubscribePage(c *fiber.Ctx) error {
+	if _, err := utils.ParseUnsubscribeToken(h.cfg, c.Params("token")); err != nil {
+		return c.Render("notifications/unsubscribed", fiber.Map{
+			"error": "The link is not valid.",
+		})
+	}
+
+	return c.Render("notifications/unsubscribed", fiber.Map{
+		"token":   c.Params("token"),
+		"confirm": true,
+	})
+}
+
+// HandleUnsubscribe turns off the alert emails of the user, from the button of the unsubscribe page or the one-click POST
+// of the List-Unsubscribe header, no login needed.
+func (h *handlerV1) HandleUnsubscribe(c *fiber.Ctx) error {
+	userID, err := utils.ParseUnsubscribeToken(h.cfg, c.Params("token"))
+	if err != nil {
+		return c.Render("notifications/unsubscribed", fiber.Map{
+			"error": "The link is not valid.",
+		})
+	}
+
+	if err := h.strg.Notifications().UpdateTheAlertIntegrations(context.Background(), userID, "email_alert", false); err != nil {
+		h.log.Error(err)
+		return c.Render("notifications/unsubscribed", fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		})
+	}
+
+	return c.Render("notifications/unsubscribed", fiber.Map{})
+}
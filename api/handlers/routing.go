@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sujit-baniya/flash"
+
+	apiModels "github.com/SaidovZohid/certalert.info/api/models"
+	"github.com/SaidovZohid/certalert.info/pkg/utils"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// deliveries shown on the routing page, with the rule that routed each of them
+const routingDeliveriesLimit = 50
+
+func (h *handlerV1) HandleRoutingPage(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	bind := fiber.Map{}
+	bind["user"] = payload
+
+	rules, err := h.strg.Routing().GetRoutingRulesByUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	bind["rules"] = rules
+
+	integrations, err := h.strg.Integrations().GetIntegrationsByUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	bind["integrations"] = integrations
+	bind["alertTypes"] = utils.RoutableAlertTypes
+	bind["severities"] = models.Severities
+	bind["channels"] = utils.RoutingChannels
+
+	deliveries, err := h.strg.Alerts().GetAlertDeliveriesByUserID(context.Background(), payload.UserID, routingDeliveriesLimit)
+	if err != nil {
+		return err
+	}
+	bind["deliveries"] = deliveries
+
+	ses, err := h.strg.Session().GetSessionInfoByID(context.Background(), payload.Id.String())
+	if err != nil {
+		return err
+	}
+	bind["locationTimeZone"] = ses.Timezone
+
+	return c.Render("routing/index", bind)
+}
+
+// HandleCreateRoutingRule adds a rule after the existing ones of the user
+func (h *handlerV1) HandleCreateRoutingRule(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	var req apiModels.CreateRoutingRuleReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please fill in the name, the conditions and the destinations of the rule.",
+		}).Redirect("/routing")
+	}
+
+	domains, err := utils.ParseDomainPatterns(req.Domains)
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": err.Error(),
+		}).Redirect("/routing")
+	}
+	rule := &models.RoutingRule{
+		UserID:         payload.UserID,
+		Name:           strings.TrimSpace(req.Name),
+		Domains:        domains,
+		Tags:           utils.ParseTags(req.Tags),
+		AlertTypes:     nonNil(req.AlertTypes),
+		Severities:     nonNil(req.Severities),
+		Channels:       nonNil(req.Channels),
+		IntegrationIDs: req.IntegrationIDs,
+	}
+	if rule.IntegrationIDs == nil {
+		rule.IntegrationIDs = make([]int64, 0)
+	}
+
+	integrations, err := h.strg.Integrations().GetIntegrationsByUserID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	if err := utils.ValidateRoutingRule(rule, integrations); err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": err.Error(),
+		}).Redirect("/routing")
+	}
+
+	if err := h.strg.Routing().CreateRoutingRule(context.Background(), rule); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/routing")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Routing rule " + rule.Name + " is created.",
+	}).Redirect("/routing")
+}
+
+// HandleMoveRoutingRule moves the rule up or down by one, the rules are tried from the top
+func (h *handlerV1) HandleMoveRoutingRule(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Routing rule is not found.",
+		}).Redirect("/routing")
+	}
+
+	var req apiModels.MoveRoutingRuleReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/routing")
+	}
+
+	if err := h.strg.Routing().MoveRoutingRule(context.Background(), payload.UserID, int64(id), req.Direction == "up"); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/routing")
+	}
+
+	return c.Redirect("/routing")
+}
+
+func (h *handlerV1) HandleDeleteRoutingRule(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "Routing rule is not found.",
+		}).Redirect("/routing")
+	}
+
+	if err := h.strg.Routing().DeleteRoutingRule(context.Background(), payload.UserID, int64(id)); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/routing")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Routing rule is deleted.",
+	}).Redirect("/routing")
+}
+
+// nonNil keeps the unchecked boxes of a form an empty list, the columns are not null
+func nonNil(values []string) []string {
+	if values == nil {
+		return make([]string, 0)
+	}
+	return values
+}
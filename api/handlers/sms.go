@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sujit-baniya/flash"
+
+	apiModels "github.com/SaidovZohid/certalert.info/api/models"
+	"github.com/SaidovZohid/certalert.info/pkg/sms"
+	"github.com/SaidovZohid/certalert.info/pkg/utils"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+const (
+	// how long the code texted to a phone is valid
+	smsCodeTime = 10 * time.Minute
+	// digits of the code
+	smsCodeLength = 6
+	// wrong codes a verification takes before a new code has to be sent
+	smsCodeAttempts = 5
+)
+
+// HandleSendSMSCode texts a one-time code to the phone, it is connected once the code is entered
+func (h *handlerV1) HandleSendSMSCode(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	if h.sms == nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "SMS alerts are not available on this server.",
+		}).Redirect("/integrations")
+	}
+
+	var req apiModels.SMSCodeReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please fill in the phone number.",
+		}).Redirect("/integrations")
+	}
+	phone, err := sms.NormalizePhone(req.Phone)
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": err.Error(),
+		}).Redirect("/integrations")
+	}
+
+	code, err := utils.GenerateRandomCode(smsCodeLength)
+	if err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+	verification := &apiModels.SMSVerificationRedis{
+		Name:  strings.TrimSpace(req.Name),
+		Phone: phone,
+		Code:  code,
+	}
+	if err := h.saveSMSVerification(payload.UserID, verification); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect("/integrations")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), integrationTestTimeout)
+	defer cancel()
+	if err := utils.SendSMS(ctx, h.strg, h.cfg, h.sms, payload.UserID, phone, utils.SMSVerificationBody(code)); err != nil {
+		h.log.Error(err)
+		_ = h.inMemory.Del(smsVerificationKey(payload.UserID))
+		message := "The code could not be sent to " + phone + ", please check the number."
+		if errors.Is(err, utils.ErrSMSCapReached) {
+			message = "You have used all the text messages of this month."
+		}
+		return flash.WithData(c, fiber.Map{
+			"error": message,
+		}).Redirect("/integrations")
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "A code is sent to " + phone + ", enter it to connect the phone.",
+	}).Redirect("/integrations")
+}
+
+// HandleVerifySMSCode connects the phone the code was texted to and turns on the sms alerts
+func (h *handlerV1) HandleVerifySMSCode(c *fiber.Ctx) error {
+	payload, _ := h.getAuth(c)
+
+	var req apiModels.SMSVerifyReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please enter the code.",
+		}).Redirect("/integrations")
+	}
+
+	verification, err := h.smsVerification(payload.UserID)
+	if err != nil {
+		return flash.WithData(c, fiber.Map{
+			"error": "The code has expired, please send a new one.",
+		}).Redirect("/integrations")
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(req.Code)), []byte(verification.Code)) != 1 {
+		verification.Attempts++
+		if verification.Attempts >= smsCodeAttempts {
+			_ = h.inMemory.Del(smsVerificationKey(payload.UserID))
+			return flash.WithData(c, fiber.Map{
+				"error": "The code is wrong too many times, please send a new one.",
+			}).Redirect("/integrations")
+		}
+		if err := h.saveSMSVerification(payload.UserID, verification); err != nil {
+			h.log.Error(err)
+		}
+		return flash.WithData(c, fiber.Map{
+			"error": "The code is wrong, please check the message and try again.",
+		}).Redirect("/integrations")
+	}
+	_ = h.inMemory.Del(smsVerificationKey(payload.UserID))
+
+	name := verification.Name
+	if name == "" {
+		name = sms.MaskPhone(verification.Phone)
+	}
+	return h.connectIntegration(c, &models.Integration{
+		UserID: payload.UserID,
+		Kind:   models.IntegrationSMS,
+		Name:   name,
+		Config: models.IntegrationConfig{
+			Phone: verification.Phone,
+		},
+	})
+}
+
+// smsVerification returns the phone of the user waiting for its code
+func (h *handlerV1) smsVerification(userID int64) (*apiModels.SMSVerificationRedis, error) {
+	val, err := h.inMemory.Get(smsVerificationKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	var verification apiModels.SMSVerificationRedis
+	if err := json.Unmarshal([]byte(val), &verification); err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+func (h *handlerV1) saveSMSVerification(userID int64, verification *apiModels.SMSVerificationRedis) error {
+	data, err := json.Marshal(verification)
+	if err != nil {
+		return err
+	}
+	return h.inMemory.Set(smsVerificationKey(userID), string(data), smsCodeTime)
+}
+
+func smsVerificationKey(userID int64) string {
+	return "sms_verify_" + strconv.FormatInt(userID, 10)
+}
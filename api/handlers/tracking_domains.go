@@ -4,12 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/SaidovZohid/certalert.info/api/models"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/pkg/utils"
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v4"
 	"github.com/sujit-baniya/flash"
 )
 
@@ -116,6 +120,7 @@ func (h *handlerV1) AddNewDomains(c *fiber.Ctx) error {
 		Domains: domains,
 		Log:     &h.log,
 		Strg:    h.strg,
+		Cfg:     h.cfg,
 	})
 	if err != nil {
 		return err
@@ -171,12 +176,32 @@ func (h *handlerV1) HandleStopMonitoringDomains(c *fiber.Ctx) error {
 		return errors.New("no domain to delete")
 	}
 
+	// kept for the removed events, they carry the domain as it was
+	removed := make([]*ssl.DomainTracking, 0, len(req.Domains))
+	for _, domainID := range req.Domains {
+		id, err := strconv.ParseInt(domainID, 10, 64)
+		if err != nil {
+			continue
+		}
+		domain, err := h.strg.Domain().GetDomainWithUserIDAndDomainID(context.Background(), payload.UserID, id)
+		if err != nil {
+			continue
+		}
+		removed = append(removed, domain)
+	}
+
 	err := h.strg.Domain().DeleteTrackingDomains(context.Background(), payload.UserID, req.Domains)
 	if err != nil {
 		h.log.Error(err)
 		return err
 	}
 
+	for _, domain := range removed {
+		if err := utils.QueueDomainRemoved(context.Background(), h.strg, h.cfg, domain); err != nil {
+			h.log.Error(err)
+		}
+	}
+
 	trackingDomains, err := h.strg.Domain().GetDomainsWithUserID(context.Background(), payload.UserID)
 	if err != nil {
 		h.log.Error(err)
@@ -204,12 +229,22 @@ func (h *handlerV1) HandleStopMonitoringDomain(c *fiber.Ctx) error {
 		return errors.New("no domain to delete")
 	}
 
+	domain, err := h.strg.Domain().GetDomainWithUserIDAndDomainID(context.Background(), payload.UserID, int64(id))
+	if err != nil {
+		h.log.Error(err)
+		return err
+	}
+
 	err = h.strg.Domain().DeleteTrackingDomain(context.Background(), payload.UserID, int64(id))
 	if err != nil {
 		h.log.Error(err)
 		return err
 	}
 
+	if err := utils.QueueDomainRemoved(context.Background(), h.strg, h.cfg, domain); err != nil {
+		h.log.Error(err)
+	}
+
 	trackingDomains, err := h.strg.Domain().GetDomainsWithUserID(context.Background(), payload.UserID)
 	if err != nil {
 		h.log.Error(err)
@@ -286,15 +321,103 @@ func (h *handlerV1) HandleDomainInfoShowPage(c *fiber.Ctx) error {
 		bind["domainName"] = "https://" + domain.DomainName
 	}
 	bind["domain"] = domain
+	renewalInfo, err := h.strg.RenewalInfo().GetRenewalInfoByDomain(context.Background(), domain.DomainName)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if renewalInfo != nil {
+		bind["renewalInfo"] = renewalInfo
+		bind["renewalWindowStart"] = &renewalInfo.WindowStart
+		bind["renewalWindowEnd"] = &renewalInfo.WindowEnd
+	}
+	user, err := h.strg.User().GetUserByID(context.Background(), payload.UserID)
+	if err != nil {
+		return err
+	}
+	bind["checkIntervals"] = h.checkIntervalOptions(user.MinCheckInterval, domain.CheckInterval)
+	bind["priorities"] = []string{ssl.PriorityLow, ssl.PriorityNormal, ssl.PriorityHigh}
+	bind["reminderDays"] = reminderDayOptions(domain.ReminderDays)
+	bind["defaultReminderDays"] = domain.ReminderDays == nil
+	bind["tags"] = strings.Join(domain.Tags, ", ")
+	timeline, err := h.domainTimeline(context.Background(), domain.DomainName)
+	if err != nil {
+		return err
+	}
+	bind["timeline"] = timeline
 	ses, err := h.strg.Session().GetSessionInfoByID(context.Background(), payload.Id.String())
 	if err != nil {
 		return err
 	}
 	bind["locationTimeZone"] = ses.Timezone
+	if domain.SnoozedUntil != nil && domain.SnoozedUntil.After(time.Now()) {
+		loc, err := time.LoadLocation(ses.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		bind["snoozedUntil"] = domain.SnoozedUntil.In(loc).Format("2006-01-02")
+	}
 
 	return c.Render("domains/info", bind)
 }
 
+// domainTimeline merges the certificate rotations and status changes of the domain, newest first
+func (h *handlerV1) domainTimeline(ctx context.Context, domain string) ([]*models.TimelineEvent, error) {
+	const maxEvents = 50
+
+	certs, err := h.strg.Certificates().GetCertificatesByDomain(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	statusChanges, err := h.strg.History().GetStatusChangesByDomain(ctx, domain, maxEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := make([]*models.TimelineEvent, 0, len(certs)+len(statusChanges))
+	for i, cert := range certs {
+		event := models.TimelineEvent{
+			Time:  cert.FirstSeen,
+			Kind:  "certificate",
+			Title: "Certificate rotated",
+		}
+		if i == 0 {
+			event.Title = "First certificate seen"
+		}
+		if cert.Issuer != nil {
+			event.Detail = "Issued by " + *cert.Issuer
+		}
+		if cert.Expires != nil {
+			event.Detail += fmt.Sprintf(", expires %v", cert.Expires.Format(time.RFC1123))
+		}
+		timeline = append(timeline, &event)
+	}
+	for _, snapshot := range statusChanges {
+		event := models.TimelineEvent{
+			Time:  snapshot.PolledAt,
+			Kind:  "status",
+			Title: "Status unavailable",
+		}
+		if snapshot.Status != nil {
+			event.Title = "Status " + *snapshot.Status
+		}
+		if snapshot.Error != nil {
+			event.Detail = *snapshot.Error
+		} else if snapshot.Latency != nil {
+			event.Detail = fmt.Sprintf("Responded in %v ms", *snapshot.Latency)
+		}
+		timeline = append(timeline, &event)
+	}
+
+	sort.Slice(timeline, func(i, j int) bool {
+		return timeline[i].Time.After(timeline[j].Time)
+	})
+	if len(timeline) > maxEvents {
+		timeline = timeline[:maxEvents]
+	}
+
+	return timeline, nil
+}
+
 func (h *handlerV1) HandleShowEncodedPEM(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id", ""))
 	if err != nil {
@@ -314,3 +437,237 @@ func (h *handlerV1) HandleShowEncodedPEM(c *fiber.Ctx) error {
 
 	return c.Send([]byte(*domain.EncodedPEM))
 }
+
+func (h *handlerV1) HandleDomainChangesPage(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id", ""))
+	if err != nil {
+		return err
+	}
+
+	payload, _ := h.getAuth(c)
+
+	bind := fiber.Map{}
+	bind["user"] = payload
+
+	domain, err := h.strg.Domain().GetDomainWithUserIDAndDomainID(context.Background(), payload.UserID, int64(id))
+	if err != nil {
+		return err
+	}
+	bind["domain"] = domain
+
+	changes, err := h.strg.Changes().GetCertificateChangesByDomain(context.Background(), domain.DomainName, 100)
+	if err != nil {
+		return err
+	}
+	bind["changes"] = changes
+
+	ses, err := h.strg.Session().GetSessionInfoByID(context.Background(), payload.Id.String())
+	if err != nil {
+		return err
+	}
+	bind["locationTimeZone"] = ses.Timezone
+
+	return c.Render("domains/changes", bind)
+}
+
+// checkIntervalOptions lists the check intervals the user's plan allows
+func (h *handlerV1) checkIntervalOptions(minCheckInterval *int, selected *int) []*models.CheckIntervalOption {
+	min, max := utils.CheckIntervalBounds(h.cfg, minCheckInterval)
+
+	options := make([]*models.CheckIntervalOption, 0)
+	for _, minutes := range []int{5, 15, 30, 60, 180, 360, 720, 1440} {
+		interval := time.Duration(minutes) * time.Minute
+		if interval < min || (max > 0 && interval > max) {
+			continue
+		}
+		options = append(options, &models.CheckIntervalOption{
+			Minutes:  minutes,
+			Title:    interval.String(),
+			Selected: selected != nil && *selected == minutes,
+		})
+	}
+	return options
+}
+
+// HandleUpdateDomainSchedule saves the check interval and the priority class of the tracked domain
+func (h *handlerV1) HandleUpdateDomainSchedule(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id", ""))
+	if err != nil {
+		return err
+	}
+	redirect := fmt.Sprintf("/domains/more/%v", id)
+
+	payload, _ := h.getAuth(c)
+
+	var req models.DomainScheduleReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please choose the check interval and the priority from the list.",
+		}).Redirect(redirect)
+	}
+
+	if !utils.IsValidPriority(req.Priority) {
+		return flash.WithData(c, fiber.Map{
+			"error": "Please choose the priority from the list.",
+		}).Redirect(redirect)
+	}
+
+	var checkInterval *int
+	if req.CheckInterval != "" {
+		minutes, err := strconv.Atoi(req.CheckInterval)
+		if err != nil {
+			return flash.WithData(c, fiber.Map{
+				"error": "Please choose the check interval from the list.",
+			}).Redirect(redirect)
+		}
+
+		user, err := h.strg.User().GetUserByID(context.Background(), payload.UserID)
+		if err != nil {
+			return err
+		}
+		min, max := utils.CheckIntervalBounds(h.cfg, user.MinCheckInterval)
+		interval := time.Duration(minutes) * time.Minute
+		if interval < min || (max > 0 && interval > max) {
+			return flash.WithData(c, fiber.Map{
+				"error": fmt.Sprintf("Your plan allows check intervals from %v to %v. Contact us on Telegram at @zohid_0212 to discuss upgrading your plan.", min, max),
+			}).Redirect(redirect)
+		}
+		checkInterval = &minutes
+	}
+
+	err = h.strg.Domain().UpdateDomainSchedule(context.Background(), payload.UserID, int64(id), checkInterval, req.Priority)
+	if err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect(redirect)
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Polling settings are saved.",
+	}).Redirect(redirect)
+}
+
+// HandleUpdateDomainReminderDays saves the reminder schedule of the tracked domain, or brings back the user's schedule
+func (h *handlerV1) HandleUpdateDomainReminderDays(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id", ""))
+	if err != nil {
+		return err
+	}
+	redirect := fmt.Sprintf("/domains/more/%v", id)
+
+	payload, _ := h.getAuth(c)
+
+	var req models.ReminderDaysReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please choose the reminder days from the list.",
+		}).Redirect(redirect)
+	}
+
+	var days []int
+	if !req.Default {
+		days = utils.ValidReminderDays(req.Days)
+	}
+
+	err = h.strg.Domain().UpdateDomainReminderDays(context.Background(), payload.UserID, int64(id), days)
+	if err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect(redirect)
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Reminder schedule is saved.",
+	}).Redirect(redirect)
+}
+
+// HandleUpdateDomainTags replaces the tags of the tracked domain
+func (h *handlerV1) HandleUpdateDomainTags(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id", ""))
+	if err != nil {
+		return err
+	}
+	redirect := fmt.Sprintf("/domains/more/%v", id)
+
+	payload, _ := h.getAuth(c)
+
+	var req models.DomainTagsReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please enter the tags separated by commas.",
+		}).Redirect(redirect)
+	}
+
+	err = h.strg.Domain().UpdateDomainTags(context.Background(), payload.UserID, int64(id), utils.ParseTags(req.Tags))
+	if err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect(redirect)
+	}
+
+	return flash.WithData(c, fiber.Map{
+		"success": "Tags are saved.",
+	}).Redirect(redirect)
+}
+
+// HandleUpdateDomainSnooze snoozes the alerts of the tracked domain until the start of the date, read in the session timezone.
+// The domain is still polled and its alerts are recorded. An empty date wakes the alerts up.
+func (h *handlerV1) HandleUpdateDomainSnooze(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id", ""))
+	if err != nil {
+		return err
+	}
+	redirect := fmt.Sprintf("/domains/more/%v", id)
+
+	payload, _ := h.getAuth(c)
+
+	var req models.DomainSnoozeReq
+	if err := c.BodyParser(&req); err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "Please pick the date to snooze the alerts until.",
+		}).Redirect(redirect)
+	}
+
+	var until *time.Time
+	if req.Until != "" {
+		ses, err := h.strg.Session().GetSessionInfoByID(context.Background(), payload.Id.String())
+		if err != nil {
+			return err
+		}
+		loc, err := time.LoadLocation(ses.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		date, err := time.ParseInLocation("2006-01-02", req.Until, loc)
+		if err != nil || !date.After(time.Now()) {
+			return flash.WithData(c, fiber.Map{
+				"error": "Please pick a date in the future.",
+			}).Redirect(redirect)
+		}
+		date = date.UTC()
+		until = &date
+	}
+
+	err = h.strg.Domain().UpdateDomainSnooze(context.Background(), payload.UserID, int64(id), until)
+	if err != nil {
+		h.log.Error(err)
+		return flash.WithData(c, fiber.Map{
+			"error": "An error occurred. Please try again later or contact support if the issue persists.",
+		}).Redirect(redirect)
+	}
+
+	message := "Alerts are snoozed until " + req.Until + "."
+	if until == nil {
+		message = "Alerts are no longer snoozed."
+	}
+	return flash.WithData(c, fiber.Map{
+		"success": message,
+	}).Redirect(redirect)
+}
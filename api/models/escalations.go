@@ -0,0 +1,14 @@
+package models
+
+type CreateEscalationPolicyReq struct {
+	Name    string `json:"name" form:"name"`
+	Domains string `json:"domains" form:"domains"` // comma separated domain names
+	Tags    string `json:"tags" form:"tags"`       // comma separated tags
+}
+
+type AddEscalationStepReq struct {
+	DelayHours    int    `json:"delay_hours" form:"delay_hours"`
+	Channel       string `json:"channel" form:"channel"`       // email, telegram or integration
+	Recipients    string `json:"recipients" form:"recipients"` // comma separated, empty means the owner
+	IntegrationID int64  `json:"integration_id" form:"integration_id"`
+}
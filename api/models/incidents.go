@@ -0,0 +1,11 @@
+package models
+
+type SnoozeIncidentReq struct {
+	Hours int `json:"hours" form:"hours"`
+}
+
+// SnoozeOption is one choice of the incident snooze select
+type SnoozeOption struct {
+	Hours int
+	Title string
+}
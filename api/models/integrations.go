@@ -0,0 +1,51 @@
+package models
+
+type IntegrationConnectReq struct {
+	Name       string `json:"name" form:"name"`
+	WebhookURL string `json:"webhook_url" form:"webhook_url"`
+	RoutingKey string `json:"routing_key" form:"routing_key"`
+	APIKey     string `json:"api_key" form:"api_key"`
+	Region     string `json:"region" form:"region"`
+	ServerURL  string `json:"server_url" form:"server_url"`
+	Topic      string `json:"topic" form:"topic"`
+	Token      string `json:"token" form:"token"`
+	UserKey    string `json:"user_key" form:"user_key"`
+	RoomID     string `json:"room_id" form:"room_id"`
+}
+
+type IntegrationAlertReq struct {
+	Enabled bool `json:"enabled" form:"enabled"`
+}
+
+// IntegrationSeveritiesReq maps the severities of the alerts to the ones of the incident tool
+type IntegrationSeveritiesReq struct {
+	Critical string `json:"critical" form:"critical"`
+	Error    string `json:"error" form:"error"`
+	Warning  string `json:"warning" form:"warning"`
+	Info     string `json:"info" form:"info"`
+}
+
+// IntegrationTemplatesReq is the text/templates of the message of a push channel, empty ones take the default
+type IntegrationTemplatesReq struct {
+	TitleTemplate   string `json:"title_template" form:"title_template"`
+	MessageTemplate string `json:"message_template" form:"message_template"`
+}
+
+// SMSCodeReq is the on-call phone a verification code is texted to
+type SMSCodeReq struct {
+	Name  string `json:"name" form:"name"`
+	Phone string `json:"phone" form:"phone"`
+}
+
+// SMSVerifyReq is the code the user got on the phone
+type SMSVerifyReq struct {
+	Code string `json:"code" form:"code"`
+}
+
+// SMSVerificationRedis is the phone waiting for its code to be entered
+type SMSVerificationRedis struct {
+	Name     string `json:"name"`
+	Phone    string `json:"phone"`
+	Code     string `json:"code"`
+	Attempts int    `json:"attempts"`
+}
@@ -0,0 +1,13 @@
+package models
+
+type CreateMaintenanceWindowReq struct {
+	Name     string `json:"name" form:"name"`
+	Domains  string `json:"domains" form:"domains"` // comma separated domain names
+	Tags     string `json:"tags" form:"tags"`       // comma separated tags
+	Kind     string `json:"kind" form:"kind"`       // once or recurring
+	StartsAt string `json:"starts_at" form:"starts_at"`
+	EndsAt   string `json:"ends_at" form:"ends_at"`
+	Cron     string `json:"cron" form:"cron"`
+	Duration int    `json:"duration" form:"duration"` // minutes
+	Timezone string `json:"timezone" form:"timezone"`
+}
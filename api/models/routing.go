@@ -0,0 +1,15 @@
+package models
+
+type CreateRoutingRuleReq struct {
+	Name           string   `json:"name" form:"name"`
+	Domains        string   `json:"domains" form:"domains"` // comma separated domain patterns, like *.example.com
+	Tags           string   `json:"tags" form:"tags"`       // comma separated tags
+	AlertTypes     []string `json:"alert_types" form:"alert_types"`
+	Severities     []string `json:"severities" form:"severities"`
+	Channels       []string `json:"channels" form:"channels"` // email and telegram
+	IntegrationIDs []int64  `json:"integration_ids" form:"integration_ids"`
+}
+
+type MoveRoutingRuleReq struct {
+	Direction string `json:"direction" form:"direction"` // up or down
+}
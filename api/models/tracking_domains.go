@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type DomainsNewReq struct {
 	Domains []string `json:"domains"`
 }
@@ -7,3 +9,64 @@ type DomainsNewReq struct {
 type DomainsReq struct {
 	Domains string `json:"domains"`
 }
+
+// TimelineEvent is one entry of the domain history timeline: a certificate rotation or a status change
+type TimelineEvent struct {
+	Time   time.Time
+	Kind   string // certificate or status
+	Title  string
+	Detail string
+}
+
+// ChangeFieldOption is one certificate field checkbox on the notifications page
+type ChangeFieldOption struct {
+	Field   string
+	Title   string
+	Checked bool
+}
+
+type ChangeAlertFieldsReq struct {
+	Fields []string `json:"fields"`
+}
+
+// CheckIntervalOption is one choice of the domain check interval select
+type CheckIntervalOption struct {
+	Minutes  int
+	Title    string
+	Selected bool
+}
+
+type DomainScheduleReq struct {
+	CheckInterval string `json:"check_interval" form:"check_interval"` // minutes, empty means the default interval
+	Priority      string `json:"priority" form:"priority"`
+}
+
+// ReminderDayOption is one checkbox of a reminder schedule
+type ReminderDayOption struct {
+	Days    int
+	Checked bool
+}
+
+type ReminderDaysReq struct {
+	Days    []int `json:"days" form:"days"`
+	Default bool  `json:"default" form:"default"` // the domain goes back to the schedule of the user
+}
+
+type DomainTagsReq struct {
+	Tags string `json:"tags" form:"tags"` // comma separated
+}
+
+type DomainSnoozeReq struct {
+	Until string `json:"until" form:"until"` // date, empty wakes the alerts up
+}
+
+type QuietHoursReq struct {
+	Timezone string `json:"timezone" form:"timezone"`
+	Enabled  bool   `json:"enabled" form:"enabled"`
+	Start    string `json:"start" form:"start"` // 22:00
+	End      string `json:"end" form:"end"`
+}
+
+type EmailAlertReq struct {
+	Enabled bool `json:"enabled" form:"enabled"`
+}
@@ -4,6 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -11,7 +17,10 @@ import (
 
 	"github.com/SaidovZohid/certalert.info/api"
 	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/email"
+	"github.com/SaidovZohid/certalert.info/pkg/leader"
 	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/pkg/sms"
 	"github.com/SaidovZohid/certalert.info/pkg/utils"
 	"github.com/SaidovZohid/certalert.info/storage"
 	"github.com/SaidovZohid/certalert.info/telegram"
@@ -49,33 +58,115 @@ func main() {
 		Addr: cfg.Redis,
 	})
 
+	mailer, err := email.NewMailer(&cfg, log)
+	if err != nil {
+		log.Fatalf("Failed to make mailer: %v", err)
+	}
+
+	smsProvider, err := sms.NewProvider(&cfg, log)
+	if err != nil {
+		log.Fatalf("Failed to make sms provider: %v", err)
+	}
+
 	strg := storage.NewStoragePg(dbPool, log)
 	inMemory := storage.NewInMemoryStorage(rdb)
 
+	// SIGINT and SIGTERM start the graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pending := &sync.WaitGroup{}
 	app := api.New(&api.RoutetOptions{
 		Cfg:      &cfg,
 		Log:      log,
 		Strg:     strg,
 		InMemory: inMemory,
+		Pending:  pending,
+		Mailer:   mailer,
+		SMS:      smsProvider,
 	})
 
-	go func(bot *tgbotapi.BotAPI) {
-		log.Info("Initializing regular domain information update...")
+	updateReg := utils.NewUpdateReg(strg, log, &cfg, bot, mailer, smsProvider)
+	background := &sync.WaitGroup{}
+	background.Add(3)
+	go func() {
+		defer background.Done()
+		log.Info("Starting poll workers...")
 
-		// Initiate the function to update domain information regularly
-		updateReg := utils.NewUpdateReg(strg, log, &cfg, bot)
-		updateReg.UpdateDomainInformationRegularly(context.Background())
-	}(bot)
+		// Every instance polls domains off the shared queue
+		updateReg.RunPollWorkers(ctx)
+	}()
 	go func() {
-		log.Info("Initializing and starting the Telegram bot...")
+		defer background.Done()
+		log.Info("Starting alert dispatcher...")
+
+		// Every instance delivers alerts from the shared outbox
+		updateReg.RunAlertDispatcher(ctx)
+	}()
+	go func() {
+		defer background.Done()
+		// Only the leader runs the polling scheduler and long polls telegram updates, the other instances
+		// wait to take over when the leader goes away
+		elector := leader.NewElector(dbPool, &log, "certalert-leader", cfg.LeaderElectionInterval)
+		elector.Run(ctx, func(ctx context.Context) {
+			wg := sync.WaitGroup{}
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				log.Info("Initializing regular domain information update...")
+
+				// Initiate the function to update domain information regularly
+				updateReg.UpdateDomainInformationRegularly(ctx)
+			}()
+			go func() {
+				defer wg.Done()
+				log.Info("Initializing and starting the Telegram bot...")
 
-		telegramBot := telegram.NewBot(bot, &log, &cfg, strg)
+				telegramBot := telegram.NewBot(bot, &log, &cfg, strg)
 
-		if err := telegramBot.Start(); err != nil {
-			log.Fatal(fmt.Sprintf("Error while starting bot: %v", err))
+				if err := telegramBot.Start(ctx); err != nil {
+					log.Error(fmt.Sprintf("Error while starting bot: %v", err))
+				}
+			}()
+			wg.Wait()
+		})
+	}()
+
+	go func() {
+		log.Info("HTTP running in PORT -> ", cfg.HttpPort)
+		if err := app.Listen(cfg.HttpPort); err != nil {
+			log.Error("Error while listening http port: ", err)
+			stop()
 		}
 	}()
 
-	log.Info("HTTP running in PORT -> ", cfg.HttpPort)
-	log.Fatal("Error while listening http port:", app.Listen(cfg.HttpPort))
+	<-ctx.Done()
+	shutdown(log, cfg.ShutdownTimeout, app, pending, background)
+	rdb.Close()
+}
+
+// shutdown drains the http server, then waits for the background emails, the poll workers and the leader's
+// work to finish. Everything shares one deadline, whatever is still running after it is dropped.
+func shutdown(log logger.Logger, timeout time.Duration, app *fiber.App, waitGroups ...*sync.WaitGroup) {
+	log.Info("Shutting down gracefully, timeout -> ", timeout)
+	deadline := time.Now().Add(timeout)
+
+	if err := app.ShutdownWithTimeout(timeout); err != nil {
+		log.Error("Failed to shut down http server: ", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, wg := range waitGroups {
+			wg.Wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("Shut down gracefully")
+	case <-time.After(time.Until(deadline)):
+		log.Error("Shutdown timeout passed before all work finished")
+	}
 }
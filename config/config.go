@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -22,20 +23,90 @@ type Config struct {
 	ForgotPasswordLinkTokenTime time.Duration
 	UpdateEmailLinkTokenTime    time.Duration
 	PullUpdateDomainInterval    time.Duration
+	AcmeDirectories             map[string]string
+	RenewalOverdueGrace         time.Duration
+	LeaderElectionInterval      time.Duration
+	ShutdownTimeout             time.Duration
+	History                     History
+	Scheduler                   Scheduler
+	Alerts                      Alerts
+	Webhooks                    Webhooks
+	SMS                         SMS
 	Postgres                    Postgres
 	Google                      Google
+	Slack                       Slack
 	Smtp                        Smtp
 }
 
+// Scheduler configures how often each domain is polled
+type Scheduler struct {
+	Tick                    time.Duration // how often the scheduler looks for due domains
+	DefaultMinCheckInterval time.Duration // plan limit for users without their own limit
+	MaxCheckInterval        time.Duration // longest check interval a domain can have
+	AdaptiveMinInterval     time.Duration // floor for the shortened interval of failing and expiring domains
+	Workers                 int           // poll workers of this instance
+	JobLease                time.Duration // how long a worker holds a poll job before another worker may take it over
+	JobMaxAttempts          int           // polls of a domain before its job is marked failed
+	JobRetryBackoff         time.Duration // wait before the first retry, grows with the square of the attempts
+	Jitter                  float64       // polls are spread over up to this fraction of the check interval
+	MaxPerIP                int           // polls running at once against one IP address over all the instances, 0 for no limit
+	MaxPerDomain            int           // polls running at once under one registrable domain over all the instances, 0 for no limit
+}
+
+// Alerts configures the delivery of alerts from the outbox
+type Alerts struct {
+	DispatchInterval time.Duration // how often the dispatcher looks for due alerts when idle
+	Lease            time.Duration // how long a dispatcher holds an alert before another one may take it over
+	MaxAttempts      int           // attempts before an alert is dead
+	RetryBackoff     time.Duration // wait after the first failed attempt, doubles with every attempt
+}
+
+// Webhooks configures the delivery of events to the endpoints of the users
+type Webhooks struct {
+	AllowPrivateNetworks bool // lets endpoints resolve to loopback and private addresses, for self-hosted installs only
+}
+
+// SMS configures how the text messages to the on-call phones are sent
+type SMS struct {
+	Provider   string // twilio (or a provider with its api), log (for development), fake (kept in memory, for tests), none when empty
+	BaseURL    string // the api of a twilio compatible provider, twilio when empty
+	AccountSID string
+	AuthToken  string
+	From       string // the sending phone number or the sid of a messaging service
+	MonthlyCap int    // messages a user gets a month, unless the user has a cap of its own
+}
+
+// History configures how long poll snapshots are kept. Zero durations turn the step off.
+type History struct {
+	Retention        time.Duration // snapshots older than this are deleted
+	DownsampleAfter  time.Duration // snapshots older than this are thinned out
+	DownsampleBucket time.Duration // one snapshot per bucket is kept, besides status and certificate changes
+}
+
+// Smtp configures how emails are sent
 type Smtp struct {
+	Provider string // smtp, http (a transactional mail api), file or log (for development), capture (kept in memory, for tests)
 	Sender   string
 	Password string
+	Host     string
+	Port     int
+	TLSMode  string // starttls, tls or none
+	Username string // the sender when empty
+	Auth     string // plain, login, crammd5 or none
+	APIURL   string // the mail api of the http provider
+	APIKey   string
+	Dir      string // the .eml files of the file provider go here
 }
 
 type Google struct {
 	Conf *oauth2.Config
 }
 
+// Slack is the app users install to their workspace, the webhook url of a channel can be pasted without it
+type Slack struct {
+	Conf *oauth2.Config
+}
+
 type Postgres struct {
 	Database string
 	User     string
@@ -49,6 +120,16 @@ func Load() Config {
 
 	conf := viper.New()
 	conf.AutomaticEnv()
+	// a zero shutdown timeout would drop the work in flight right away
+	conf.SetDefault("SHUTDOWN_TIMEOUT", 30*time.Second)
+	// deployments whose env predates the scheduler have no tick, and a zero ticker panics
+	conf.SetDefault("SCHEDULER_TICK", time.Minute)
+	conf.SetDefault("MAIL_PROVIDER", "smtp")
+	conf.SetDefault("SMS_MONTHLY_CAP", 30)
+	conf.SetDefault("SMTP_HOST", "smtp.gmail.com")
+	conf.SetDefault("SMTP_PORT", 587)
+	conf.SetDefault("SMTP_TLS", "starttls")
+	conf.SetDefault("SMTP_AUTH", "plain")
 
 	return Config{
 		HttpPort:                    conf.GetString("HTTP_PORT"),
@@ -79,12 +160,86 @@ func Load() Config {
 				Endpoint: google.Endpoint,
 			},
 		},
+		Slack: Slack{
+			Conf: &oauth2.Config{
+				ClientID:     conf.GetString("SLACK_CLIENT_ID"),
+				ClientSecret: conf.GetString("SLACK_CLIENT_SECRET"),
+				RedirectURL:  conf.GetString("SLACK_REDIRECT_URI"),
+				Scopes:       []string{"incoming-webhook"},
+				Endpoint: oauth2.Endpoint{
+					AuthURL:   "https://slack.com/oauth/v2/authorize",
+					TokenURL:  "https://slack.com/api/oauth.v2.access",
+					AuthStyle: oauth2.AuthStyleInParams,
+				},
+			},
+		},
 		Smtp: Smtp{
+			Provider: conf.GetString("MAIL_PROVIDER"),
 			Sender:   conf.GetString("SMTP_SENDER"),
 			Password: conf.GetString("SMTP_PASSWORD"),
+			Host:     conf.GetString("SMTP_HOST"),
+			Port:     conf.GetInt("SMTP_PORT"),
+			TLSMode:  conf.GetString("SMTP_TLS"),
+			Username: conf.GetString("SMTP_USERNAME"),
+			Auth:     conf.GetString("SMTP_AUTH"),
+			APIURL:   conf.GetString("MAIL_API_URL"),
+			APIKey:   conf.GetString("MAIL_API_KEY"),
+			Dir:      conf.GetString("MAIL_DIR"),
 		},
 		PullUpdateDomainInterval: conf.GetDuration("PULL_UPDATE_DOMAIN_INTERVAL"),
 		TelegramApiToken:         conf.GetString("TELEGRAM_APITOKEN"),
 		TelegramBotUsername:      conf.GetString("TELEGRAM_BOT_USERNAME"),
+		AcmeDirectories:          parseAcmeDirectories(conf.GetString("ACME_DIRECTORIES")),
+		RenewalOverdueGrace:      conf.GetDuration("RENEWAL_OVERDUE_GRACE"),
+		LeaderElectionInterval:   conf.GetDuration("LEADER_ELECTION_INTERVAL"),
+		ShutdownTimeout:          conf.GetDuration("SHUTDOWN_TIMEOUT"),
+		Scheduler: Scheduler{
+			Tick:                    conf.GetDuration("SCHEDULER_TICK"),
+			DefaultMinCheckInterval: conf.GetDuration("PLAN_MIN_CHECK_INTERVAL"),
+			MaxCheckInterval:        conf.GetDuration("MAX_CHECK_INTERVAL"),
+			AdaptiveMinInterval:     conf.GetDuration("ADAPTIVE_MIN_CHECK_INTERVAL"),
+			Workers:                 conf.GetInt("POLL_WORKERS"),
+			JobLease:                conf.GetDuration("POLL_JOB_LEASE"),
+			JobMaxAttempts:          conf.GetInt("POLL_JOB_MAX_ATTEMPTS"),
+			JobRetryBackoff:         conf.GetDuration("POLL_JOB_RETRY_BACKOFF"),
+			Jitter:                  conf.GetFloat64("POLL_JITTER"),
+			MaxPerIP:                conf.GetInt("POLL_MAX_PER_IP"),
+			MaxPerDomain:            conf.GetInt("POLL_MAX_PER_DOMAIN"),
+		},
+		Alerts: Alerts{
+			DispatchInterval: conf.GetDuration("ALERT_DISPATCH_INTERVAL"),
+			Lease:            conf.GetDuration("ALERT_LEASE"),
+			MaxAttempts:      conf.GetInt("ALERT_MAX_ATTEMPTS"),
+			RetryBackoff:     conf.GetDuration("ALERT_RETRY_BACKOFF"),
+		},
+		Webhooks: Webhooks{
+			AllowPrivateNetworks: conf.GetBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS"),
+		},
+		SMS: SMS{
+			Provider:   conf.GetString("SMS_PROVIDER"),
+			BaseURL:    conf.GetString("SMS_BASE_URL"),
+			AccountSID: conf.GetString("SMS_ACCOUNT_SID"),
+			AuthToken:  conf.GetString("SMS_AUTH_TOKEN"),
+			From:       conf.GetString("SMS_FROM"),
+			MonthlyCap: conf.GetInt("SMS_MONTHLY_CAP"),
+		},
+		History: History{
+			Retention:        conf.GetDuration("HISTORY_RETENTION"),
+			DownsampleAfter:  conf.GetDuration("HISTORY_DOWNSAMPLE_AFTER"),
+			DownsampleBucket: conf.GetDuration("HISTORY_DOWNSAMPLE_BUCKET"),
+		},
+	}
+}
+
+// parseAcmeDirectories parses "Issuer Org=https://acme/directory,Other Org=https://..." into issuer -> directory url
+func parseAcmeDirectories(value string) map[string]string {
+	directories := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		issuer, url, found := strings.Cut(pair, "=")
+		if !found {
+			continue
+		}
+		directories[strings.TrimSpace(issuer)] = strings.TrimSpace(url)
 	}
+	return directories
 }
@@ -0,0 +1,10 @@
+-- ACME Renewal Information (ARI) suggested windows, one row per tracked domain name
+CREATE TABLE IF NOT EXISTS "renewal_info" (
+    "domain" VARCHAR PRIMARY KEY,
+    "cert_id" VARCHAR NOT NULL,
+    "window_start" TIMESTAMP NOT NULL,
+    "window_end" TIMESTAMP NOT NULL,
+    "explanation_url" VARCHAR,
+    "retry_after" TIMESTAMP NOT NULL,
+    "checked_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
@@ -0,0 +1,12 @@
+-- every distinct certificate a tracked domain name has served
+CREATE TABLE IF NOT EXISTS "certificates" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "domain" VARCHAR NOT NULL,
+    "fingerprint" VARCHAR NOT NULL,
+    "issuer" VARCHAR,
+    "issued" TIMESTAMP,
+    "expires" TIMESTAMP,
+    "first_seen" TIMESTAMP NOT NULL,
+    "last_seen" TIMESTAMP NOT NULL,
+    UNIQUE ("domain", "fingerprint")
+);
@@ -0,0 +1,13 @@
+-- append-only results of every poll of a tracked domain name
+CREATE TABLE IF NOT EXISTS "poll_history" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "domain" VARCHAR NOT NULL,
+    "status" VARCHAR,
+    "latency" BIGINT,
+    "error" VARCHAR,
+    "remote_address" VARCHAR,
+    "fingerprint" VARCHAR,
+    "polled_at" TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "poll_history_domain_polled_at_idx" ON "poll_history" ("domain", "polled_at");
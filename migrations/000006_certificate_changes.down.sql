@@ -0,0 +1,3 @@
+ALTER TABLE "notifications"
+    DROP COLUMN "change_alert_fields";
+DROP TABLE IF EXISTS "certificate_changes";
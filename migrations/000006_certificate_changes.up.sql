@@ -0,0 +1,16 @@
+-- field by field changes of the certificate of a tracked domain name
+CREATE TABLE IF NOT EXISTS "certificate_changes" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "domain" VARCHAR NOT NULL,
+    "field" VARCHAR NOT NULL,
+    "old_value" VARCHAR NOT NULL,
+    "new_value" VARCHAR NOT NULL,
+    "detected_at" TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "certificate_changes_domain_idx" ON "certificate_changes" ("domain", "detected_at");
+
+-- which changed fields are worth a change alert for the user
+ALTER TABLE "notifications"
+    ADD COLUMN "change_alert_fields" VARCHAR[] NOT NULL
+    DEFAULT '{issuer,dns_names,public_key,key_usage,ext_key_usages,expires,remote_address}';
@@ -0,0 +1,6 @@
+ALTER TABLE "users"
+    DROP COLUMN "min_check_interval";
+ALTER TABLE "tracking_domains"
+    DROP COLUMN "priority";
+ALTER TABLE "tracking_domains"
+    DROP COLUMN "check_interval";
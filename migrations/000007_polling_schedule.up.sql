@@ -0,0 +1,9 @@
+-- per domain check interval in minutes, NULL means the default PULL_UPDATE_DOMAIN_INTERVAL
+ALTER TABLE "tracking_domains"
+    ADD COLUMN "check_interval" INT;
+-- priority class: low, normal or high
+ALTER TABLE "tracking_domains"
+    ADD COLUMN "priority" VARCHAR NOT NULL DEFAULT 'normal';
+-- shortest check interval in minutes the user's plan allows, NULL means the default plan limit
+ALTER TABLE "users"
+    ADD COLUMN "min_check_interval" INT;
@@ -0,0 +1,18 @@
+-- persistent queue of domain polls, shared by every running instance
+CREATE TABLE IF NOT EXISTS "poll_jobs" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "domain" VARCHAR NOT NULL,
+    "priority" INT NOT NULL DEFAULT 1,
+    "status" VARCHAR NOT NULL DEFAULT 'pending', -- pending, leased, failed
+    "attempts" INT NOT NULL DEFAULT 0,
+    "max_attempts" INT NOT NULL DEFAULT 3,
+    "run_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "leased_by" VARCHAR,
+    "lease_until" TIMESTAMP,
+    "last_error" VARCHAR,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- a domain has at most one job waiting or running
+CREATE UNIQUE INDEX IF NOT EXISTS "poll_jobs_active_domain_idx" ON "poll_jobs" ("domain") WHERE "status" IN ('pending', 'leased');
+CREATE INDEX IF NOT EXISTS "poll_jobs_runnable_idx" ON "poll_jobs" ("status", "priority" DESC, "run_at");
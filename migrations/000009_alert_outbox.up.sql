@@ -0,0 +1,33 @@
+-- outbox of alerts, written in the same transaction as the poll result that raised them
+CREATE TABLE IF NOT EXISTS "alerts" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    "domain" VARCHAR NOT NULL,
+    "type" VARCHAR NOT NULL,
+    "channel" VARCHAR NOT NULL, -- email, telegram
+    "payload" JSONB NOT NULL DEFAULT '{}',
+    "status" VARCHAR NOT NULL DEFAULT 'pending', -- pending, sending, sent, dead
+    "attempts" INT NOT NULL DEFAULT 0,
+    "max_attempts" INT NOT NULL DEFAULT 8,
+    "next_attempt_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "leased_by" VARCHAR,
+    "lease_until" TIMESTAMP,
+    "last_error" VARCHAR,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    "sent_at" TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "alerts_due_idx" ON "alerts" ("status", "next_attempt_at");
+CREATE INDEX IF NOT EXISTS "alerts_user_id_idx" ON "alerts" ("user_id", "created_at" DESC);
+
+-- every attempt to deliver an alert
+CREATE TABLE IF NOT EXISTS "alert_deliveries" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "alert_id" BIGINT REFERENCES alerts(id) ON DELETE CASCADE,
+    "attempt" INT NOT NULL,
+    "status" VARCHAR NOT NULL, -- sent, failed
+    "error" VARCHAR,
+    "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "alert_deliveries_alert_id_idx" ON "alert_deliveries" ("alert_id");
@@ -0,0 +1,19 @@
+-- one problem of one domain as seen by one of its owners
+CREATE TABLE IF NOT EXISTS "incidents" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    "domain" VARCHAR NOT NULL,
+    "problem" VARCHAR NOT NULL, -- expiring, expired, invalid, offline, changed
+    "status" VARCHAR NOT NULL DEFAULT 'open', -- open, acknowledged, snoozed, resolved
+    "ack_token" VARCHAR NOT NULL UNIQUE,
+    "opened_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "acknowledged_at" TIMESTAMP,
+    "snoozed_until" TIMESTAMP,
+    "resolved_at" TIMESTAMP,
+    "last_notified_at" TIMESTAMP
+);
+
+-- a user has at most one unresolved incident per domain and problem
+CREATE UNIQUE INDEX IF NOT EXISTS "incidents_unresolved_idx" ON "incidents" ("user_id", "domain", "problem") WHERE "status" <> 'resolved';
+
+ALTER TABLE "alerts" ADD COLUMN IF NOT EXISTS "incident_id" BIGINT REFERENCES incidents(id) ON DELETE SET NULL;
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS "sent_reminders";
+ALTER TABLE "certificates"
+    DROP COLUMN "serial";
+ALTER TABLE "tracking_domains"
+    DROP COLUMN "reminder_days";
+ALTER TABLE "notifications"
+    ADD COLUMN "before" INT DEFAULT 30;
+UPDATE "notifications" SET "before" = COALESCE((SELECT max("day") FROM unnest("reminder_days") AS "day"), 30);
+ALTER TABLE "notifications"
+    DROP COLUMN "reminder_days";
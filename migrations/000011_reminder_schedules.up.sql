@@ -0,0 +1,28 @@
+-- days before expiry the expiry reminders go out at, replaces the single "before" setting
+ALTER TABLE "notifications"
+    ADD COLUMN "reminder_days" INT[] NOT NULL DEFAULT '{30,14,7,3,1}';
+UPDATE "notifications" SET "reminder_days" = ARRAY(
+    SELECT "day" FROM unnest('{60,30,20,14,7,3,1}'::INT[]) AS "day" WHERE "day" <= COALESCE("before", 30)
+);
+ALTER TABLE "notifications"
+    DROP COLUMN "before";
+
+-- per domain reminder days, NULL means the schedule of the user
+ALTER TABLE "tracking_domains"
+    ADD COLUMN "reminder_days" INT[];
+
+-- serial number of the certificate, NULL for the certificates seen before it was recorded
+ALTER TABLE "certificates"
+    ADD COLUMN "serial" VARCHAR;
+
+-- every reminder a user got about a certificate, so each one goes out once per certificate
+CREATE TABLE IF NOT EXISTS "sent_reminders" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    "domain" VARCHAR NOT NULL,
+    "serial" VARCHAR NOT NULL,
+    "kind" VARCHAR NOT NULL, -- expiry, expired, renewal, overdue
+    "days" INT NOT NULL, -- days before expiry for expiry, days after it for expired
+    "sent_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE ("user_id", "domain", "serial", "kind", "days")
+);
@@ -0,0 +1,10 @@
+ALTER TABLE "alerts"
+    DROP COLUMN "recipient";
+ALTER TABLE "incidents"
+    DROP COLUMN "escalation_step";
+ALTER TABLE "incidents"
+    DROP COLUMN "escalation_policy_id";
+DROP TABLE IF EXISTS "escalation_steps";
+DROP TABLE IF EXISTS "escalation_policies";
+ALTER TABLE "tracking_domains"
+    DROP COLUMN "tags";
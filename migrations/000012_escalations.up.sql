@@ -0,0 +1,32 @@
+-- labels the owner groups the domain by, escalation policies can be attached to them
+ALTER TABLE "tracking_domains"
+    ADD COLUMN "tags" VARCHAR[] NOT NULL DEFAULT '{}';
+
+-- who else is alerted, and when, about an incident nobody acts on
+CREATE TABLE IF NOT EXISTS "escalation_policies" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    "name" VARCHAR NOT NULL,
+    "domains" VARCHAR[] NOT NULL DEFAULT '{}', -- domain names the policy is attached to
+    "tags" VARCHAR[] NOT NULL DEFAULT '{}', -- domains with any of these tags get the policy too
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS "escalation_steps" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "policy_id" BIGINT NOT NULL REFERENCES escalation_policies(id) ON DELETE CASCADE,
+    "position" INT NOT NULL, -- steps are taken in the order of their positions
+    "delay" INT NOT NULL, -- minutes after the incident opened
+    "channel" VARCHAR NOT NULL,
+    "recipients" VARCHAR[] NOT NULL DEFAULT '{}' -- email addresses or telegram chat ids, empty means the owner
+);
+
+ALTER TABLE "incidents"
+    ADD COLUMN "escalation_policy_id" BIGINT REFERENCES escalation_policies(id) ON DELETE SET NULL;
+-- position of the last escalation step taken, 0 before the first one
+ALTER TABLE "incidents"
+    ADD COLUMN "escalation_step" INT NOT NULL DEFAULT 0;
+
+-- who the alert goes to when it is not the owner: an email address or a telegram chat id
+ALTER TABLE "alerts"
+    ADD COLUMN "recipient" VARCHAR;
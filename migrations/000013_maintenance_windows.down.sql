@@ -0,0 +1,6 @@
+DELETE FROM "alerts" WHERE "status" = 'suppressed';
+ALTER TABLE "alerts"
+    DROP COLUMN "suppressed_by";
+ALTER TABLE "tracking_domains"
+    DROP COLUMN "snoozed_until";
+DROP TABLE IF EXISTS "maintenance_windows";
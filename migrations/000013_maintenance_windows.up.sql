@@ -0,0 +1,22 @@
+-- planned work on domains, their alerts are recorded but not delivered while a window is active
+CREATE TABLE IF NOT EXISTS "maintenance_windows" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    "name" VARCHAR NOT NULL,
+    "domains" VARCHAR[] NOT NULL DEFAULT '{}',
+    "tags" VARCHAR[] NOT NULL DEFAULT '{}',
+    "starts_at" TIMESTAMP, -- one-off window
+    "ends_at" TIMESTAMP,
+    "cron" VARCHAR, -- recurring window, starts at every match of the cron expression
+    "duration" INT, -- minutes a recurring window lasts
+    "timezone" VARCHAR NOT NULL, -- the cron expression is read in it
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- alerts about the domain are recorded but not delivered until then
+ALTER TABLE "tracking_domains"
+    ADD COLUMN "snoozed_until" TIMESTAMP;
+
+-- why a suppressed alert was not delivered
+ALTER TABLE "alerts"
+    ADD COLUMN "suppressed_by" VARCHAR;
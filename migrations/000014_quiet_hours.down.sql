@@ -0,0 +1,4 @@
+ALTER TABLE "notifications"
+    DROP COLUMN "quiet_hours_end",
+    DROP COLUMN "quiet_hours_start",
+    DROP COLUMN "timezone";
@@ -0,0 +1,5 @@
+-- the timezone alerts are timed in, apart from the session timezone that is guessed from the ip address
+ALTER TABLE "notifications"
+    ADD COLUMN "timezone" VARCHAR NOT NULL DEFAULT 'UTC',
+    ADD COLUMN "quiet_hours_start" SMALLINT, -- minutes after midnight, NULL means no quiet hours
+    ADD COLUMN "quiet_hours_end" SMALLINT;
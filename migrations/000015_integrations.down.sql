@@ -0,0 +1,3 @@
+ALTER TABLE "alerts"
+    DROP COLUMN "integration_id";
+DROP TABLE IF EXISTS "integrations";
@@ -0,0 +1,14 @@
+-- chat and incident tools the alerts of a user go to, besides email and telegram
+CREATE TABLE IF NOT EXISTS "integrations" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    "kind" VARCHAR NOT NULL, -- slack
+    "name" VARCHAR NOT NULL, -- what the user sees, e.g. the workspace and the channel
+    "config" JSONB NOT NULL DEFAULT '{}', -- webhook url, tokens and the like, depending on the kind
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS "integrations_user_id_idx" ON "integrations" ("user_id");
+
+-- the connection an alert goes to, for the channels that can have several
+ALTER TABLE "alerts"
+    ADD COLUMN "integration_id" BIGINT REFERENCES integrations(id) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+DELETE FROM "integrations" WHERE "kind" = 'webhook';
+ALTER TABLE "notifications"
+    DROP COLUMN "webhook_alert";
@@ -0,0 +1,3 @@
+-- signed json events to the user's own webhook endpoints, kept in the integrations table
+ALTER TABLE "notifications"
+    ADD COLUMN "webhook_alert" BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+DELETE FROM "integrations" WHERE "kind" IN ('pagerduty', 'opsgenie');
+ALTER TABLE "notifications"
+    DROP COLUMN "pagerduty_alert",
+    DROP COLUMN "opsgenie_alert";
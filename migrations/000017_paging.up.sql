@@ -0,0 +1,4 @@
+-- pagerduty services and opsgenie teams the incidents of the user are paged to, kept in the integrations table
+ALTER TABLE "notifications"
+    ADD COLUMN "pagerduty_alert" BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN "opsgenie_alert" BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+DELETE FROM "integrations" WHERE "kind" IN ('ntfy', 'gotify', 'pushover', 'matrix');
+ALTER TABLE "notifications"
+    DROP COLUMN "ntfy_alert",
+    DROP COLUMN "gotify_alert",
+    DROP COLUMN "pushover_alert",
+    DROP COLUMN "matrix_alert";
@@ -0,0 +1,6 @@
+-- ntfy topics, gotify applications, pushover users and matrix rooms the alerts are pushed to, kept in the integrations table
+ALTER TABLE "notifications"
+    ADD COLUMN "ntfy_alert" BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN "gotify_alert" BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN "pushover_alert" BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN "matrix_alert" BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS "sms_usage";
+DELETE FROM "integrations" WHERE "kind" = 'sms';
+ALTER TABLE "users"
+    DROP COLUMN "max_sms_per_month";
+ALTER TABLE "notifications"
+    DROP COLUMN "sms_alert";
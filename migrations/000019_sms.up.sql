@@ -0,0 +1,15 @@
+-- on-call phones the last-minute expiry and the expired alerts are texted to, kept in the integrations table once verified
+ALTER TABLE "notifications"
+    ADD COLUMN "sms_alert" BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- text messages the user gets a month, NULL means the default cap
+ALTER TABLE "users"
+    ADD COLUMN "max_sms_per_month" INT;
+
+-- text messages sent to the user in a month, verification codes included
+CREATE TABLE IF NOT EXISTS "sms_usage" (
+    "user_id" BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "month" DATE NOT NULL, -- the first day of the month, in UTC
+    "sent" INT NOT NULL DEFAULT 0,
+    PRIMARY KEY ("user_id", "month")
+);
@@ -0,0 +1,3 @@
+ALTER TABLE "alerts"
+    DROP COLUMN "routed_by";
+DROP TABLE IF EXISTS "routing_rules";
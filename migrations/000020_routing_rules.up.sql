@@ -0,0 +1,20 @@
+-- where the alerts of the user go, the first rule that matches an alert routes it, an alert no rule matches goes everywhere
+CREATE TABLE IF NOT EXISTS "routing_rules" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    "name" VARCHAR NOT NULL,
+    "position" INT NOT NULL, -- rules are tried in the order of their positions
+    "domains" VARCHAR[] NOT NULL DEFAULT '{}', -- domain name patterns, like *.example.com
+    "tags" VARCHAR[] NOT NULL DEFAULT '{}',
+    "alert_types" VARCHAR[] NOT NULL DEFAULT '{}',
+    "severities" VARCHAR[] NOT NULL DEFAULT '{}', -- empty conditions match every alert
+    "channels" VARCHAR[] NOT NULL DEFAULT '{}', -- email and telegram
+    "integration_ids" BIGINT[] NOT NULL DEFAULT '{}', -- connections of the integrations table
+    "created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "routing_rules_user_id_idx" ON "routing_rules" ("user_id", "position");
+
+-- the routing rule that addressed the alert, NULL when no rule matched it
+ALTER TABLE "alerts"
+    ADD COLUMN "routed_by" VARCHAR;
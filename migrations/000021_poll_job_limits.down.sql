@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "poll_jobs_leased_site_idx";
+DROP INDEX IF EXISTS "poll_jobs_leased_ip_idx";
+ALTER TABLE "poll_jobs"
+    DROP COLUMN "site",
+    DROP COLUMN "ip";
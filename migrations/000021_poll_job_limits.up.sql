@@ -0,0 +1,8 @@
+-- the IP address the domain answered from at its last poll and its registrable domain, the leases cap the jobs
+-- running at once against each of them over all the instances
+ALTER TABLE "poll_jobs"
+    ADD COLUMN "ip" VARCHAR,
+    ADD COLUMN "site" VARCHAR;
+
+CREATE INDEX IF NOT EXISTS "poll_jobs_leased_ip_idx" ON "poll_jobs" ("ip") WHERE "status" = 'leased';
+CREATE INDEX IF NOT EXISTS "poll_jobs_leased_site_idx" ON "poll_jobs" ("site") WHERE "status" = 'leased';
@@ -0,0 +1,2 @@
+ALTER TABLE "escalation_steps"
+    DROP COLUMN "integration_id";
@@ -0,0 +1,4 @@
+-- a step can page one of the integrations of the user instead of alerting over email or telegram,
+-- its channel is then the kind of the integration. Disconnecting the integration removes its steps.
+ALTER TABLE "escalation_steps"
+    ADD COLUMN "integration_id" BIGINT REFERENCES integrations(id) ON DELETE CASCADE;
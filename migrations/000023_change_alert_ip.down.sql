@@ -0,0 +1,3 @@
+ALTER TABLE "notifications"
+    ALTER COLUMN "change_alert_fields"
+    SET DEFAULT '{issuer,dns_names,public_key,key_usage,ext_key_usages,expires,remote_address}';
@@ -0,0 +1,8 @@
+-- the ip address of a domain behind round-robin dns or a cdn changes from poll to poll, its change is still listed
+-- on the changes page but is no change alert unless the user turns it on
+ALTER TABLE "notifications"
+    ALTER COLUMN "change_alert_fields"
+    SET DEFAULT '{issuer,dns_names,public_key,key_usage,ext_key_usages,expires}';
+
+UPDATE "notifications" SET "change_alert_fields" = array_remove("change_alert_fields", 'remote_address')
+    WHERE "change_alert_fields" = '{issuer,dns_names,public_key,key_usage,ext_key_usages,expires,remote_address}';
@@ -0,0 +1,182 @@
+package ari
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownIssuer     = errors.New("no acme directory is configured for the issuer")
+	ErrNoRenewalInfo     = errors.New("acme directory does not advertise renewalInfo")
+	ErrInvalidCert       = errors.New("certificate pem is not valid")
+	ErrMissingAuthKeyID  = errors.New("certificate has no authority key identifier")
+	defaultRetryInterval = 6 * time.Hour
+)
+
+// RenewalInfo is the suggested renewal window that the CA published for one certificate.
+type RenewalInfo struct {
+	CertID         string
+	WindowStart    time.Time
+	WindowEnd      time.Time
+	ExplanationURL string
+	RetryAfter     time.Time
+}
+
+type renewalInfoResp struct {
+	SuggestedWindow struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	} `json:"suggestedWindow"`
+	ExplanationURL string `json:"explanationURL"`
+}
+
+type directoryResp struct {
+	RenewalInfo string `json:"renewalInfo"`
+}
+
+// Client queries the ACME Renewal Information (ARI) endpoints of the configured CAs.
+type Client struct {
+	httpClient  *http.Client
+	directories map[string]string // issuer organization -> acme directory url
+
+	mu           sync.Mutex
+	renewalInfos map[string]string // acme directory url -> renewalInfo url
+}
+
+func NewClient(directories map[string]string) *Client {
+	return &Client{
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		directories:  directories,
+		renewalInfos: make(map[string]string),
+	}
+}
+
+// GetRenewalInfo finds the ACME directory of the issuer, and asks its renewalInfo endpoint
+// for the suggested renewal window of the certificate in encodedPEM.
+func (c *Client) GetRenewalInfo(ctx context.Context, issuer, encodedPEM string) (*RenewalInfo, error) {
+	directory, ok := c.directories[issuer]
+	if !ok {
+		return nil, ErrUnknownIssuer
+	}
+
+	certID, err := CertIDFromPEM(encodedPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := c.renewalInfoURL(ctx, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/"+certID, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("renewalInfo for %s responded with status %d", certID, resp.StatusCode)
+	}
+
+	var body renewalInfoResp
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return &RenewalInfo{
+		CertID:         certID,
+		WindowStart:    body.SuggestedWindow.Start,
+		WindowEnd:      body.SuggestedWindow.End,
+		ExplanationURL: body.ExplanationURL,
+		RetryAfter:     retryAfter(resp.Header.Get("Retry-After")),
+	}, nil
+}
+
+// renewalInfoURL reads the directory object once and remembers its renewalInfo url.
+func (c *Client) renewalInfoURL(ctx context.Context, directory string) (string, error) {
+	c.mu.Lock()
+	endpoint, ok := c.renewalInfos[directory]
+	c.mu.Unlock()
+	if ok {
+		return endpoint, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, directory, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("acme directory %s responded with status %d", directory, resp.StatusCode)
+	}
+
+	var body directoryResp
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.RenewalInfo == "" {
+		return "", ErrNoRenewalInfo
+	}
+
+	c.mu.Lock()
+	c.renewalInfos[directory] = body.RenewalInfo
+	c.mu.Unlock()
+
+	return body.RenewalInfo, nil
+}
+
+// CertIDFromPEM builds the ARI certificate identifier: base64url(authority key identifier) "." base64url(serial number).
+func CertIDFromPEM(encodedPEM string) (string, error) {
+	block, _ := pem.Decode([]byte(encodedPEM))
+	if block == nil {
+		return "", ErrInvalidCert
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	if len(cert.AuthorityKeyId) == 0 {
+		return "", ErrMissingAuthKeyID
+	}
+
+	// The serial is encoded as the bytes of a DER INTEGER, so a positive number
+	// with the high bit set needs a leading zero byte.
+	serial := cert.SerialNumber.Bytes()
+	if len(serial) > 0 && serial[0]&0x80 != 0 {
+		serial = append([]byte{0}, serial...)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(cert.AuthorityKeyId) + "." + base64.RawURLEncoding.EncodeToString(serial), nil
+}
+
+func retryAfter(header string) time.Time {
+	if header == "" {
+		return time.Now().Add(defaultRetryInterval)
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	if tm, err := http.ParseTime(header); err == nil {
+		return tm
+	}
+	return time.Now().Add(defaultRetryInterval)
+}
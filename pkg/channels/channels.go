@@ -0,0 +1,118 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// severities of a message, the same as the ones of the alerts
+const (
+	SeverityCritical = "critical"
+	SeverityError    = "error"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// Message is what a channel posts, rendered from the templates of the connection
+type Message struct {
+	ID       string // the same on every attempt of one alert, a channel that can deduplicate uses it
+	Title    string
+	Body     string
+	Severity string
+	URL      string // the page of the domain
+}
+
+// Config is the settings of a connection, the fields used depend on the channel
+type Config struct {
+	ServerURL string // ntfy server, gotify server, matrix homeserver
+	Topic     string // ntfy topic
+	Token     string // ntfy access token, gotify application token, pushover application token, matrix access token
+	UserKey   string // pushover user or group key
+	RoomID    string // matrix room
+}
+
+// Channel pushes messages to the user over a service that needs nothing but the settings of the connection.
+// A new one is added to NewRegistry and shows up on the integrations page with its own connection form.
+type Channel interface {
+	// Kind is the kind of the integrations of the channel
+	Kind() string
+	// Title is the human readable name of the channel
+	Title() string
+	// Validate checks the settings of a new connection, filling in the defaults of the ones left empty
+	Validate(cfg *Config) error
+	// DefaultTemplates returns the text/templates of the title and of the body a new connection starts with,
+	// in the markup the channel shows
+	DefaultTemplates() (title string, body string)
+	// Send posts the message
+	Send(ctx context.Context, cfg *Config, msg *Message) error
+}
+
+// Registry holds the channels by kind
+type Registry struct {
+	channels map[string]Channel
+	kinds    []string
+}
+
+// NewRegistry returns the registry of every channel, sending with the http client
+func NewRegistry(httpClient *http.Client) *Registry {
+	r := &Registry{channels: make(map[string]Channel)}
+	r.Register(&Ntfy{httpClient: httpClient})
+	r.Register(&Gotify{httpClient: httpClient})
+	r.Register(&Pushover{httpClient: httpClient, apiURL: PushoverURL})
+	r.Register(&Matrix{httpClient: httpClient})
+	return r
+}
+
+// Register adds the channel, replacing the one of the same kind
+func (r *Registry) Register(ch Channel) {
+	if _, ok := r.channels[ch.Kind()]; !ok {
+		r.kinds = append(r.kinds, ch.Kind())
+	}
+	r.channels[ch.Kind()] = ch
+}
+
+// Get returns the channel of the kind
+func (r *Registry) Get(kind string) (Channel, bool) {
+	ch, ok := r.channels[kind]
+	return ch, ok
+}
+
+// Kinds returns the kinds of the channels in the order they were registered
+func (r *Registry) Kinds() []string {
+	return r.kinds
+}
+
+// validateServerURL checks the url of a server, trimming its trailing slash
+func validateServerURL(cfg *Config, what string) error {
+	cfg.ServerURL = strings.TrimRight(strings.TrimSpace(cfg.ServerURL), "/")
+	u, err := url.Parse(cfg.ServerURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("paste the url of the %s, starting with https://", what)
+	}
+	return nil
+}
+
+// do sends the request, an answer but a 2xx is an error with the start of its body
+func do(httpClient *http.Client, req *http.Request, name string) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// the url can carry a secret, like the topic of ntfy, keep it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("%s: %w", name, urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		answer, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s answered %s: %s", name, resp.Status, strings.TrimSpace(string(answer)))
+	}
+	return nil
+}
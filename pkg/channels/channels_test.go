@@ -0,0 +1,197 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// request is what the test server got
+type request struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// newServer records the requests it gets and answers them with the status
+func newServer(t *testing.T, status int) (*httptest.Server, *[]request) {
+	t.Helper()
+	got := make([]request, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, request{method: r.Method, path: r.URL.EscapedPath(), header: r.Header.Clone(), body: body})
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("nope"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+var testMessage = &Message{
+	ID:       "alert-1",
+	Title:    "Expired: example.com",
+	Body:     "**example.com** has an expired <SSL> certificate.",
+	Severity: SeverityCritical,
+	URL:      "https://certalert.info/domains/more/1",
+}
+
+func TestNtfySend(t *testing.T) {
+	srv, got := newServer(t, http.StatusOK)
+	ch := &Ntfy{httpClient: srv.Client()}
+	cfg := &Config{ServerURL: srv.URL, Topic: "alerts", Token: "tk_secret"}
+
+	if err := ch.Send(context.Background(), cfg, testMessage); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(*got) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*got))
+	}
+	req := (*got)[0]
+	if req.method != http.MethodPost || req.path != "/" {
+		t.Errorf("got %s %s, want POST /", req.method, req.path)
+	}
+	if auth := req.header.Get("Authorization"); auth != "Bearer tk_secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	var msg ntfyMessage
+	if err := json.Unmarshal(req.body, &msg); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	if msg.Topic != "alerts" || msg.Title != testMessage.Title || msg.Message != testMessage.Body ||
+		msg.Click != testMessage.URL || !msg.Markdown || msg.Priority != 5 {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestGotifySend(t *testing.T) {
+	srv, got := newServer(t, http.StatusOK)
+	ch := &Gotify{httpClient: srv.Client()}
+	cfg := &Config{ServerURL: srv.URL, Token: "app-token"}
+
+	if err := ch.Send(context.Background(), cfg, testMessage); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	req := (*got)[0]
+	if req.method != http.MethodPost || req.path != "/message" {
+		t.Errorf("got %s %s, want POST /message", req.method, req.path)
+	}
+	if key := req.header.Get("X-Gotify-Key"); key != "app-token" {
+		t.Errorf("X-Gotify-Key = %q", key)
+	}
+	var msg gotifyMessage
+	if err := json.Unmarshal(req.body, &msg); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	if msg.Title != testMessage.Title || msg.Message != testMessage.Body || msg.Priority != 8 {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestPushoverSend(t *testing.T) {
+	srv, got := newServer(t, http.StatusOK)
+	ch := &Pushover{httpClient: srv.Client(), apiURL: srv.URL + "/1/messages.json"}
+	cfg := &Config{Token: strings.Repeat("a", 30), UserKey: strings.Repeat("u", 30)}
+	msg := *testMessage
+	msg.Body = strings.Repeat("x", maxPushoverMessage+10)
+
+	if err := ch.Send(context.Background(), cfg, &msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	req := (*got)[0]
+	if req.method != http.MethodPost || req.path != "/1/messages.json" {
+		t.Errorf("got %s %s, want POST /1/messages.json", req.method, req.path)
+	}
+	form, err := url.ParseQuery(string(req.body))
+	if err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	if form.Get("token") != cfg.Token || form.Get("user") != cfg.UserKey || form.Get("priority") != "1" || form.Get("url") != msg.URL {
+		t.Errorf("unexpected form %v", form)
+	}
+	if n := len([]rune(form.Get("message"))); n != maxPushoverMessage {
+		t.Errorf("message is %d characters, want it cut to %d", n, maxPushoverMessage)
+	}
+}
+
+func TestMatrixSend(t *testing.T) {
+	srv, got := newServer(t, http.StatusOK)
+	ch := &Matrix{httpClient: srv.Client()}
+	cfg := &Config{ServerURL: srv.URL, RoomID: "!room:example.org", Token: "syt_token"}
+
+	if err := ch.Send(context.Background(), cfg, testMessage); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	req := (*got)[0]
+	wantPath := "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/alert-1"
+	if req.method != http.MethodPut || req.path != wantPath {
+		t.Errorf("got %s %s, want PUT %s", req.method, req.path, wantPath)
+	}
+	if auth := req.header.Get("Authorization"); auth != "Bearer syt_token" {
+		t.Errorf("Authorization = %q", auth)
+	}
+	var msg matrixMessage
+	if err := json.Unmarshal(req.body, &msg); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	if msg.MsgType != "m.notice" || !strings.HasPrefix(msg.Body, testMessage.Title+"\n") {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if !strings.Contains(msg.FormattedBody, "&lt;SSL&gt;") {
+		t.Errorf("formatted body is not escaped: %q", msg.FormattedBody)
+	}
+}
+
+func TestSendFails(t *testing.T) {
+	srv, _ := newServer(t, http.StatusUnauthorized)
+	ch := &Ntfy{httpClient: srv.Client()}
+	err := ch.Send(context.Background(), &Config{ServerURL: srv.URL, Topic: "alerts"}, testMessage)
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("got %v, want the status and the answer of the server", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	key := strings.Repeat("k", 30)
+	tests := []struct {
+		name  string
+		ch    Channel
+		cfg   Config
+		valid bool
+	}{
+		{"ntfy default server", &Ntfy{}, Config{Topic: "alerts"}, true},
+		{"ntfy bad topic", &Ntfy{}, Config{Topic: "no spaces"}, false},
+		{"gotify", &Gotify{}, Config{ServerURL: "https://push.example.com/", Token: "t"}, true},
+		{"gotify no token", &Gotify{}, Config{ServerURL: "https://push.example.com"}, false},
+		{"gotify bad url", &Gotify{}, Config{ServerURL: "push.example.com", Token: "t"}, false},
+		{"pushover", &Pushover{}, Config{Token: key, UserKey: key}, true},
+		{"pushover short key", &Pushover{}, Config{Token: key, UserKey: "short"}, false},
+		{"matrix", &Matrix{}, Config{ServerURL: "https://matrix.org", RoomID: " !abc:matrix.org ", Token: "t"}, true},
+		{"matrix room alias", &Matrix{}, Config{ServerURL: "https://matrix.org", RoomID: "#room:matrix.org", Token: "t"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			err := tt.ch.Validate(&cfg)
+			if (err == nil) != tt.valid {
+				t.Errorf("Validate(%+v) = %v, want valid %v", tt.cfg, err, tt.valid)
+			}
+		})
+	}
+
+	cfg := Config{Topic: "alerts"}
+	_ = (&Ntfy{}).Validate(&cfg)
+	if cfg.ServerURL != NtfyServer {
+		t.Errorf("ntfy server = %q, want %q", cfg.ServerURL, NtfyServer)
+	}
+	cfg = Config{ServerURL: "https://push.example.com/", Token: "t"}
+	_ = (&Gotify{}).Validate(&cfg)
+	if cfg.ServerURL != "https://push.example.com" {
+		t.Errorf("gotify server = %q, want the trailing slash trimmed", cfg.ServerURL)
+	}
+}
@@ -0,0 +1,77 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Gotify pushes to an application of a gotify server
+type Gotify struct {
+	httpClient *http.Client
+}
+
+type gotifyMessage struct {
+	Title    string                 `json:"title,omitempty"`
+	Message  string                 `json:"message"`
+	Priority int                    `json:"priority"` // 0 to 10, the clients notify from 4 up by default
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+func (g *Gotify) Kind() string  { return "gotify" }
+func (g *Gotify) Title() string { return "Gotify" }
+
+func (g *Gotify) Validate(cfg *Config) error {
+	if err := validateServerURL(cfg, "gotify server"); err != nil {
+		return err
+	}
+	if cfg.Token == "" {
+		return errors.New("paste the token of a gotify application")
+	}
+	return nil
+}
+
+func (g *Gotify) DefaultTemplates() (string, string) {
+	return "{{.Alert}}: {{.Domain}}",
+		"**{{.Domain}}** {{.Headline}}." +
+			"{{if .Expires}}\n\nExpires {{.Expires}}, {{.DaysLeft}} days left.{{end}}" +
+			"{{if .Error}}\n\nError: {{.Error}}{{end}}" +
+			"{{if .Changes}}\n{{range .Changes}}\n- {{.}}{{end}}{{end}}" +
+			"\n\n[View domain]({{.Link}})"
+}
+
+func (g *Gotify) Send(ctx context.Context, cfg *Config, msg *Message) error {
+	body, err := json.Marshal(&gotifyMessage{
+		Title:    msg.Title,
+		Message:  msg.Body,
+		Priority: gotifyPriority(msg.Severity),
+		Extras: map[string]interface{}{
+			"client::display":      map[string]string{"contentType": "text/markdown"},
+			"client::notification": map[string]interface{}{"click": map[string]string{"url": msg.URL}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.ServerURL+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", cfg.Token)
+	return do(g.httpClient, req, "gotify")
+}
+
+func gotifyPriority(severity string) int {
+	switch severity {
+	case SeverityCritical:
+		return 8
+	case SeverityError:
+		return 6
+	case SeverityWarning:
+		return 4
+	}
+	return 2
+}
@@ -0,0 +1,79 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Matrix sends notices to a room over the client-server api, as the user of the access token
+type Matrix struct {
+	httpClient *http.Client
+}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+func (m *Matrix) Kind() string  { return "matrix" }
+func (m *Matrix) Title() string { return "Matrix" }
+
+func (m *Matrix) Validate(cfg *Config) error {
+	if err := validateServerURL(cfg, "matrix homeserver"); err != nil {
+		return err
+	}
+	cfg.RoomID = strings.TrimSpace(cfg.RoomID)
+	if !strings.HasPrefix(cfg.RoomID, "!") || !strings.Contains(cfg.RoomID, ":") {
+		return errors.New("paste the internal id of the room, like !abcdef:matrix.org, from its advanced settings")
+	}
+	if cfg.Token == "" {
+		return errors.New("paste the access token of the user that sends the alerts, it has to be in the room")
+	}
+	return nil
+}
+
+func (m *Matrix) DefaultTemplates() (string, string) {
+	return "{{.Alert}}: {{.Domain}}",
+		"{{.Domain}} {{.Headline}}." +
+			"{{if .Expires}}\nExpires {{.Expires}}, {{.DaysLeft}} days left.{{end}}" +
+			"{{if .Error}}\nError: {{.Error}}{{end}}" +
+			"{{range .Changes}}\n• {{.}}{{end}}" +
+			"\n{{.Link}}"
+}
+
+// Send sends the message as a notice, which bots send so clients don't answer it. The id of the message is the
+// transaction id, so a retried alert that reached the homeserver already is not posted twice.
+func (m *Matrix) Send(ctx context.Context, cfg *Config, msg *Message) error {
+	plain := msg.Body
+	formatted := strings.ReplaceAll(html.EscapeString(msg.Body), "\n", "<br>")
+	if msg.Title != "" {
+		plain = msg.Title + "\n" + plain
+		formatted = "<b>" + html.EscapeString(msg.Title) + "</b><br>" + formatted
+	}
+	body, err := json.Marshal(&matrixMessage{
+		MsgType:       "m.notice",
+		Body:          plain,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted,
+	})
+	if err != nil {
+		return err
+	}
+	endpoint := cfg.ServerURL + "/_matrix/client/v3/rooms/" + url.PathEscape(cfg.RoomID) +
+		"/send/m.room.message/" + url.PathEscape(msg.ID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	return do(m.httpClient, req, "matrix")
+}
@@ -0,0 +1,101 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+)
+
+// NtfyServer is the public ntfy server, used when the connection names none
+const NtfyServer = "https://ntfy.sh"
+
+var ntfyTopicRegexp = regexp.MustCompile(`^[-_A-Za-z0-9]{1,64}$`)
+
+// Ntfy publishes to a topic of a ntfy server
+type Ntfy struct {
+	httpClient *http.Client
+}
+
+type ntfyMessage struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title,omitempty"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority,omitempty"` // 1 min to 5 max
+	Tags     []string `json:"tags,omitempty"`     // emoji short codes show up in front of the title
+	Click    string   `json:"click,omitempty"`
+	Markdown bool     `json:"markdown,omitempty"`
+}
+
+func (n *Ntfy) Kind() string  { return "ntfy" }
+func (n *Ntfy) Title() string { return "ntfy" }
+
+func (n *Ntfy) Validate(cfg *Config) error {
+	if cfg.ServerURL == "" {
+		cfg.ServerURL = NtfyServer
+	}
+	if err := validateServerURL(cfg, "ntfy server"); err != nil {
+		return err
+	}
+	if !ntfyTopicRegexp.MatchString(cfg.Topic) {
+		return errors.New("a ntfy topic is up to 64 letters, digits, dashes and underscores")
+	}
+	return nil
+}
+
+func (n *Ntfy) DefaultTemplates() (string, string) {
+	return "{{.Alert}}: {{.Domain}}",
+		"**{{.Domain}}** {{.Headline}}." +
+			"{{if .Expires}}\nExpires {{.Expires}}, {{.DaysLeft}} days left.{{end}}" +
+			"{{if .Error}}\nError: {{.Error}}{{end}}" +
+			"{{range .Changes}}\n- {{.}}{{end}}"
+}
+
+// Send publishes the message as json to the server, so the topic needs no escaping
+func (n *Ntfy) Send(ctx context.Context, cfg *Config, msg *Message) error {
+	body, err := json.Marshal(&ntfyMessage{
+		Topic:    cfg.Topic,
+		Title:    msg.Title,
+		Message:  msg.Body,
+		Priority: ntfyPriority(msg.Severity),
+		Tags:     []string{ntfyTag(msg.Severity)},
+		Click:    msg.URL,
+		Markdown: true,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.ServerURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+	return do(n.httpClient, req, "ntfy")
+}
+
+func ntfyPriority(severity string) int {
+	switch severity {
+	case SeverityCritical:
+		return 5
+	case SeverityError:
+		return 4
+	case SeverityWarning:
+		return 3
+	}
+	return 2
+}
+
+func ntfyTag(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return "rotating_light"
+	case SeverityError, SeverityWarning:
+		return "warning"
+	}
+	return "information_source"
+}
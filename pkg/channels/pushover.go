@@ -0,0 +1,88 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PushoverURL is the endpoint of the messages api
+const PushoverURL = "https://api.pushover.net/1/messages.json"
+
+const (
+	// pushover cuts the title at 250 characters and the message at 1024
+	maxPushoverTitle   = 250
+	maxPushoverMessage = 1024
+)
+
+var pushoverKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9]{30}$`)
+
+// Pushover pushes to the devices of a pushover user or group
+type Pushover struct {
+	httpClient *http.Client
+	apiURL     string
+}
+
+func (p *Pushover) Kind() string  { return "pushover" }
+func (p *Pushover) Title() string { return "Pushover" }
+
+func (p *Pushover) Validate(cfg *Config) error {
+	if !pushoverKeyRegexp.MatchString(cfg.UserKey) {
+		return errors.New("paste the 30 character user or group key of pushover")
+	}
+	if !pushoverKeyRegexp.MatchString(cfg.Token) {
+		return errors.New("paste the 30 character api token of a pushover application")
+	}
+	return nil
+}
+
+func (p *Pushover) DefaultTemplates() (string, string) {
+	return "{{.Alert}}: {{.Domain}}",
+		"{{.Domain}} {{.Headline}}." +
+			"{{if .Expires}}\nExpires {{.Expires}}, {{.DaysLeft}} days left.{{end}}" +
+			"{{if .Error}}\nError: {{.Error}}{{end}}" +
+			"{{range .Changes}}\n• {{.}}{{end}}"
+}
+
+func (p *Pushover) Send(ctx context.Context, cfg *Config, msg *Message) error {
+	form := url.Values{
+		"token":     {cfg.Token},
+		"user":      {cfg.UserKey},
+		"title":     {truncate(msg.Title, maxPushoverTitle)},
+		"message":   {truncate(msg.Body, maxPushoverMessage)},
+		"priority":  {strconv.Itoa(pushoverPriority(msg.Severity))},
+		"url":       {msg.URL},
+		"url_title": {"View domain"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.apiURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return do(p.httpClient, req, "pushover")
+}
+
+// pushoverPriority returns the priority of the message, up to high: the emergency one repeats until it is acknowledged
+// in pushover, the incidents are acknowledged in certalert
+func pushoverPriority(severity string) int {
+	switch severity {
+	case SeverityCritical, SeverityError:
+		return 1
+	case SeverityWarning:
+		return 0
+	}
+	return -1
+}
+
+// truncate cuts the text to max characters, marking the cut with an ellipsis
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,122 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExpression = errors.New("cron expression must have five fields: minute hour day-of-month month day-of-week")
+
+// Schedule is a parsed five field cron expression. Every field accepts *, numbers, ranges (1-5),
+// lists (1,15) and steps (*/15, 0-30/10). Sunday is 0 or 7 in the day of week field.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit n is set when value n matches
+	domAny, dowAny                bool
+}
+
+type bounds struct {
+	min, max int
+}
+
+var fieldBounds = []bounds{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week
+}
+
+// Parse parses the five field cron expression
+func Parse(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, ErrInvalidExpression
+	}
+
+	bits := make([]uint64, 5)
+	for i, field := range fields {
+		b, err := parseField(field, fieldBounds[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron field %q: %w", field, err)
+		}
+		bits[i] = b
+	}
+	// Sunday can be written as 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, errors.New("step is not a positive number")
+			}
+		}
+
+		start, end := b.min, b.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, errors.New("range start is not a number")
+			}
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, errors.New("range end is not a number")
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, errors.New("value is not a number")
+			}
+			start = value
+			if step == 1 {
+				end = value
+			}
+		}
+		if start < b.min || end > b.max || start > end {
+			return 0, fmt.Errorf("values must be between %d and %d", b.min, b.max)
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Matches reports whether the minute of t, in its own location, is one of the schedule.
+// As in cron, when both day fields are restricted a day matches if either of them does.
+func (s *Schedule) Matches(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 || s.hour&(1<<uint(t.Hour())) == 0 || s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
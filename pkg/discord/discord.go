@@ -0,0 +1,221 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// retries of one message after a rate limit or a server error
+	maxRetries = 3
+	// longer waits are left to the caller, the message is retried later
+	maxRateLimitWait = 30 * time.Second
+)
+
+// WebhookPrefixes are where the webhooks of discord live
+var WebhookPrefixes = []string{
+	"https://discord.com/api/webhooks/",
+	"https://discordapp.com/api/webhooks/",
+	"https://ptb.discord.com/api/webhooks/",
+	"https://canary.discord.com/api/webhooks/",
+}
+
+// IsWebhookURL tells if the url is a discord webhook
+func IsWebhookURL(webhookURL string) bool {
+	for _, prefix := range WebhookPrefixes {
+		if strings.HasPrefix(webhookURL, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Message is what a webhook posts, up to 10 embeds
+type Message struct {
+	Username        string           `json:"username,omitempty"`
+	Content         string           `json:"content,omitempty"`
+	Embeds          []*Embed         `json:"embeds,omitempty"`
+	AllowedMentions *AllowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+// Embed is a rich card with a coloured stripe on the left
+type Embed struct {
+	Title       string   `json:"title,omitempty"` // up to 256 characters
+	Description string   `json:"description,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Color       int      `json:"color,omitempty"`
+	Fields      []*Field `json:"fields,omitempty"` // up to 25
+	Footer      *Footer  `json:"footer,omitempty"`
+	Timestamp   string   `json:"timestamp,omitempty"` // ISO 8601
+}
+
+// Field is a name and a value shown in the embed, inline fields share a row
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"` // up to 1024 characters
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type Footer struct {
+	Text string `json:"text"`
+}
+
+// AllowedMentions with an empty Parse keeps the texts from outside, like certificate fields, from pinging anyone
+type AllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// RateLimitError is returned when discord asks to wait longer than the client is willing to
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Global     bool
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Global {
+		return fmt.Sprintf("discord is globally rate limited, retry after %s", e.RetryAfter)
+	}
+	return fmt.Sprintf("discord webhook is rate limited, retry after %s", e.RetryAfter)
+}
+
+type rateLimitResp struct {
+	RetryAfter float64 `json:"retry_after"` // seconds
+	Global     bool    `json:"global"`
+}
+
+// Client posts messages to webhooks. It keeps to the rate limit discord reports for each webhook,
+// and retries a message that was rate limited or hit a server error.
+type Client struct {
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	resetAt map[string]time.Time // webhook url -> when its rate limit bucket refills
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		resetAt:    make(map[string]time.Time),
+	}
+}
+
+// PostWebhook posts the message to the webhook
+func (c *Client) PostWebhook(ctx context.Context, webhookURL string, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForBucket(ctx, webhookURL); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			// the url carries the token of the webhook, keep it out of the error
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				return fmt.Errorf("discord webhook: %w", urlErr.Err)
+			}
+			return err
+		}
+		answer, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		c.updateBucket(webhookURL, resp.Header)
+
+		var wait time.Duration
+		switch {
+		case resp.StatusCode/100 == 2:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests:
+			limit := retryAfter(resp.Header, answer)
+			if attempt >= maxRetries || limit.RetryAfter > maxRateLimitWait {
+				return limit
+			}
+			wait = limit.RetryAfter
+		case resp.StatusCode >= 500:
+			if attempt >= maxRetries {
+				return fmt.Errorf("discord webhook answered %s", resp.Status)
+			}
+			wait = time.Second << attempt
+		default:
+			return fmt.Errorf("discord webhook answered %s: %s", resp.Status, strings.TrimSpace(string(answer)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// waitForBucket waits until the rate limit bucket of the webhook refills, if it ran out
+func (c *Client) waitForBucket(ctx context.Context, webhookURL string) error {
+	c.mu.Lock()
+	wait := time.Until(c.resetAt[webhookURL])
+	c.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	if wait > maxRateLimitWait {
+		return &RateLimitError{RetryAfter: wait}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// updateBucket remembers when the bucket of the webhook refills once the response says it is empty
+func (c *Client) updateBucket(webhookURL string, header http.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		delete(c.resetAt, webhookURL)
+		return
+	}
+	resetAfter, err := strconv.ParseFloat(header.Get("X-RateLimit-Reset-After"), 64)
+	if err != nil {
+		return
+	}
+	c.resetAt[webhookURL] = time.Now().Add(time.Duration(resetAfter * float64(time.Second)))
+}
+
+// retryAfter reads how long to wait from the body of a 429 answer, or from its Retry-After header
+func retryAfter(header http.Header, body []byte) *RateLimitError {
+	limit := &RateLimitError{RetryAfter: time.Second}
+
+	var resp rateLimitResp
+	if err := json.Unmarshal(body, &resp); err == nil && resp.RetryAfter > 0 {
+		limit.RetryAfter = time.Duration(resp.RetryAfter * float64(time.Second))
+		limit.Global = resp.Global
+	} else if seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64); err == nil {
+		limit.RetryAfter = time.Duration(seconds * float64(time.Second))
+	}
+	if header.Get("X-RateLimit-Global") == "true" {
+		limit.Global = true
+	}
+	return limit
+}
@@ -2,51 +2,82 @@ package email
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 	"html/template"
-	"net/smtp"
-
-	"github.com/SaidovZohid/certalert.info/config"
+	"os"
+	texttemplate "text/template"
 )
 
 type SendEmailRequest struct {
-	To      []string
-	Type    string
-	Body    map[string]string
-	Subject string
+	To          []string
+	Type        string
+	Body        map[string]string
+	Items       []map[string]string // rows of the email, e.g. the alerts of a digest, available as .items in the templates
+	Subject     string
+	Unsubscribe string // link that stops the alert emails, also sent in the List-Unsubscribe header
 }
 
 const (
 	VerificationEmail   = "verification_email"
 	ForgotPasswordEmail = "forgot_password_email"
 	ChangeEmail         = "change_email"
+	ExpiryAlertEmail    = "expiry_alert_email"
+	ChangeAlertEmail    = "change_alert_email"
+	RecoveryAlertEmail  = "recovery_alert_email"
+	ProblemAlertEmail   = "problem_alert_email"
+	DigestEmail         = "digest_email"
 )
 
-func SendEmail(cfg *config.Config, req *SendEmailRequest) error {
-	from := cfg.Smtp.Sender
-	to := req.To
-
-	password := cfg.Smtp.Password
-
-	var body bytes.Buffer
-
-	templatePath := getTemplatePath(req.Type)
-	t, err := template.ParseFiles(templatePath)
+// SendEmail renders the email of the request and hands it to the mailer
+func SendEmail(ctx context.Context, mailer Mailer, req *SendEmailRequest) error {
+	msg, err := Render(req)
 	if err != nil {
 		return err
 	}
-	t.Execute(&body, req.Body)
+	return mailer.Send(ctx, msg)
+}
 
-	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	subject := fmt.Sprintf("Subject: %s\n", req.Subject)
-	msg := []byte(subject + mime + body.String())
+// Render renders the templates of the email type. Types with a plain text template get a text alternative.
+func Render(req *SendEmailRequest) (*Message, error) {
+	data := make(map[string]interface{}, len(req.Body)+2)
+	for k, v := range req.Body {
+		data[k] = v
+	}
+	data["items"] = req.Items
+	data["unsubscribe"] = req.Unsubscribe
 
-	auth := smtp.PlainAuth("", from, password, "smtp.gmail.com")
-	err = smtp.SendMail("smtp.gmail.com:587", auth, from, to, msg)
+	var html bytes.Buffer
+	t, err := template.ParseFiles(getTemplatePath(req.Type))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := t.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	msg := &Message{
+		To:      req.To,
+		Subject: req.Subject,
+		HTML:    html.String(),
+		Headers: make(map[string]string),
+	}
+	if req.Unsubscribe != "" {
+		msg.Headers["List-Unsubscribe"] = "<" + req.Unsubscribe + ">"
+		msg.Headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
 	}
-	return nil
+
+	if textPath := getTextTemplatePath(req.Type); textPath != "" {
+		var text bytes.Buffer
+		tt, err := texttemplate.ParseFiles(textPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := tt.Execute(&text, data); err != nil {
+			return nil, err
+		}
+		msg.Text = text.String()
+	}
+	return msg, nil
 }
 
 func getTemplatePath(emailType string) string {
@@ -57,6 +88,20 @@ func getTemplatePath(emailType string) string {
 		return "./templates/forgot_password_email.html"
 	case ChangeEmail:
 		return "./templates/change_email.html"
+	case ExpiryAlertEmail, ChangeAlertEmail, RecoveryAlertEmail, ProblemAlertEmail, DigestEmail:
+		return "./templates/" + emailType + ".html"
+	}
+	return ""
+}
+
+// getTextTemplatePath returns the plain text template of the email type, empty when it has none
+func getTextTemplatePath(emailType string) string {
+	switch emailType {
+	case ExpiryAlertEmail, ChangeAlertEmail, RecoveryAlertEmail, ProblemAlertEmail, DigestEmail:
+		path := "./templates/" + emailType + ".txt"
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
 	}
 	return ""
 }
@@ -0,0 +1,83 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/config"
+)
+
+type httpMailer struct {
+	httpClient *http.Client
+	from       string
+	url        string
+	apiKey     string
+}
+
+// httpMailRequest is the body posted to the mail api
+type httpMailRequest struct {
+	MessageID string            `json:"message_id"`
+	From      string            `json:"from"`
+	To        []string          `json:"to"`
+	Subject   string            `json:"subject"`
+	Text      string            `json:"text,omitempty"`
+	HTML      string            `json:"html"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// NewHTTPMailer sends the emails through a transactional mail api. The message is posted as json to the api url
+// with the api key as a bearer token, any 2xx answer means it is accepted.
+func NewHTTPMailer(cfg *config.Smtp) (Mailer, error) {
+	if cfg.APIURL == "" {
+		return nil, errors.New("mail api url is not configured")
+	}
+	return &httpMailer{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		from:       cfg.Sender,
+		url:        cfg.APIURL,
+		apiKey:     cfg.APIKey,
+	}, nil
+}
+
+func (m *httpMailer) Send(ctx context.Context, msg *Message) error {
+	msg.prepare(m.from)
+	body, err := json.Marshal(&httpMailRequest{
+		MessageID: msg.ID,
+		From:      msg.From,
+		To:        msg.To,
+		Subject:   msg.Subject,
+		Text:      msg.Text,
+		HTML:      msg.HTML,
+		Headers:   msg.Headers,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		answer, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("mail api answered %s: %s", resp.Status, answer)
+	}
+	return nil
+}
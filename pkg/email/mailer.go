@@ -0,0 +1,40 @@
+package email
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+)
+
+// providers a Mailer can be made for, see config.Smtp.Provider
+const (
+	ProviderSMTP    = "smtp"
+	ProviderHTTP    = "http"
+	ProviderFile    = "file"
+	ProviderLog     = "log"
+	ProviderCapture = "capture"
+)
+
+// Mailer delivers rendered emails
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewMailer makes the mailer of the configured provider
+func NewMailer(cfg *config.Config, log logger.Logger) (Mailer, error) {
+	switch cfg.Smtp.Provider {
+	case ProviderSMTP, "":
+		return NewSMTPMailer(&cfg.Smtp)
+	case ProviderHTTP:
+		return NewHTTPMailer(&cfg.Smtp)
+	case ProviderFile:
+		return NewFileMailer(cfg.Smtp.Sender, cfg.Smtp.Dir)
+	case ProviderLog:
+		return NewLogMailer(cfg.Smtp.Sender, log), nil
+	case ProviderCapture:
+		return NewCaptureMailer(cfg.Smtp.Sender), nil
+	}
+	return nil, fmt.Errorf("unknown mail provider %s", cfg.Smtp.Provider)
+}
@@ -0,0 +1,121 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is a rendered email, ready to be handed to a Mailer
+type Message struct {
+	ID      string // Message-ID without the angle brackets, made up when the message is sent
+	Date    time.Time
+	From    string
+	To      []string
+	Subject string
+	Text    string            // plain text alternative, may be empty
+	HTML    string            // html body
+	Headers map[string]string // extra headers, e.g. List-Unsubscribe
+}
+
+// prepare fills what the sending mailer knows: the sender, the date and the Message-ID
+func (m *Message) prepare(from string) {
+	if m.From == "" {
+		m.From = from
+	}
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+	if m.ID == "" {
+		m.ID = newMessageID(m.From)
+	}
+}
+
+// newMessageID returns a unique Message-ID in the domain of the sender
+func newMessageID(from string) string {
+	domain := "certalert.info"
+	if _, host, ok := strings.Cut(from, "@"); ok && host != "" {
+		domain = strings.TrimSuffix(host, ">")
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b) + "@" + domain
+}
+
+// Bytes encodes the message as RFC 5322 with MIME bodies. A message with a text alternative is multipart/alternative,
+// the text part first so that clients prefer the html one.
+func (m *Message) Bytes() ([]byte, error) {
+	var msg bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", key, value)
+	}
+	header("Message-ID", "<"+m.ID+">")
+	header("Date", m.Date.Format(time.RFC1123Z))
+	header("From", m.From)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	keys := make([]string, 0, len(m.Headers))
+	for key := range m.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		header(key, m.Headers[key])
+	}
+	header("MIME-Version", "1.0")
+
+	if m.Text == "" {
+		header("Content-Type", `text/html; charset="UTF-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		msg.WriteString("\r\n")
+		if err := writeQuotedPrintable(&msg, m.HTML); err != nil {
+			return nil, err
+		}
+		return msg.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{`text/plain; charset="UTF-8"`, m.Text},
+		{`text/html; charset="UTF-8"`, m.HTML},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(pw, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	header("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", w.Boundary()))
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
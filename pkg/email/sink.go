@@ -0,0 +1,89 @@
+package email
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+)
+
+type fileMailer struct {
+	from string
+	dir  string
+}
+
+// NewFileMailer writes every email as an .eml file to the directory instead of sending it, for development
+func NewFileMailer(from, dir string) (Mailer, error) {
+	if dir == "" {
+		dir = "mail"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fileMailer{from: from, dir: dir}, nil
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg *Message) error {
+	msg.prepare(m.from)
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.dir, msg.Date.Format("20060102-150405")+"-"+msg.ID+".eml"), data, 0o644)
+}
+
+type logMailer struct {
+	from string
+	log  logger.Logger
+}
+
+// NewLogMailer logs the emails instead of sending them, for development
+func NewLogMailer(from string, log logger.Logger) Mailer {
+	return &logMailer{from: from, log: log}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg *Message) error {
+	msg.prepare(m.from)
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	m.log.Info("Email to ", msg.To, ":\n", string(data))
+	return nil
+}
+
+// CaptureMailer keeps the emails in memory instead of sending them, for tests
+type CaptureMailer struct {
+	from string
+
+	mu       sync.Mutex
+	messages []*Message
+}
+
+func NewCaptureMailer(from string) *CaptureMailer {
+	return &CaptureMailer{from: from}
+}
+
+func (m *CaptureMailer) Send(ctx context.Context, msg *Message) error {
+	msg.prepare(m.from)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns the emails sent so far, oldest first
+func (m *CaptureMailer) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Message(nil), m.messages...)
+}
+
+// Reset forgets the emails sent so far
+func (m *CaptureMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
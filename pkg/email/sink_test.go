@@ -0,0 +1,92 @@
+package email
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+// the templates are read relative to the root of the repository, like the server does
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestCaptureMailer(t *testing.T) {
+	mailer := NewCaptureMailer("CertAlert <alerts@certalert.info>")
+
+	err := SendEmail(context.Background(), mailer, &SendEmailRequest{
+		To:          []string{"user@example.com"},
+		Type:        ProblemAlertEmail,
+		Subject:     "[CertAlert] example.com is not responding",
+		Unsubscribe: "https://certalert.info/unsubscribe/token",
+		Body: map[string]string{
+			"name":         "Zohid",
+			"domain":       "example.com",
+			"headline":     "is not responding",
+			"reason":       "connection refused",
+			"details_link": "https://certalert.info/domains",
+		},
+	})
+	if err != nil {
+		t.Fatalf("SendEmail: %v", err)
+	}
+
+	messages := mailer.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("captured %d emails, want 1", len(messages))
+	}
+	msg := messages[0]
+	if msg.From != "CertAlert <alerts@certalert.info>" || msg.ID == "" || msg.Date.IsZero() {
+		t.Errorf("sender, id and date are not filled in: %+v", msg)
+	}
+	if !strings.HasSuffix(msg.ID, "@certalert.info") {
+		t.Errorf("Message-ID %q is not in the domain of the sender", msg.ID)
+	}
+	if len(msg.To) != 1 || msg.To[0] != "user@example.com" {
+		t.Errorf("To = %v", msg.To)
+	}
+	if msg.Headers["List-Unsubscribe"] != "<https://certalert.info/unsubscribe/token>" {
+		t.Errorf("List-Unsubscribe = %q", msg.Headers["List-Unsubscribe"])
+	}
+	for _, part := range []string{msg.HTML, msg.Text} {
+		if !strings.Contains(part, "example.com") || !strings.Contains(part, "connection refused") {
+			t.Errorf("the email does not say what happened:\n%s", part)
+		}
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	if !strings.Contains(string(data), "multipart/alternative") {
+		t.Error("an email with a text template is not sent as multipart/alternative")
+	}
+
+	mailer.Reset()
+	if n := len(mailer.Messages()); n != 0 {
+		t.Errorf("captured %d emails after Reset, want 0", n)
+	}
+}
+
+func TestCaptureMailerKeepsTheOrder(t *testing.T) {
+	mailer := NewCaptureMailer("alerts@certalert.info")
+	for _, subject := range []string{"first", "second"} {
+		if err := mailer.Send(context.Background(), &Message{To: []string{"user@example.com"}, Subject: subject}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	messages := mailer.Messages()
+	if len(messages) != 2 || messages[0].Subject != "first" || messages[1].Subject != "second" {
+		t.Fatalf("got %v, want first and second in order", messages)
+	}
+	// the returned slice is a copy, appending to it does not change the captured emails
+	_ = append(messages[:1], &Message{Subject: "other"})
+	if mailer.Messages()[1].Subject != "second" {
+		t.Error("Messages does not return a copy")
+	}
+}
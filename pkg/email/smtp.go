@@ -0,0 +1,166 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+
+	"github.com/SaidovZohid/certalert.info/config"
+)
+
+// TLS modes of the smtp connection
+const (
+	TLSStartTLS = "starttls" // plain connection upgraded with STARTTLS, usually port 587
+	TLSImplicit = "tls"      // TLS from the start, usually port 465
+	TLSNone     = "none"     // only for a relay on a trusted network
+)
+
+// auth methods of the smtp server
+const (
+	AuthPlain   = "plain"
+	AuthLogin   = "login"
+	AuthCramMD5 = "crammd5"
+	AuthNone    = "none"
+)
+
+type smtpMailer struct {
+	from    string
+	host    string
+	port    int
+	tlsMode string
+	auth    smtp.Auth
+}
+
+// NewSMTPMailer sends the emails through the smtp server of the config
+func NewSMTPMailer(cfg *config.Smtp) (Mailer, error) {
+	m := &smtpMailer{
+		from:    cfg.Sender,
+		host:    cfg.Host,
+		port:    cfg.Port,
+		tlsMode: cfg.TLSMode,
+	}
+	switch m.tlsMode {
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %s", m.tlsMode)
+	}
+
+	username := cfg.Username
+	if username == "" {
+		username = cfg.Sender
+	}
+	switch cfg.Auth {
+	case AuthPlain:
+		m.auth = smtp.PlainAuth("", username, cfg.Password, cfg.Host)
+	case AuthLogin:
+		m.auth = &loginAuth{username: username, password: cfg.Password, host: cfg.Host}
+	case AuthCramMD5:
+		m.auth = smtp.CRAMMD5Auth(username, cfg.Password)
+	case AuthNone:
+	default:
+		return nil, fmt.Errorf("unknown smtp auth %s", cfg.Auth)
+	}
+	return m, nil
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg *Message) error {
+	msg.prepare(m.from)
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	tlsConfig := &tls.Config{ServerName: m.host}
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	if m.tlsMode == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if m.tlsMode == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		if err := c.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(m.from); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// loginAuth is the LOGIN mechanism some relays ask for instead of PLAIN. Like smtp.PlainAuth it refuses
+// to send the password over a connection that is not encrypted, unless the server is local.
+type loginAuth struct {
+	username, password, host string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	local := server.Name == "localhost" || server.Name == "127.0.0.1" || server.Name == "::1"
+	if !server.TLS && !local {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch string(fromServer) {
+	case "Username:":
+		return []byte(a.username), nil
+	case "Password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected smtp LOGIN challenge %q", fromServer)
+}
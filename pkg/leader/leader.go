@@ -0,0 +1,123 @@
+package leader
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+)
+
+// DefaultRetryInterval is how often the followers try to take the lock when no interval is given
+const DefaultRetryInterval = 15 * time.Second
+
+// Elector elects one leader among the running instances with a Postgres session advisory lock.
+// The lock lives as long as the database session that took it, so when the leader dies
+// or loses its connection another instance takes the lock over on its next try.
+type Elector struct {
+	db    *pgxpool.Pool
+	log   *logger.Logger
+	name  string
+	key   int64
+	retry time.Duration
+}
+
+// NewElector returns an elector for the lock called name. Instances that use the same name compete for the same lock.
+// retry is how often a follower tries to take the lock and how often the leader checks it still holds it.
+func NewElector(db *pgxpool.Pool, log *logger.Logger, name string, retry time.Duration) *Elector {
+	if retry <= 0 {
+		retry = DefaultRetryInterval
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(name))
+
+	return &Elector{
+		db:    db,
+		log:   log,
+		name:  name,
+		key:   int64(h.Sum64()),
+		retry: retry,
+	}
+}
+
+// Run blocks until ctx is canceled. Whenever this instance is the leader, lead runs with a context
+// that is canceled as soon as the leadership is lost, and Run waits for lead to return before trying again.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	for {
+		if err := e.tryLead(ctx, lead); err != nil && ctx.Err() == nil {
+			e.log.Errorf("Leader election %s failed: %s", e.name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.retry):
+		}
+	}
+}
+
+// tryLead takes the lock if it is free and leads until the lock or ctx is lost
+func (e *Elector) tryLead(ctx context.Context, lead func(ctx context.Context)) error {
+	conn, err := e.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+	// Closing the session is the only way to be sure the lock is gone, even when the last check timed out
+	// on a healthy connection. A closed connection is dropped from the pool on release.
+	defer conn.Conn().Close(context.Background())
+
+	e.log.Infof("This instance is the leader of %s", e.name)
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+
+	ticker := time.NewTicker(e.retry)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			e.log.Infof("Stepped down as the leader of %s", e.name)
+			return nil
+		case <-ctx.Done():
+			cancel()
+			<-done
+			e.log.Infof("Stepped down as the leader of %s", e.name)
+			return nil
+		case <-ticker.C:
+			if err := e.ping(ctx, conn); err != nil {
+				e.log.Errorf("Lost the leadership of %s: %s", e.name, err)
+				cancel()
+				<-done
+				return nil
+			}
+		}
+	}
+}
+
+// ping checks the session holding the lock is still alive
+func (e *Elector) ping(ctx context.Context, conn *pgxpool.Conn) error {
+	ctxPing, cancel := context.WithTimeout(ctx, e.retry)
+	defer cancel()
+
+	_, err := conn.Exec(ctxPing, "SELECT 1")
+	return err
+}
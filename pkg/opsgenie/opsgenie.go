@@ -0,0 +1,114 @@
+package opsgenie
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// regions of an Opsgenie account, each has its own api
+const (
+	RegionUS = "us"
+	RegionEU = "eu"
+)
+
+var apiURLs = map[string]string{
+	RegionUS: "https://api.opsgenie.com",
+	RegionEU: "https://api.eu.opsgenie.com",
+}
+
+// Priorities an alert can have, from the worst
+var Priorities = []string{"P1", "P2", "P3", "P4", "P5"}
+
+// IsRegion tells if the region has an api
+func IsRegion(region string) bool {
+	_, ok := apiURLs[region]
+	return ok
+}
+
+// Alert is an alert of the Alerts API. An alert created with the alias of an open one is grouped into it,
+// so the alias works as the dedup key.
+type Alert struct {
+	Message     string            `json:"message"` // at most 130 characters
+	Alias       string            `json:"alias,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+}
+
+// Close closes the open alert of an alias
+type Close struct {
+	Source string `json:"source,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Client calls the Alerts API
+type Client struct {
+	httpClient *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// CreateAlert creates the alert, or groups it into the open alert of its alias
+func (c *Client) CreateAlert(ctx context.Context, region, apiKey string, alert *Alert) error {
+	return c.post(ctx, region, apiKey, "/v2/alerts", alert)
+}
+
+// CloseAlert closes the open alert of the alias, an alias with no open alert is not an error
+func (c *Client) CloseAlert(ctx context.Context, region, apiKey, alias string, req *Close) error {
+	return c.post(ctx, region, apiKey, "/v2/alerts/"+url.PathEscape(alias)+"/close?identifierType=alias", req)
+}
+
+// post calls the api. Requests are processed asynchronously, the api answers 202 once it has taken one.
+func (c *Client) post(ctx context.Context, region, apiKey, path string, v interface{}) error {
+	base, ok := apiURLs[region]
+	if !ok {
+		return fmt.Errorf("opsgenie: unknown region %q", region)
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("opsgenie: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		answer, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(answer, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("opsgenie answered %s: %s", resp.Status, apiErr.Message)
+		}
+		return fmt.Errorf("opsgenie answered %s: %s", resp.Status, strings.TrimSpace(string(answer)))
+	}
+	return nil
+}
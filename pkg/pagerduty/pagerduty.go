@@ -0,0 +1,117 @@
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EventsURL is the endpoint of the Events API v2
+const EventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// actions of an event
+const (
+	ActionTrigger     = "trigger"
+	ActionAcknowledge = "acknowledge"
+	ActionResolve     = "resolve"
+)
+
+// Severities an event can have, from the worst
+var Severities = []string{"critical", "error", "warning", "info"}
+
+// Event is an event of the Events API v2. Events with the same dedup key are about the same alert of the service:
+// triggers while it is open are grouped into it, a resolve closes it.
+type Event struct {
+	RoutingKey  string   `json:"routing_key"`
+	EventAction string   `json:"event_action"`
+	DedupKey    string   `json:"dedup_key,omitempty"`
+	Payload     *Payload `json:"payload,omitempty"` // only of a trigger
+	Client      string   `json:"client,omitempty"`
+	ClientURL   string   `json:"client_url,omitempty"`
+	Links       []*Link  `json:"links,omitempty"`
+}
+
+type Payload struct {
+	Summary       string            `json:"summary"` // at most 1024 characters
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type Link struct {
+	Href string `json:"href"`
+	Text string `json:"text,omitempty"`
+}
+
+// IsRoutingKey tells if the key looks like the integration key of an Events API v2 integration
+func IsRoutingKey(key string) bool {
+	if len(key) != 32 {
+		return false
+	}
+	for _, r := range key {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Client sends events to the Events API
+type Client struct {
+	httpClient *http.Client
+	eventsURL  string
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		eventsURL:  EventsURL,
+	}
+}
+
+// Send sends the event. The API answers 202 once it has taken the event, 429 when the routing key sends too many.
+func (c *Client) Send(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.eventsURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("pagerduty: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		answer, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		var apiErr struct {
+			Message string   `json:"message"`
+			Errors  []string `json:"errors"`
+		}
+		if json.Unmarshal(answer, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("pagerduty answered %s: %s %s", resp.Status, apiErr.Message, strings.Join(apiErr.Errors, ", "))
+		}
+		return fmt.Errorf("pagerduty answered %s: %s", resp.Status, strings.TrimSpace(string(answer)))
+	}
+	return nil
+}
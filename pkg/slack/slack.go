@@ -0,0 +1,161 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// WebhookPrefix is where the incoming webhooks of slack live
+const WebhookPrefix = "https://hooks.slack.com/"
+
+var ErrNoWebhook = errors.New("slack install has no incoming webhook")
+
+// Message is a Block Kit message. Text is shown in notifications and by clients that can't show the blocks.
+type Message struct {
+	Text   string   `json:"text"`
+	Blocks []*Block `json:"blocks,omitempty"`
+}
+
+// Block is one block of a message: header, section, actions, context or divider
+type Block struct {
+	Type     string        `json:"type"`
+	Text     *Text         `json:"text,omitempty"`
+	Fields   []*Text       `json:"fields,omitempty"`
+	Elements []interface{} `json:"elements,omitempty"` // buttons of an actions block, texts of a context block
+}
+
+// Text is a plain_text or a mrkdwn text object
+type Text struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// Element is a button of an actions block
+type Element struct {
+	Type  string `json:"type"`
+	Text  *Text  `json:"text,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Style string `json:"style,omitempty"` // primary or danger
+}
+
+func PlainText(text string) *Text {
+	return &Text{Type: "plain_text", Text: text, Emoji: true}
+}
+
+func Markdown(text string) *Text {
+	return &Text{Type: "mrkdwn", Text: text}
+}
+
+// Escape escapes the characters that have a meaning in mrkdwn, for the texts that come from outside
+func Escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// Header is a large bold title, slack cuts it at 150 characters
+func Header(text string) *Block {
+	if len([]rune(text)) > 150 {
+		text = string([]rune(text)[:149]) + "…"
+	}
+	return &Block{Type: "header", Text: PlainText(text)}
+}
+
+func Section(text *Text, fields ...*Text) *Block {
+	return &Block{Type: "section", Text: text, Fields: fields}
+}
+
+// Context is a row of small grey texts
+func Context(texts ...*Text) *Block {
+	elements := make([]interface{}, 0, len(texts))
+	for _, text := range texts {
+		elements = append(elements, text)
+	}
+	return &Block{Type: "context", Elements: elements}
+}
+
+// Actions is a row of buttons
+func Actions(buttons ...*Element) *Block {
+	elements := make([]interface{}, 0, len(buttons))
+	for _, button := range buttons {
+		elements = append(elements, button)
+	}
+	return &Block{Type: "actions", Elements: elements}
+}
+
+// Button opens url in the browser
+func Button(text, url, style string) *Element {
+	return &Element{Type: "button", Text: PlainText(text), URL: url, Style: style}
+}
+
+// Install is what an app install to a workspace gives back
+type Install struct {
+	TeamID     string
+	TeamName   string
+	Channel    string
+	WebhookURL string
+}
+
+// InstallFromToken reads the workspace and the incoming webhook the user picked from the oauth token of the install
+func InstallFromToken(token *oauth2.Token) (*Install, error) {
+	install := &Install{}
+	if team, ok := token.Extra("team").(map[string]interface{}); ok {
+		install.TeamID, _ = team["id"].(string)
+		install.TeamName, _ = team["name"].(string)
+	}
+	webhook, ok := token.Extra("incoming_webhook").(map[string]interface{})
+	if !ok {
+		return nil, ErrNoWebhook
+	}
+	install.Channel, _ = webhook["channel"].(string)
+	install.WebhookURL, _ = webhook["url"].(string)
+	if install.WebhookURL == "" {
+		return nil, ErrNoWebhook
+	}
+	return install, nil
+}
+
+// Client posts messages to incoming webhooks
+type Client struct {
+	httpClient *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// PostWebhook posts the message to the incoming webhook. Slack answers an error with its code in the body,
+// like no_service for a removed webhook or invalid_blocks.
+func (c *Client) PostWebhook(ctx context.Context, webhookURL string, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		answer, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("slack webhook answered %s: %s", resp.Status, strings.TrimSpace(string(answer)))
+	}
+	return nil
+}
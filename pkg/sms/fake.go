@@ -0,0 +1,63 @@
+package sms
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+)
+
+type logProvider struct {
+	log logger.Logger
+}
+
+// NewLogProvider logs the messages instead of sending them, for development
+func NewLogProvider(log logger.Logger) Provider {
+	return &logProvider{log: log}
+}
+
+func (p *logProvider) Send(ctx context.Context, to, body string) error {
+	p.log.Info("SMS to ", to, ": ", body)
+	return nil
+}
+
+// Message is a text message the fake provider got
+type Message struct {
+	To   string
+	Body string
+}
+
+// Fake keeps the messages in memory instead of sending them, for tests. A set Err fails every send.
+type Fake struct {
+	mu       sync.Mutex
+	messages []Message
+	Err      error
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) Send(ctx context.Context, to, body string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.messages = append(f.messages, Message{To: to, Body: body})
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first
+func (f *Fake) Messages() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.messages...)
+}
+
+// Reset forgets the messages sent so far
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = nil
+}
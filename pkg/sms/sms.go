@@ -0,0 +1,73 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+)
+
+// providers an SMS Provider can be made for, see config.SMS.Provider
+const (
+	ProviderNone   = ""
+	ProviderTwilio = "twilio"
+	ProviderLog    = "log"
+	ProviderFake   = "fake"
+)
+
+// ErrNotConfigured is returned for an install without an SMS provider
+var ErrNotConfigured = errors.New("sms is not set up on this server")
+
+// phoneRegexp matches a phone number in the E.164 format, like +14155550100
+var phoneRegexp = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// Provider sends text messages
+type Provider interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// NewProvider makes the provider of the configured kind, nil when SMS is off
+func NewProvider(cfg *config.Config, log logger.Logger) (Provider, error) {
+	switch cfg.SMS.Provider {
+	case ProviderNone:
+		return nil, nil
+	case ProviderTwilio:
+		return NewTwilio(&cfg.SMS)
+	case ProviderLog:
+		return NewLogProvider(log), nil
+	case ProviderFake:
+		return NewFake(), nil
+	}
+	return nil, fmt.Errorf("unknown sms provider %s", cfg.SMS.Provider)
+}
+
+// NormalizePhone drops the spaces, dashes, dots and parentheses people write phone numbers with and checks the rest is
+// an international number
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+	if !phoneRegexp.MatchString(phone) {
+		return "", errors.New("write the phone number with its country code, like +14155550100")
+	}
+	return phone, nil
+}
+
+// MaskPhone hides all but the last digits of the number, for the pages and the logs
+func MaskPhone(phone string) string {
+	if len(phone) <= 4 {
+		return phone
+	}
+	return strings.Repeat("•", len(phone)-4) + phone[len(phone)-4:]
+}
@@ -0,0 +1,140 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+)
+
+func TestFakeProvider(t *testing.T) {
+	provider, err := NewProvider(&config.Config{SMS: config.SMS{Provider: ProviderFake}}, logger.Logger{})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	fake, ok := provider.(*Fake)
+	if !ok {
+		t.Fatalf("NewProvider made a %T, want the fake", provider)
+	}
+
+	ctx := context.Background()
+	if err := fake.Send(ctx, "+14155550100", "example.com expired"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := fake.Send(ctx, "+998901234567", "example.com expires tomorrow"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	messages := fake.Messages()
+	want := []Message{{To: "+14155550100", Body: "example.com expired"}, {To: "+998901234567", Body: "example.com expires tomorrow"}}
+	if len(messages) != len(want) || messages[0] != want[0] || messages[1] != want[1] {
+		t.Fatalf("got %v, want %v", messages, want)
+	}
+
+	fake.Err = errors.New("provider is down")
+	if err := fake.Send(ctx, "+14155550100", "lost"); !errors.Is(err, fake.Err) {
+		t.Errorf("Send = %v, want the set error", err)
+	}
+	if n := len(fake.Messages()); n != 2 {
+		t.Errorf("a failed send was kept, %d messages", n)
+	}
+
+	fake.Reset()
+	if n := len(fake.Messages()); n != 0 {
+		t.Errorf("%d messages after Reset, want 0", n)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&config.Config{}, logger.Logger{})
+	if provider != nil || err != nil {
+		t.Errorf("NewProvider without a provider = %v, %v, want SMS off", provider, err)
+	}
+	if _, err := NewProvider(&config.Config{SMS: config.SMS{Provider: "carrier-pigeon"}}, logger.Logger{}); err == nil {
+		t.Error("NewProvider made an unknown provider")
+	}
+	if _, err := NewProvider(&config.Config{SMS: config.SMS{Provider: ProviderTwilio}}, logger.Logger{}); err == nil {
+		t.Error("NewProvider made twilio without its credentials")
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+		valid bool
+	}{
+		{"+1 (415) 555-0100", "+14155550100", true},
+		{"00998 90.123.45.67", "+998901234567", true},
+		{" +447700900123 ", "+447700900123", true},
+		{"4155550100", "", false},
+		{"+0123456789", "", false},
+		{"+1415abc0100", "", false},
+		{"+1234", "", false},
+	}
+	for _, tt := range tests {
+		got, err := NormalizePhone(tt.phone)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, %v, want %q valid %v", tt.phone, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	if got := MaskPhone("+14155550100"); got != "••••••••0100" {
+		t.Errorf("MaskPhone = %q", got)
+	}
+	if got := MaskPhone("+12"); got != "+12" {
+		t.Errorf("MaskPhone of a short number = %q", got)
+	}
+}
+
+func TestTwilioSend(t *testing.T) {
+	var (
+		path string
+		form url.Values
+		user string
+		pass string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		user, pass, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		form, _ = url.ParseQuery(string(body))
+		if form.Get("To") == "+10000000000" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code": 21211, "message": "Invalid 'To' Phone Number"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	provider, err := NewTwilio(&config.SMS{BaseURL: srv.URL + "/", AccountSID: "AC123", AuthToken: "secret", From: "MG456"})
+	if err != nil {
+		t.Fatalf("NewTwilio: %v", err)
+	}
+	if err := provider.Send(context.Background(), "+14155550100", "example.com expired"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if path != "/2010-04-01/Accounts/AC123/Messages.json" {
+		t.Errorf("path = %q", path)
+	}
+	if user != "AC123" || pass != "secret" {
+		t.Errorf("basic auth = %q, %q", user, pass)
+	}
+	if form.Get("To") != "+14155550100" || form.Get("Body") != "example.com expired" ||
+		form.Get("MessagingServiceSid") != "MG456" || form.Get("From") != "" {
+		t.Errorf("unexpected form %v", form)
+	}
+
+	err = provider.Send(context.Background(), "+10000000000", "lost")
+	if err == nil || err.Error() != "sms provider responded 400: Invalid 'To' Phone Number (code 21211)" {
+		t.Errorf("Send = %v, want the error of the provider", err)
+	}
+}
@@ -0,0 +1,93 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/config"
+)
+
+// TwilioURL is the api of twilio, compatible providers are reached by setting their own
+const TwilioURL = "https://api.twilio.com"
+
+// Twilio sends messages over the messages api of twilio, or of a provider with the same api
+type Twilio struct {
+	httpClient *http.Client
+	baseURL    string
+	accountSID string
+	authToken  string
+	from       string // a phone number or the sid of a messaging service
+}
+
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewTwilio makes the twilio provider of the config
+func NewTwilio(cfg *config.SMS) (*Twilio, error) {
+	if cfg.AccountSID == "" || cfg.AuthToken == "" {
+		return nil, errors.New("twilio needs SMS_ACCOUNT_SID and SMS_AUTH_TOKEN")
+	}
+	if cfg.From == "" {
+		return nil, errors.New("twilio needs SMS_FROM, a phone number or a messaging service sid")
+	}
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = TwilioURL
+	}
+	return &Twilio{
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		baseURL:    baseURL,
+		accountSID: cfg.AccountSID,
+		authToken:  cfg.AuthToken,
+		from:       cfg.From,
+	}, nil
+}
+
+func (t *Twilio) Send(ctx context.Context, to, body string) error {
+	form := url.Values{
+		"To":   {to},
+		"Body": {body},
+	}
+	if strings.HasPrefix(t.from, "MG") {
+		form.Set("MessagingServiceSid", t.from)
+	} else {
+		form.Set("From", t.from)
+	}
+	endpoint := t.baseURL + "/2010-04-01/Accounts/" + url.PathEscape(t.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.accountSID, t.authToken)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		// the url has the account sid in it
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("sms provider: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	var apiErr twilioError
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+		return fmt.Errorf("sms provider responded %d: %s (code %d)", resp.StatusCode, apiErr.Message, apiErr.Code)
+	}
+	return fmt.Errorf("sms provider responded %d", resp.StatusCode)
+}
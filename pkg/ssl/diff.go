@@ -0,0 +1,102 @@
+package ssl
+
+import (
+	"time"
+)
+
+// fields of the certificate that are compared between two polls
+const (
+	FieldIssuer       = "issuer"
+	FieldDNSNames     = "dns_names"
+	FieldPublicKey    = "public_key"
+	FieldKeyUsage     = "key_usage"
+	FieldExtKeyUsages = "ext_key_usages"
+	FieldExpires      = "expires"
+	FieldRemoteAddr   = "remote_address"
+)
+
+// ChangeFields lists every field that Diff compares, in the order they are displayed
+var ChangeFields = []string{
+	FieldIssuer,
+	FieldDNSNames,
+	FieldPublicKey,
+	FieldKeyUsage,
+	FieldExtKeyUsages,
+	FieldExpires,
+	FieldRemoteAddr,
+}
+
+var fieldTitles = map[string]string{
+	FieldIssuer:       "Issuer",
+	FieldDNSNames:     "SANs",
+	FieldPublicKey:    "Public Key",
+	FieldKeyUsage:     "Key Usage",
+	FieldExtKeyUsages: "Extended Usage",
+	FieldExpires:      "Expiration Date",
+	FieldRemoteAddr:   "IP Address",
+}
+
+// FieldChange is the old and new value of one certificate field
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// FieldTitle returns the human readable name of the field
+func FieldTitle(field string) string {
+	if title, ok := fieldTitles[field]; ok {
+		return title
+	}
+	return field
+}
+
+// Diff compares the certificate details of two polls field by field.
+// It returns nil when one of the polls got no certificate, going offline is not a certificate change.
+func Diff(prev, current *TrackingDomainInfo) []FieldChange {
+	if prev == nil || current == nil || prev.Expires == nil || current.Expires == nil {
+		return nil
+	}
+
+	var (
+		changes = make([]FieldChange, 0)
+		values  = []struct {
+			field    string
+			old, cur string
+		}{
+			{FieldIssuer, stringValue(prev.Issuer), stringValue(current.Issuer)},
+			{FieldDNSNames, stringValue(prev.DNSNames), stringValue(current.DNSNames)},
+			{FieldPublicKey, stringValue(prev.PublicKey), stringValue(current.PublicKey)},
+			{FieldKeyUsage, stringValue(prev.KeyUsage), stringValue(current.KeyUsage)},
+			{FieldExtKeyUsages, stringValue(prev.ExtKeyUsages), stringValue(current.ExtKeyUsages)},
+			{FieldExpires, timeValue(prev.Expires), timeValue(current.Expires)},
+			{FieldRemoteAddr, stringValue(prev.RemoteAddr), stringValue(current.RemoteAddr)},
+		}
+	)
+
+	for _, v := range values {
+		if v.old != v.cur {
+			changes = append(changes, FieldChange{
+				Field: v.field,
+				Old:   v.old,
+				New:   v.cur,
+			})
+		}
+	}
+
+	return changes
+}
+
+func stringValue(str *string) string {
+	if str == nil {
+		return "unavailable"
+	}
+	return *str
+}
+
+func timeValue(tm *time.Time) string {
+	if tm == nil {
+		return "unavailable"
+	}
+	return tm.UTC().Format(time.RFC1123)
+}
@@ -4,10 +4,12 @@ import (
 	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
+	"errors"
 	"net"
 	"strings"
 	"time"
@@ -126,6 +128,31 @@ func sha1HexFromCertSignature(signature []byte) *string {
 	return &str
 }
 
+// FingerprintFromPEM returns the sha256 fingerprint of the certificate in the encoded PEM as a hex string
+func FingerprintFromPEM(encodedPEM string) (string, error) {
+	block, _ := pem.Decode([]byte(encodedPEM))
+	if block == nil {
+		return "", errors.New("certificate pem is not valid")
+	}
+
+	hash := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// SerialFromPEM returns the serial number of the certificate in the encoded PEM as a hex string
+func SerialFromPEM(encodedPEM string) (string, error) {
+	block, _ := pem.Decode([]byte(encodedPEM))
+	if block == nil {
+		return "", errors.New("certificate pem is not valid")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	return cert.SerialNumber.Text(16), nil
+}
+
 func checkCertificateStatus(tm time.Time) *string {
 	now := time.Now()
 
@@ -0,0 +1,99 @@
+package ssl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// how long the OCSP responder of the issuer gets to answer, the poll goes on without revocation info after that
+const ocspTimeout = 5 * time.Second
+
+var ocspClient = &http.Client{Timeout: ocspTimeout}
+
+// checkRevocation asks OCSP whether the certificate the server presented is revoked: the response the server stapled
+// to the handshake, or the responder of the issuer when nothing was stapled. Verification in crypto/x509 checks neither
+// OCSP nor CRLs, a revoked certificate passes it. An unknown answer or a responder that fails counts as not revoked,
+// and certificates that only publish a CRL are not checked.
+func checkRevocation(ctx context.Context, state tls.ConnectionState) (revoked bool, reason string) {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) < 2 {
+		return false, ""
+	}
+	leaf, issuer := state.VerifiedChains[0][0], state.VerifiedChains[0][1]
+
+	raw := state.OCSPResponse
+	if len(raw) == 0 {
+		raw = queryOCSP(ctx, leaf, issuer)
+	}
+	if len(raw) == 0 {
+		return false, ""
+	}
+
+	resp, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil || resp.Status != ocsp.Revoked {
+		return false, ""
+	}
+	return true, "certificate revoked on " + resp.RevokedAt.UTC().Format(time.RFC1123) + ", " + revocationReason(resp.RevocationReason)
+}
+
+// queryOCSP asks the first OCSP responder of the certificate, nil when it has none or it does not answer
+func queryOCSP(ctx context.Context, leaf, issuer *x509.Certificate) []byte {
+	if len(leaf.OCSPServer) == 0 {
+		return nil
+	}
+	body, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ocspTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, leaf.OCSPServer[0], bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+
+	resp, err := ocspClient.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// revocationReason returns the RFC 5280 reason code of the revocation in words
+func revocationReason(code int) string {
+	switch code {
+	case ocsp.KeyCompromise:
+		return "key compromise"
+	case ocsp.CACompromise:
+		return "CA compromise"
+	case ocsp.AffiliationChanged:
+		return "affiliation changed"
+	case ocsp.Superseded:
+		return "superseded"
+	case ocsp.CessationOfOperation:
+		return "cessation of operation"
+	case ocsp.CertificateHold:
+		return "certificate hold"
+	case ocsp.PrivilegeWithdrawn:
+		return "privilege withdrawn"
+	case ocsp.AACompromise:
+		return "AA compromise"
+	}
+	return "no reason given"
+}
@@ -11,6 +11,7 @@ import (
 
 const (
 	StatusInvalid      = "invalid"
+	StatusRevoked      = "revoked" // passes verification, but OCSP says the issuer revoked it
 	StatusOffline      = "offline"
 	StatusHealthy      = "healthy"
 	StatusExpires      = "expires"
@@ -38,10 +39,22 @@ type TrackingDomainInfo struct {
 	LastAlertTime *time.Time // the last time of alert of domain's expiration or changes
 }
 
+// priority classes of a tracked domain, due high priority domains are polled first
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
 type DomainTracking struct {
-	ID         int64
-	UserID     int64
-	DomainName string
+	ID            int64
+	UserID        int64
+	DomainName    string
+	CheckInterval *int // minutes, nil means the default interval
+	Priority      string
+	ReminderDays  []int      // days before expiry the owner is reminded at, nil means the owner's schedule
+	Tags          []string   // labels the owner groups the domain by
+	SnoozedUntil  *time.Time // alerts about the domain are recorded but not delivered until then
 	TrackingDomainInfo
 }
 
@@ -125,6 +138,12 @@ func PollDomain(ctx context.Context, domain string) (*TrackingDomainInfo, error)
 		pubAlgo := cert.PublicKeyAlgorithm.String()   // Get the public key algorithm
 		sigAlgo := cert.SignatureAlgorithm.String()   // Get the signature algorithm
 		rmtAddr := conn.RemoteAddr().String()         // Get the remote address of the connection
+		status := checkCertificateStatus(cert.NotAfter)
+		var revocationErr *string
+		if revoked, reason := checkRevocation(ctx, state); revoked {
+			revokedStatus := StatusRevoked
+			status, revocationErr = &revokedStatus, &reason
+		}
 		// Create and send a 'TrackingDomainInfo' object through the channel
 		resultch <- TrackingDomainInfo{
 			RemoteAddr:    &rmtAddr,
@@ -141,7 +160,8 @@ func PollDomain(ctx context.Context, domain string) (*TrackingDomainInfo, error)
 			Issuer:        &org,
 			LastPollAt:    time.Now(),
 			Latency:       &lt,
-			Status:        checkCertificateStatus(cert.NotAfter),
+			Status:        status,
+			Error:         revocationErr,
 		}
 	}()
 
@@ -0,0 +1,162 @@
+package teams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// hosts of the incoming webhooks of teams and of the Workflows (Power Automate) that post to a channel
+var webhookHosts = []string{
+	".webhook.office.com",
+	".logic.azure.com",
+	".api.powerplatform.com",
+}
+
+// IsWebhookURL tells if the url is an incoming webhook or a Workflows url
+func IsWebhookURL(webhookURL string) bool {
+	u, err := url.Parse(webhookURL)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	for _, host := range webhookHosts {
+		if strings.HasSuffix(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Message carries one Adaptive Card, the form both incoming webhooks and Workflows accept
+type Message struct {
+	Type        string        `json:"type"`
+	Attachments []*Attachment `json:"attachments"`
+}
+
+type Attachment struct {
+	ContentType string `json:"contentType"`
+	Content     *Card  `json:"content"`
+}
+
+// Card is an Adaptive Card
+type Card struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []interface{} `json:"body"`
+	Actions []*Action     `json:"actions,omitempty"`
+	MSTeams *MSTeams      `json:"msteams,omitempty"`
+}
+
+// MSTeams holds the teams specific settings of a card
+type MSTeams struct {
+	Width string `json:"width,omitempty"` // Full stretches the card over the channel
+}
+
+// TextBlock is a text of a card
+type TextBlock struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Size     string `json:"size,omitempty"`   // Small, Default, Medium, Large or ExtraLarge
+	Weight   string `json:"weight,omitempty"` // Lighter, Default or Bolder
+	Color    string `json:"color,omitempty"`  // Default, Good, Warning, Attention, Accent
+	Wrap     bool   `json:"wrap,omitempty"`
+	IsSubtle bool   `json:"isSubtle,omitempty"`
+	Spacing  string `json:"spacing,omitempty"`
+}
+
+// FactSet is a list of names and values
+type FactSet struct {
+	Type  string  `json:"type"`
+	Facts []*Fact `json:"facts"`
+}
+
+type Fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Action is a button of a card
+type Action struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// NewMessage wraps the card into a message
+func NewMessage(card *Card) *Message {
+	card.Schema = "http://adaptivecards.io/schemas/adaptive-card.json"
+	card.Type = "AdaptiveCard"
+	card.Version = "1.4"
+	return &Message{
+		Type: "message",
+		Attachments: []*Attachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+	}
+}
+
+func Text(text string) *TextBlock {
+	return &TextBlock{Type: "TextBlock", Text: text, Wrap: true}
+}
+
+func Facts(facts ...*Fact) *FactSet {
+	return &FactSet{Type: "FactSet", Facts: facts}
+}
+
+// OpenURL is a button that opens url in the browser
+func OpenURL(title, url string) *Action {
+	return &Action{Type: "Action.OpenUrl", Title: title, URL: url}
+}
+
+// Client posts cards to incoming webhooks and Workflows
+type Client struct {
+	httpClient *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// PostWebhook posts the message. An incoming webhook answers 200, a Workflow 202 once it has taken the card.
+func (c *Client) PostWebhook(ctx context.Context, webhookURL string, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// the url carries the signature of the webhook, keep it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("teams webhook: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		answer, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			return fmt.Errorf("teams webhook answered %s, retry after %s seconds", resp.Status, retryAfter)
+		}
+		return fmt.Errorf("teams webhook answered %s: %s", resp.Status, strings.TrimSpace(string(answer)))
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+const (
+	defaultAlertDispatchInterval = 10 * time.Second
+	defaultAlertLease            = time.Minute
+	defaultAlertAttempts         = 8
+	defaultAlertBackoff          = 30 * time.Second
+	// longest wait between two attempts of an alert
+	maxAlertBackoff = 6 * time.Hour
+	// alerts leased at once by the dispatcher
+	alertBatchSize = 20
+	// how long recording the result of a delivery may take, apart from the time the send took
+	alertRecordTimeout = 10 * time.Second
+)
+
+// RunAlertDispatcher delivers the alerts from the outbox until ctx is canceled. It runs on every instance,
+// an alert is leased by one dispatcher at a time. A failed delivery is retried with an exponential backoff,
+// on its own channel only, and ends up dead when it runs out of attempts. Every attempt goes to the delivery log.
+// Email alerts leased together for one recipient, like the ones held back by quiet hours, go out as one digest.
+// Before every batch it takes the due escalation steps of the incidents nobody acts on.
+func (args *UpdateDomainRegArgs) RunAlertDispatcher(ctx context.Context) {
+	interval := args.Cfg.Alerts.DispatchInterval
+	if interval <= 0 {
+		interval = defaultAlertDispatchInterval
+	}
+	workerID := "alerts-" + newInstanceID()
+
+	for {
+		if ctx.Err() == nil {
+			args.escalateIncidents(ctx)
+			args.deadLetterAbandonedAlerts(ctx)
+		}
+
+		// once stopped, the batch in flight is still delivered, with the deadline of the shutdown
+		alerts, err := args.Strg.Alerts().LeaseDueAlerts(ctx, workerID, args.alertLease(), alertBatchSize)
+		if err != nil && ctx.Err() == nil {
+			args.Log.Errorf("Failed to lease due alerts: %s", err)
+		}
+		for _, group := range groupAlerts(alerts) {
+			args.deliverAlerts(workerID, group)
+		}
+
+		if len(alerts) == alertBatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			args.Log.Info("Alert dispatcher stopped")
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// deadLetterAbandonedAlerts marks the alerts left by dispatchers that crashed or hung on them with no attempts left as dead,
+// so an alert that takes its dispatcher down is not tried forever
+func (args *UpdateDomainRegArgs) deadLetterAbandonedAlerts(ctx context.Context) {
+	dead, err := args.Strg.Alerts().DeadLetterExhaustedAlerts(ctx)
+	if err != nil {
+		args.Log.Errorf("Failed to dead letter abandoned alerts: %s", err)
+	} else if dead > 0 {
+		args.Log.Info("Marked abandoned alerts dead -> ", dead)
+	}
+}
+
+// groupAlerts puts the email alerts to the same recipient in one group, every other alert in its own, keeping their order
+func groupAlerts(alerts []*models.Alert) [][]*models.Alert {
+	groups := make([][]*models.Alert, 0, len(alerts))
+	digests := make(map[string]int)
+	for _, alert := range alerts {
+		if alert.Channel != models.ChannelEmail {
+			groups = append(groups, []*models.Alert{alert})
+			continue
+		}
+		key := fmt.Sprint(alert.UserID)
+		if alert.Recipient != nil {
+			key += ":" + *alert.Recipient
+		}
+		if i, ok := digests[key]; ok {
+			groups[i] = append(groups[i], alert)
+			continue
+		}
+		digests[key] = len(groups)
+		groups = append(groups, []*models.Alert{alert})
+	}
+	return groups
+}
+
+// deliverAlerts sends the alert, or the digest of the group, logs the attempt of every alert and moves each of them to sent,
+// back to pending or to dead. The result is recorded with a context of its own: a send that used up the lease must still
+// mark its alert sent, or the alert would be leased and delivered again.
+func (args *UpdateDomainRegArgs) deliverAlerts(workerID string, alerts []*models.Alert) {
+	sendCtx, cancelSend := context.WithTimeout(context.Background(), args.alertLease())
+	var sendErr error
+	if len(alerts) == 1 {
+		sendErr = args.sendNotificationChangeOrExpire(sendCtx, alerts[0])
+	} else {
+		sendErr = args.sendDigestByEmail(sendCtx, alerts)
+	}
+	cancelSend()
+
+	ctx, cancel := context.WithTimeout(context.Background(), alertRecordTimeout)
+	defer cancel()
+	for _, alert := range alerts {
+		args.recordDelivery(ctx, workerID, alert, sendErr)
+	}
+}
+
+// recordDelivery logs the attempt to deliver the alert and moves it to sent, back to pending or to dead
+func (args *UpdateDomainRegArgs) recordDelivery(ctx context.Context, workerID string, alert *models.Alert, sendErr error) {
+	delivery := &models.AlertDelivery{
+		AlertID: alert.ID,
+		Attempt: alert.Attempts,
+		Status:  models.DeliverySent,
+	}
+	if sendErr != nil {
+		errMsg := sendErr.Error()
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = &errMsg
+	}
+	if err := args.Strg.Alerts().CreateAlertDelivery(ctx, delivery); err != nil {
+		args.Log.Errorf("Failed to log delivery of alert %d: %s", alert.ID, err)
+	}
+
+	var err error
+	switch {
+	case sendErr == nil:
+		err = args.Strg.Alerts().MarkAlertSent(ctx, alert.ID, workerID)
+	case errors.Is(sendErr, ErrSMSCapReached):
+		// the cap is not lifted before the next month, the user can retry the dead alert once it is raised
+		args.Log.Errorf("Alert %d to %s over %s is dead: %s", alert.ID, alert.Domain, alert.Channel, sendErr)
+		err = args.Strg.Alerts().DeadLetterAlert(ctx, alert.ID, workerID, sendErr.Error())
+	case alert.Attempts >= alert.MaxAttempts:
+		args.Log.Errorf("Alert %d to %s over %s is dead after %d attempts: %s", alert.ID, alert.Domain, alert.Channel, alert.Attempts, sendErr)
+		err = args.Strg.Alerts().DeadLetterAlert(ctx, alert.ID, workerID, sendErr.Error())
+	default:
+		args.Log.Errorf("Failed to deliver alert %d over %s, attempt %d of %d: %s", alert.ID, alert.Channel, alert.Attempts, alert.MaxAttempts, sendErr)
+		err = args.Strg.Alerts().RetryAlert(ctx, alert.ID, workerID, time.Now().Add(args.alertBackoff(alert.Attempts)), sendErr.Error())
+	}
+	if err != nil {
+		args.Log.Errorf("Failed to update alert %d: %s", alert.ID, err)
+	}
+}
+
+// alertBackoff doubles the wait after every failed attempt
+func (args *UpdateDomainRegArgs) alertBackoff(attempts int) time.Duration {
+	backoff := args.Cfg.Alerts.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultAlertBackoff
+	}
+	for i := 1; i < attempts && backoff < maxAlertBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxAlertBackoff {
+		backoff = maxAlertBackoff
+	}
+	return backoff
+}
+
+func (args *UpdateDomainRegArgs) alertLease() time.Duration {
+	if args.Cfg.Alerts.Lease <= 0 {
+		return defaultAlertLease
+	}
+	return args.Cfg.Alerts.Lease
+}
+
+func (args *UpdateDomainRegArgs) maxAlertAttempts() int {
+	return alertAttempts(args.Cfg)
+}
+
+// alertAttempts returns the attempts an alert gets before it is dead, for the alerts queued outside the poller
+func alertAttempts(cfg *config.Config) int {
+	if cfg.Alerts.MaxAttempts <= 0 {
+		return defaultAlertAttempts
+	}
+	return cfg.Alerts.MaxAttempts
+}
+
+// an expiry alert this close to the expiry is an error rather than a warning
+const urgentExpiryDays = 3
+
+// AlertSeverity returns how bad the alert is: critical when the certificate is broken, error when it is about to be,
+// warning for what needs a look and info for the rest
+func AlertSeverity(alert *models.Alert) string {
+	switch alert.Type {
+	case expiredAlertStr, invalidAlertStr, revokedAlertStr, escalationAlertStr:
+		return models.SeverityCritical
+	case offlineAlertStr, overdueAlertStr:
+		return models.SeverityError
+	case expiryAlertStr:
+		if alert.Payload.Expires != nil && daysUntilExpiration(*alert.Payload.Expires) <= urgentExpiryDays {
+			return models.SeverityError
+		}
+		return models.SeverityWarning
+	case changeAlertStr:
+		return models.SeverityWarning
+	}
+	return models.SeverityInfo
+}
+
+// AlertTypeTitle returns the human readable name of the alert type
+func AlertTypeTitle(tp string) string {
+	switch tp {
+	case expiryAlertStr:
+		return "Expiry"
+	case renewalAlertStr:
+		return "Renewal window"
+	case overdueAlertStr:
+		return "Renewal overdue"
+	case changeAlertStr:
+		return "Certificate change"
+	case expiredAlertStr:
+		return "Expired"
+	case invalidAlertStr:
+		return "Invalid certificate"
+	case revokedAlertStr:
+		return "Revoked certificate"
+	case offlineAlertStr:
+		return "Offline"
+	case recoveryAlertStr:
+		return "Recovery"
+	case escalationAlertStr:
+		return "Escalation"
+	case domainAddedAlertStr:
+		return "Domain added"
+	case domainRemovedAlertStr:
+		return "Domain removed"
+	}
+	return tp
+}
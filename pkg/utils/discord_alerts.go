@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/discord"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+const (
+	// discord cuts the value of an embed field at 1024 characters
+	maxDiscordFieldValue = 1024
+	discordUsername      = "CertAlert"
+)
+
+var discordClient = discord.NewClient()
+
+// sendNotificationToDiscord posts the alert to the discord channel it is addressed to
+func (args *UpdateDomainRegArgs) sendNotificationToDiscord(ctx context.Context, alert *models.Alert) error {
+	integration, err := args.alertIntegration(ctx, alert)
+	if err != nil {
+		return err
+	}
+	msg := discordAlertMessage(args.Cfg, alert, args.alertDomain(ctx, alert))
+	return discordClient.PostWebhook(ctx, integration.Config.WebhookURL, msg)
+}
+
+// discordColor returns the colour of the embed for the status of the domain, the same colours the domains page uses
+func discordColor(status string) int {
+	switch status {
+	case ssl.StatusExpired, ssl.StatusRevoked:
+		return 0xdc2626 // red
+	case ssl.StatusHealthy:
+		return 0x16a34a // green
+	case ssl.StatusInvalid:
+		return 0xca8a04 // yellow
+	case ssl.StatusOffline:
+		return 0x9ca3af // gray
+	case ssl.StatusUnResponsive:
+		return 0x0d9488 // teal
+	case ssl.StatusExpires:
+		return 0xea580c // orange
+	}
+	return 0x4b5563
+}
+
+// discordAlertMessage builds the embed of the alert: what happened, the details of the domain,
+// the changes of a change alert and a link to the domain's page
+func discordAlertMessage(cfg *config.Config, alert *models.Alert, domain *ssl.DomainTracking) *discord.Message {
+	link := domainLink(cfg, domain)
+	headline := alertHeadline(alert)
+
+	fields := []*discord.Field{
+		{Name: "Alert", Value: AlertTypeTitle(alert.Type), Inline: true},
+	}
+	if status := alertStatus(alert, domain); status != "" {
+		fields = append(fields, &discord.Field{Name: "Status", Value: status, Inline: true})
+	}
+	expires := alert.Payload.Expires
+	if expires == nil && domain != nil {
+		expires = domain.Expires
+	}
+	if expires != nil {
+		fields = append(fields, &discord.Field{Name: "Expires", Value: expiryCountdown(*expires), Inline: true})
+	}
+	if alert.Payload.RenewalWindowStart != nil && alert.Payload.RenewalWindowEnd != nil {
+		fields = append(fields, &discord.Field{
+			Name: "Renewal window",
+			Value: fmt.Sprintf("%s - %s",
+				alert.Payload.RenewalWindowStart.Format("02 Jan 2006"), alert.Payload.RenewalWindowEnd.Format("02 Jan 2006")),
+			Inline: true,
+		})
+	}
+	if domain != nil {
+		if domain.Issuer != nil {
+			fields = append(fields, &discord.Field{Name: "Issuer", Value: *domain.Issuer, Inline: true})
+		}
+		if ip := remoteIP(domain.RemoteAddr); ip != "" {
+			fields = append(fields, &discord.Field{Name: "IP address", Value: ip, Inline: true})
+		}
+	}
+	if alert.Payload.Error != nil {
+		fields = append(fields, &discord.Field{Name: "Error", Value: truncate(*alert.Payload.Error, maxDiscordFieldValue)})
+	}
+	if len(alert.Payload.Changes) > 0 {
+		var changes strings.Builder
+		for _, change := range alert.Payload.Changes {
+			changes.WriteString(fmt.Sprintf("• **%s**: `%s` → `%s`\n", ssl.FieldTitle(change.Field), change.Old, change.New))
+		}
+		fields = append(fields, &discord.Field{Name: "Changes", Value: truncate(changes.String(), maxDiscordFieldValue)})
+	}
+
+	// webhooks can't have buttons, the links go in a field
+	links := []string{fmt.Sprintf("[View](%s)", link)}
+	// reminders of an incident stop once it is acknowledged, which can be done right from the message
+	if alert.Payload.AckToken != "" && alert.Type != recoveryAlertStr {
+		links = append(links, fmt.Sprintf("[Acknowledge](%s/incidents/ack/%s)", cfg.BaseUrl, alert.Payload.AckToken))
+	}
+	if alert.Payload.ExplanationURL != nil {
+		links = append(links, fmt.Sprintf("[Explanation](%s)", *alert.Payload.ExplanationURL))
+	}
+	fields = append(fields, &discord.Field{Name: "Links", Value: strings.Join(links, " · ")})
+
+	return &discord.Message{
+		Username: discordUsername,
+		Embeds: []*discord.Embed{{
+			Title:       truncate(fmt.Sprintf("%s %s: %s", alertEmoji(alert.Type), AlertTypeTitle(alert.Type), alert.Domain), 256),
+			Description: fmt.Sprintf("**%s** %s.", alert.Domain, headline),
+			URL:         link,
+			Color:       discordColor(alertStatus(alert, domain)),
+			Fields:      fields,
+			Footer:      &discord.Footer{Text: "CertAlert"},
+			Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		}},
+		AllowedMentions: &discord.AllowedMentions{Parse: []string{}},
+	}
+}
+
+// discordTestMessage is sent when the user tests the connection
+func discordTestMessage(cfg *config.Config) *discord.Message {
+	return &discord.Message{
+		Username: discordUsername,
+		Embeds: []*discord.Embed{{
+			Title:       "🔔 CertAlert test message",
+			Description: "This channel is connected to CertAlert. Alerts about your tracked domains will show up here.",
+			URL:         cfg.BaseUrl + "/domains",
+			Color:       discordColor(ssl.StatusHealthy),
+			Footer:      &discord.Footer{Text: "CertAlert"},
+			Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		}},
+		AllowedMentions: &discord.AllowedMentions{Parse: []string{}},
+	}
+}
+
+// truncate cuts the text to max characters, marking the cut with an ellipsis
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
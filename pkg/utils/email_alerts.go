@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/pkg/email"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// emailRecipient is who an alert email goes to
+type emailRecipient struct {
+	address     string
+	name        string
+	unsubscribe string // empty for the recipients of an escalation step, they can't turn off the user's emails
+}
+
+// alertEmailRecipient returns the user, or the email address of an escalation step
+func (args *UpdateDomainRegArgs) alertEmailRecipient(ctx context.Context, alert *models.Alert) (*emailRecipient, error) {
+	if alert.Recipient != nil {
+		return &emailRecipient{
+			address: *alert.Recipient,
+			name:    "there",
+		}, nil
+	}
+
+	user, err := args.Strg.User().GetUserByID(ctx, alert.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &emailRecipient{
+		address:     user.Email,
+		name:        user.FirstName,
+		unsubscribe: args.Cfg.BaseUrl + "/unsubscribe/" + UnsubscribeToken(args.Cfg, user.ID),
+	}, nil
+}
+
+// sendNotificationToUserByEmail sends the alert email of the alert type
+func (args *UpdateDomainRegArgs) sendNotificationToUserByEmail(ctx context.Context, alert *models.Alert) error {
+	if alert == nil {
+		return errors.New("nil alert")
+	}
+	recipient, err := args.alertEmailRecipient(ctx, alert)
+	if err != nil {
+		return err
+	}
+
+	req := &email.SendEmailRequest{
+		To:          []string{recipient.address},
+		Subject:     fmt.Sprintf("[CertAlert] %s: %s", alert.Domain, AlertTypeTitle(alert.Type)),
+		Unsubscribe: recipient.unsubscribe,
+		Body: map[string]string{
+			"name":         recipient.name,
+			"domain":       alert.Domain,
+			"headline":     alertHeadline(alert),
+			"details_link": args.Cfg.BaseUrl + "/domains",
+		},
+	}
+	// reminders of an incident stop once it is acknowledged, which can be done right from the email
+	if alert.Payload.AckToken != "" && alert.Type != recoveryAlertStr {
+		req.Body["ack_link"] = args.Cfg.BaseUrl + "/incidents/ack/" + alert.Payload.AckToken
+	}
+
+	switch alert.Type {
+	case expiryAlertStr, expiredAlertStr, renewalAlertStr, overdueAlertStr:
+		req.Type = email.ExpiryAlertEmail
+		if alert.Payload.Expires != nil {
+			req.Body["expires"] = alert.Payload.Expires.Format(time.RFC1123)
+		}
+	case changeAlertStr:
+		req.Type = email.ChangeAlertEmail
+		for _, change := range alert.Payload.Changes {
+			req.Items = append(req.Items, map[string]string{
+				"field": ssl.FieldTitle(change.Field),
+				"old":   change.Old,
+				"new":   change.New,
+			})
+		}
+	case recoveryAlertStr:
+		req.Type = email.RecoveryAlertEmail
+		req.Body["problem"] = ProblemTitle(alert.Payload.Problem)
+	case invalidAlertStr, revokedAlertStr, offlineAlertStr, escalationAlertStr:
+		req.Type = email.ProblemAlertEmail
+		if alert.Payload.Error != nil {
+			req.Body["reason"] = *alert.Payload.Error
+		}
+	default:
+		return errors.New("unknown type " + alert.Type)
+	}
+
+	return email.SendEmail(ctx, args.Mailer, req)
+}
+
+// sendDigestByEmail sends the email alerts to one recipient as one email
+func (args *UpdateDomainRegArgs) sendDigestByEmail(ctx context.Context, alerts []*models.Alert) error {
+	recipient, err := args.alertEmailRecipient(ctx, alerts[0])
+	if err != nil {
+		return err
+	}
+
+	req := &email.SendEmailRequest{
+		To:          []string{recipient.address},
+		Type:        email.DigestEmail,
+		Subject:     fmt.Sprintf("[CertAlert] %d alerts about your domains", len(alerts)),
+		Unsubscribe: recipient.unsubscribe,
+		Body: map[string]string{
+			"name":         recipient.name,
+			"details_link": args.Cfg.BaseUrl + "/domains",
+		},
+	}
+	for _, alert := range alerts {
+		req.Items = append(req.Items, map[string]string{
+			"domain":  alert.Domain,
+			"title":   AlertTypeTitle(alert.Type),
+			"summary": alertHeadline(alert),
+		})
+	}
+
+	return email.SendEmail(ctx, args.Mailer, req)
+}
+
+// alertHeadline says in a few words what the alert is about, it follows the domain name
+func alertHeadline(alert *models.Alert) string {
+	switch alert.Type {
+	case expiryAlertStr:
+		if alert.Payload.Expires == nil {
+			return "has an upcoming SSL expiration"
+		}
+		return fmt.Sprintf("has an upcoming SSL expiration, only %d days left", daysUntilExpiration(*alert.Payload.Expires))
+	case expiredAlertStr:
+		return "has an expired SSL certificate"
+	case renewalAlertStr:
+		if alert.Payload.RenewalWindowStart == nil || alert.Payload.RenewalWindowEnd == nil {
+			return "is inside the renewal window suggested by its certificate authority"
+		}
+		return fmt.Sprintf("is inside the renewal window suggested by its certificate authority, %s - %s, but the SSL certificate has not been replaced yet",
+			alert.Payload.RenewalWindowStart.Format(time.RFC1123), alert.Payload.RenewalWindowEnd.Format(time.RFC1123))
+	case overdueAlertStr:
+		return "usually has its SSL certificate renewed by now, but it has not been renewed yet"
+	case changeAlertStr:
+		return fmt.Sprintf("has %d changes in its SSL certificate", len(alert.Payload.Changes))
+	case invalidAlertStr:
+		return "serves an SSL certificate that fails verification"
+	case revokedAlertStr:
+		return "serves a revoked SSL certificate"
+	case offlineAlertStr:
+		return "is not responding"
+	case recoveryAlertStr:
+		return "has recovered from " + ProblemTitle(alert.Payload.Problem)
+	case escalationAlertStr:
+		return fmt.Sprintf("has a problem nobody has acknowledged yet: %s, escalation step %d", ProblemTitle(alert.Payload.Problem), alert.Payload.EscalationStep)
+	}
+	return AlertTypeTitle(alert.Type)
+}
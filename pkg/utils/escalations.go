@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+var escalationAlertStr = "escalation_alert"
+
+// incidents escalated at once by the dispatcher
+const escalationBatchSize = 50
+
+// EscalationChannels are the channels an escalation step can alert over, besides the integrations of the user
+var EscalationChannels = []string{models.ChannelEmail, models.ChannelTelegram}
+
+// EscalationToIntegration is the channel of the step form that pages one of the integrations of the user
+const EscalationToIntegration = "integration"
+
+const maxTags = 10
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9_\-]{1,32}$`)
+
+// ParseTags splits the comma or space separated tags, lowercased and without duplicates. Invalid tags are dropped.
+func ParseTags(value string) []string {
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, tag := range strings.FieldsFunc(strings.ToLower(value), func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !tagPattern.MatchString(tag) || seen[tag] || len(tags) == maxTags {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// ParseEscalationRecipients splits the comma separated recipients of an escalation step and checks that the channel
+// can reach them: email addresses for email, chat ids for telegram
+func ParseEscalationRecipients(channel string, value string) ([]string, error) {
+	recipients := make([]string, 0)
+	for _, recipient := range strings.Split(value, ",") {
+		recipient = strings.TrimSpace(recipient)
+		if recipient == "" {
+			continue
+		}
+		switch channel {
+		case models.ChannelEmail:
+			address, err := mail.ParseAddress(recipient)
+			if err != nil {
+				return nil, fmt.Errorf("%s is not a valid email address", recipient)
+			}
+			recipient = address.Address
+		case models.ChannelTelegram:
+			if _, err := strconv.ParseInt(recipient, 10, 64); err != nil {
+				return nil, fmt.Errorf("%s is not a valid telegram chat id", recipient)
+			}
+		default:
+			return nil, errors.New("unknown channel " + channel)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// matchEscalationPolicy returns the policy that escalates the incidents of the domain. A policy attached to the domain
+// itself wins over one attached to its tags, among equals the oldest one wins.
+func matchEscalationPolicy(policies []*models.EscalationPolicy, domain *ssl.DomainTracking) *int64 {
+	for _, policy := range policies {
+		for _, name := range policy.Domains {
+			if name == domain.DomainName {
+				return &policy.ID
+			}
+		}
+	}
+	for _, policy := range policies {
+		for _, tag := range policy.Tags {
+			for _, domainTag := range domain.Tags {
+				if tag == domainTag {
+					return &policy.ID
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// escalateIncidents takes the due escalation step of every open incident and queues its alerts.
+// Every instance's dispatcher runs it, a step is taken by one of them only.
+func (args *UpdateDomainRegArgs) escalateIncidents(ctx context.Context) {
+	due, err := args.Strg.Escalations().GetDueEscalations(ctx, escalationBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			args.Log.Errorf("Failed to get due escalations: %s", err)
+		}
+		return
+	}
+
+	for _, escalation := range due {
+		incident, step := escalation.Incident, escalation.Step
+		alerts := args.escalationAlerts(incident, step)
+		// the step is still taken during maintenance, so it is not escalated all at once when the window ends
+		domain, err := args.Strg.Domain().GetDomainWithUserIDAndDomainName(ctx, &ssl.DomainTracking{
+			DomainName: incident.Domain,
+			UserID:     incident.UserID,
+		})
+		if err != nil {
+			args.Log.Errorf("Failed to get domain %s of incident %d: %s", incident.Domain, incident.ID, err)
+		} else {
+			alerts = suppress(alerts, args.domainSuppression(ctx, incident.UserID, domain, time.Now()))
+		}
+		ok, err := args.Strg.Escalations().EscalateIncident(ctx, incident, step, alerts)
+		if err != nil {
+			args.Log.Errorf("Failed to escalate incident %d to step %d: %s", incident.ID, step.Position, err)
+			continue
+		}
+		if ok {
+			args.Log.Info("Escalated incident ", incident.ID, " of ", incident.Domain, " to step ", step.Position)
+		}
+	}
+}
+
+// escalationAlerts addresses the alert of the step to its integration, to each of its recipients, or to the owner when it has none.
+// The step is timed by its own delay, so it does not wait for quiet hours.
+func (args *UpdateDomainRegArgs) escalationAlerts(incident *models.Incident, step *models.EscalationStep) []*models.Alert {
+	payload := models.AlertPayload{
+		Problem:        incident.Problem,
+		AckToken:       incident.AckToken,
+		OpenedAt:       &incident.OpenedAt,
+		EscalationStep: step.Position,
+	}
+	newAlert := func(recipient *string) *models.Alert {
+		return &models.Alert{
+			UserID:        incident.UserID,
+			Domain:        incident.Domain,
+			Type:          escalationAlertStr,
+			Channel:       step.Channel,
+			Recipient:     recipient,
+			Payload:       payload,
+			Incident:      incident,
+			MaxAttempts:   args.maxAlertAttempts(),
+			NextAttemptAt: time.Now(),
+		}
+	}
+
+	if step.IntegrationID != nil {
+		alert := newAlert(nil)
+		alert.IntegrationID = step.IntegrationID
+		if step.Channel == models.IntegrationWebhook {
+			// the endpoint tells a retried event from a new one by its key
+			alert.Payload.EventID = uuid.NewString()
+		}
+		return []*models.Alert{alert}
+	}
+	if len(step.Recipients) == 0 {
+		return []*models.Alert{newAlert(nil)}
+	}
+	alerts := make([]*models.Alert, 0, len(step.Recipients))
+	for i := range step.Recipients {
+		alerts = append(alerts, newAlert(&step.Recipients[i]))
+	}
+	return alerts
+}
+
+// escalatedPagingIntegrations returns the incident tools the steps taken for the incident paged. Their alert is resolved
+// on recovery like the one of a tool the user turned on.
+func escalatedPagingIntegrations(policies []*models.EscalationPolicy, incident *models.Incident) map[int64]bool {
+	paged := make(map[int64]bool)
+	if incident.EscalationPolicyID == nil {
+		return paged
+	}
+	for _, policy := range policies {
+		if policy.ID != *incident.EscalationPolicyID {
+			continue
+		}
+		for _, step := range policy.Steps {
+			if step.Position <= incident.EscalationStep && step.IntegrationID != nil && isPagingKind(step.Channel) {
+				paged[*step.IntegrationID] = true
+			}
+		}
+	}
+	return paged
+}
@@ -4,292 +4,734 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"sync"
+	"strconv"
 	"time"
 
 	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/ari"
+	"github.com/SaidovZohid/certalert.info/pkg/email"
 	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/pkg/sms"
 	"github.com/SaidovZohid/certalert.info/pkg/ssl"
 	"github.com/SaidovZohid/certalert.info/storage"
 	"github.com/SaidovZohid/certalert.info/storage/models"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
 )
 
 type DomainNowAndPreviousInfo struct {
 	DomainName string
 	Current    *ssl.TrackingDomainInfo
 	Prev       *ssl.TrackingDomainInfo
+	// ARI suggested renewal window of the current certificate, nil if the issuer has no ARI endpoint configured
+	RenewalInfo *models.RenewalInfo
+	// the certificate the domain serves, or the last one it served when the poll got none, nil if it never served one
+	Certificate *models.Certificate
+	// the certificate has not been replaced at the point in its lifetime where this domain usually renews
+	RenewalOverdue bool
+	// certificate fields that differ from the previous poll
+	Changes []ssl.FieldChange
 }
 
 type UpdateDomainRegArgs struct {
-	Strg storage.StorageI
-	Log  *logger.Logger
-	Cfg  *config.Config
-	Bot  *tgbotapi.BotAPI
+	Strg   storage.StorageI
+	Log    *logger.Logger
+	Cfg    *config.Config
+	Bot    *tgbotapi.BotAPI
+	Ari    *ari.Client
+	Mailer email.Mailer
+	SMS    sms.Provider // nil when SMS is off
 }
 
 type UpdateDomainRegI interface {
 	UpdateDomainInformationRegularly(ctx context.Context)
+	RunPollWorkers(ctx context.Context)
+	RunAlertDispatcher(ctx context.Context)
 }
 
-func NewUpdateReg(strg storage.StorageI, log logger.Logger, cfg *config.Config, bot *tgbotapi.BotAPI) UpdateDomainRegI {
+func NewUpdateReg(strg storage.StorageI, log logger.Logger, cfg *config.Config, bot *tgbotapi.BotAPI, mailer email.Mailer, smsProvider sms.Provider) UpdateDomainRegI {
 	return &UpdateDomainRegArgs{
-		Strg: strg,
-		Log:  &log,
-		Cfg:  cfg,
-		Bot:  bot,
+		Strg:   strg,
+		Log:    &log,
+		Cfg:    cfg,
+		Bot:    bot,
+		Ari:    ari.NewClient(cfg.AcmeDirectories),
+		Mailer: mailer,
+		SMS:    smsProvider,
 	}
 }
 
-// UpdateDomainInformationRegularly fetches information about domains at regular intervals
-// of x minutes, pausing in between iterations.
-func (args *UpdateDomainRegArgs) UpdateDomainInformationRegularly(ctx context.Context) {
-	//
-	ticker := time.NewTicker(args.Cfg.PullUpdateDomainInterval)
-	done := make(chan struct{})
+// defaultSchedulerTick is used when SCHEDULER_TICK is not a positive duration
+const defaultSchedulerTick = time.Minute
 
-	if err := args.poll(ctx); err != nil {
-		args.Log.Errorf("Failed to pull data from certificate transparency logs for all domains: %s", err)
-		return
+// UpdateDomainInformationRegularly runs the polling scheduler until ctx is canceled. Only the leader instance runs it.
+// Every tick it queues the domains whose own check interval has passed, and the poll workers of every
+// instance poll them off the shared queue, so a domain is never polled twice at the same time.
+// Once per PullUpdateDomainInterval it cleans up the poll history and the failed poll jobs.
+func (args *UpdateDomainRegArgs) UpdateDomainInformationRegularly(ctx context.Context) {
+	tick := args.Cfg.Scheduler.Tick
+	if tick <= 0 {
+		tick = defaultSchedulerTick
 	}
-
-	args.Log.Info("Successfully pulled info and updated for the first time!")
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	var lastCleanup time.Time
 
 	// Run the domain information update loop
 	for {
-		select {
-		case <-ticker.C:
-			args.Log.Info("Domain information update started")
+		if err := args.enqueueDueDomains(ctx); err != nil {
+			args.Log.Errorf("Failed to queue polls for due domains: %s", err)
+		}
 
-			if err := args.poll(ctx); err != nil {
-				args.Log.Errorf("Failed to pull data from certificate transparency logs for all domains: %s", err)
-				continue
-			}
+		if time.Since(lastCleanup) >= args.Cfg.PullUpdateDomainInterval {
+			args.cleanupHistory(ctx)
+			args.cleanupPollJobs(ctx)
+			lastCleanup = time.Now()
+		}
 
-			args.Log.Info("Successfully pulled info and updated!")
-		case <-done:
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
 			args.Log.Info("UpdateDomainInformationRegularly has been canceled!")
 			return // If the context is canceled, exit the function
 		}
 	}
 }
 
-func (args *UpdateDomainRegArgs) poll(ctx context.Context) error {
-	domains, err := args.Strg.Domain().GetListofDomainsThatExists(ctx)
+// enqueueDueDomains queues a poll job for every domain that is due now. Domains that already have
+// a job waiting or running, queued by this or another instance, are skipped by the queue.
+func (args *UpdateDomainRegArgs) enqueueDueDomains(ctx context.Context) error {
+	schedules, err := args.Strg.Domain().GetDomainSchedules(ctx)
 	if err != nil {
-		args.Log.Errorf("Failed to get list of domains from storage: %s", err)
+		args.Log.Errorf("Failed to get domain schedules from storage: %s", err)
 		return err
 	}
 
-	var (
-		workers = make(chan struct{}, 50)
-		wg      = sync.WaitGroup{}
-		results = make(chan DomainNowAndPreviousInfo, len(domains))
-	)
+	due := dueDomains(args.Cfg, schedules, time.Now())
+	if len(due) == 0 {
+		return nil
+	}
 
-	args.Log.Info("Domains -> ", len(domains))
+	jobs := make([]*models.PollJob, 0, len(due))
+	for _, domain := range due {
+		jobs = append(jobs, &models.PollJob{
+			Domain:      domain.name,
+			Priority:    domain.priority,
+			IP:          remoteIP(domain.remoteAddr),
+			Site:        registrableDomain(domain.name),
+			MaxAttempts: args.maxPollAttempts(),
+			RunAt:       domain.dueAt.Add(jitter(domain.interval, args.Cfg.Scheduler.Jitter)),
+		})
+	}
 
-	for _, domain := range domains {
-		wg.Add(1)
-		go func(domain *ssl.DomainTracking) {
-			defer func() {
-				<-workers
-				wg.Done()
-			}()
-			ctxPoll, cancel := context.WithTimeout(context.Background(), time.Second*10)
-			defer cancel()
+	queued, err := args.Strg.PollJobs().EnqueuePollJobs(ctx, jobs)
+	if err != nil {
+		return err
+	}
+	if queued > 0 {
+		args.Log.Info("Queued polls of due domains -> ", queued)
+	}
 
-			workers <- struct{}{}
+	return nil
+}
 
-			info, err := ssl.PollDomain(ctxPoll, domain.DomainName)
-			if err != nil {
-				args.Log.Error(err)
-				return
-			}
+// pollDomain polls the domain, records what was found in the history and returns it together with the previous poll.
+// The polled info itself is saved by the caller, in one transaction with the alerts it raised.
+func (args *UpdateDomainRegArgs) pollDomain(ctx context.Context, domain *ssl.DomainTracking) (*DomainNowAndPreviousInfo, error) {
+	ctxPoll, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
 
-			err = args.Strg.Domain().UpdateAllTheSameDomainsInfo(ctx, &ssl.DomainTracking{
-				DomainName:         domain.DomainName,
-				TrackingDomainInfo: *info,
-			})
-			if err != nil {
-				args.Log.Error(err)
-				return
-			}
+	info, err := ssl.PollDomain(ctxPoll, domain.DomainName)
+	if err != nil {
+		return nil, err
+	}
 
-			results <- DomainNowAndPreviousInfo{
-				DomainName: domain.DomainName,
-				Prev:       &domain.TrackingDomainInfo,
-				Current:    info,
-			}
-		}(domain)
+	fingerprint := fingerprintOf(info)
+	err = args.Strg.History().CreatePollSnapshot(ctx, &models.PollSnapshot{
+		Domain:      domain.DomainName,
+		Status:      info.Status,
+		Latency:     info.Latency,
+		Error:       info.Error,
+		RemoteAddr:  info.RemoteAddr,
+		Fingerprint: fingerprint,
+		PolledAt:    info.LastPollAt,
+	})
+	if err != nil {
+		args.Log.Errorf("Failed to save poll snapshot of %s: %s", domain.DomainName, err)
 	}
 
-	wg.Wait()
-	close(results)
+	changes := ssl.Diff(&domain.TrackingDomainInfo, info)
+	if len(changes) > 0 {
+		args.saveCertificateChanges(ctx, domain.DomainName, changes, info.LastPollAt)
+	}
 
-	return args.filterDomainsOwnersNotif(ctx, results)
+	certs := args.recordCertificate(ctx, domain.DomainName, info, fingerprint)
+
+	return &DomainNowAndPreviousInfo{
+		DomainName:     domain.DomainName,
+		Prev:           &domain.TrackingDomainInfo,
+		Current:        info,
+		RenewalInfo:    args.checkRenewalInfo(ctx, domain.DomainName, info),
+		Certificate:    servedCertificate(certs, fingerprint),
+		RenewalOverdue: fingerprint != nil && isRenewalOverdue(certs, info.Expires, args.Cfg.RenewalOverdueGrace),
+		Changes:        changes,
+	}, nil
 }
 
-func (args *UpdateDomainRegArgs) filterDomainsOwnersNotif(ctx context.Context, results chan DomainNowAndPreviousInfo) error {
-	args.Log.Info("In process of sending notification to the users ")
-
-	// TODO:
-	// * for loop the domain and give the domain to strg and get the users info in Query.
-	// * run loop inside the results loop.
-	// * in for loop one by one get the user and check which things are turned on in user setting for notification.
-	// * if email is turned on, send notification throw email
-	// * if telegram is turned on, send notification throw telegram bot. if it turned on, try to get the user telegram id that is linked to the user account, and send the chat id within bot.
-	for v := range results {
-		users, err := args.Strg.Domain().GetListofUsersThatDomainExists(ctx, v.DomainName)
-		if err != nil {
-			args.Log.Errorf("error getting list of user that has this domain %s", err)
-			continue
-		}
-		for _, userId := range users {
-			domain, err := args.Strg.Domain().GetDomainWithUserIDAndDomainName(ctx, &ssl.DomainTracking{
-				DomainName: v.DomainName,
-				UserID:     userId,
-			})
-			if err != nil {
-				args.Log.Errorf("error getting domain with user id and domain name %s", err)
-				continue
-			}
-			// If last alert time is one day after, send notification about expiration. Otherwise, just skip it.
-			if isLastAlertTimeOneDayAgo(*domain.LastAlertTime) {
-				user, err := args.Strg.User().GetUserByID(ctx, userId)
-				if err != nil {
-					args.Log.Errorf("error getting user by id %d", err)
-					continue
-				}
-				notification, err := args.Strg.Notifications().GetNotificationRowByUserID(ctx, userId)
-				if err != nil {
-					args.Log.Errorf("error getting notification row by userid %d", err)
-					continue
-				}
-				err = args.notifyUser(ctx, user, notification, &v)
-				if err != nil {
-					args.Log.Errorf("error notifying user %s", err)
-					continue
-				}
-			}
+// checkRenewalInfo returns the ARI suggested renewal window of the certificate that the domain serves now.
+// The stored window is reused until the CA's Retry-After passes or the certificate gets replaced.
+func (args *UpdateDomainRegArgs) checkRenewalInfo(ctx context.Context, domain string, info *ssl.TrackingDomainInfo) *models.RenewalInfo {
+	if info.EncodedPEM == nil || info.Issuer == nil {
+		return nil
+	}
+
+	certID, err := ari.CertIDFromPEM(*info.EncodedPEM)
+	if err != nil {
+		args.Log.Errorf("Failed to get ARI certificate id of %s: %s", domain, err)
+		return nil
+	}
+
+	stored, err := args.Strg.RenewalInfo().GetRenewalInfoByDomain(ctx, domain)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		args.Log.Errorf("Failed to get renewal info of %s: %s", domain, err)
+		return nil
+	}
+	if stored != nil && stored.CertID == certID && time.Now().Before(stored.RetryAfter) {
+		return stored
+	}
+
+	ctxAri, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	renewalInfo, err := args.Ari.GetRenewalInfo(ctxAri, *info.Issuer, *info.EncodedPEM)
+	if err != nil {
+		if !errors.Is(err, ari.ErrUnknownIssuer) {
+			args.Log.Errorf("Failed to get ARI renewal window of %s: %s", domain, err)
 		}
+		return nil
 	}
-	return nil
+
+	result := &models.RenewalInfo{
+		Domain:      domain,
+		CertID:      renewalInfo.CertID,
+		WindowStart: renewalInfo.WindowStart,
+		WindowEnd:   renewalInfo.WindowEnd,
+		RetryAfter:  renewalInfo.RetryAfter,
+		CheckedAt:   time.Now(),
+	}
+	if renewalInfo.ExplanationURL != "" {
+		result.ExplanationURL = &renewalInfo.ExplanationURL
+	}
+	if err := args.Strg.RenewalInfo().UpsertRenewalInfo(ctx, result); err != nil {
+		args.Log.Errorf("Failed to save renewal info of %s: %s", domain, err)
+	}
+
+	return result
 }
 
-func (args *UpdateDomainRegArgs) notifyUser(ctx context.Context, user *models.User, notification *models.Notification, domainPrInfo *DomainNowAndPreviousInfo) error {
-	expiryAlert, changeAlert := checkExpiryAndChangeSSLOfDomain(domainPrInfo, notification)
-	// TODO:
-	// * check the expiry or change alert true or false and write the logic of sending of notification code!
-	var isNotified bool
-	if notification.ExpiryAlerts && expiryAlert {
-		args.Log.Info("Expiration Notify ", domainPrInfo.DomainName)
-		if err := args.sendNotificationChangeOrExpire(&expiryAlertStr, user, domainPrInfo, notification); err != nil {
-			return err
+// recordCertificate records the certificate the domain serves now in its certificate history, and returns the history
+func (args *UpdateDomainRegArgs) recordCertificate(ctx context.Context, domain string, info *ssl.TrackingDomainInfo, fingerprint *string) []*models.Certificate {
+	if fingerprint != nil {
+		cert := &models.Certificate{
+			Domain:      domain,
+			Fingerprint: *fingerprint,
+			Issuer:      info.Issuer,
+			Issued:      info.Issued,
+			Expires:     info.Expires,
+			LastSeen:    info.LastPollAt,
+		}
+		if serial, err := ssl.SerialFromPEM(*info.EncodedPEM); err == nil {
+			cert.Serial = &serial
 		}
-		isNotified = true
-	} else if notification.ChangeAlert && changeAlert {
-		args.Log.Info("Change Notify ", domainPrInfo.DomainName)
-		if err := args.sendNotificationChangeOrExpire(&changeAlertStr, user, domainPrInfo, notification); err != nil {
-			return err
+		if err := args.Strg.Certificates().SaveSeenCertificate(ctx, cert); err != nil {
+			args.Log.Errorf("Failed to save certificate of %s: %s", domain, err)
 		}
-		isNotified = true
 	}
-	if isNotified {
-		return args.Strg.Domain().UpdateTheLastAlertTime(ctx, user.ID, domainPrInfo.DomainName)
+
+	certs, err := args.Strg.Certificates().GetCertificatesByDomain(ctx, domain)
+	if err != nil {
+		args.Log.Errorf("Failed to get certificate history of %s: %s", domain, err)
+		return nil
 	}
 
-	return nil
+	return certs
 }
 
-// tp = {change_alert or expiry_alert}
-func (args *UpdateDomainRegArgs) sendNotificationChangeOrExpire(tp *string, user *models.User, domainPrInfo *DomainNowAndPreviousInfo, notification *models.Notification) error {
-	if tp == nil {
-		return errors.New("nil notification type")
+// saveCertificateChanges keeps the field by field changes for the domain changes page
+func (args *UpdateDomainRegArgs) saveCertificateChanges(ctx context.Context, domain string, changes []ssl.FieldChange, detectedAt time.Time) {
+	records := make([]*models.CertificateChange, 0, len(changes))
+	for _, change := range changes {
+		records = append(records, &models.CertificateChange{
+			Domain:     domain,
+			Field:      change.Field,
+			OldValue:   change.Old,
+			NewValue:   change.New,
+			DetectedAt: detectedAt,
+		})
 	}
-	var err error
-	switch *tp {
-	case expiryAlertStr:
-		if notification.EmailAlert {
-			err = args.sendNotificationToUserByEmail(tp)
-			if err != nil {
-				return err
-			}
+
+	if err := args.Strg.Changes().CreateCertificateChanges(ctx, records); err != nil {
+		args.Log.Errorf("Failed to save certificate changes of %s: %s", domain, err)
+	}
+}
+
+// cleanupHistory applies the retention and downsampling settings to the poll history
+func (args *UpdateDomainRegArgs) cleanupHistory(ctx context.Context) {
+	if args.Cfg.History.DownsampleAfter > 0 && args.Cfg.History.DownsampleBucket > 0 {
+		deleted, err := args.Strg.History().DownsamplePollSnapshots(ctx, time.Now().Add(-args.Cfg.History.DownsampleAfter), args.Cfg.History.DownsampleBucket)
+		if err != nil {
+			args.Log.Errorf("Failed to downsample poll history: %s", err)
+		} else {
+			args.Log.Info("Downsampled poll history, deleted snapshots -> ", deleted)
 		}
-		if notification.TelegramAlert {
-			err = args.sendNotificationToUserByTelegram(tp, user, domainPrInfo)
-			if err != nil {
-				return err
-			}
+	}
+
+	if args.Cfg.History.Retention > 0 {
+		deleted, err := args.Strg.History().DeletePollSnapshotsBefore(ctx, time.Now().Add(-args.Cfg.History.Retention))
+		if err != nil {
+			args.Log.Errorf("Failed to delete old poll history: %s", err)
 		} else {
-			err = errors.New("no alert method is set")
+			args.Log.Info("Deleted poll snapshots past retention -> ", deleted)
 		}
-	case changeAlertStr:
+	}
+}
+
+// pollOutcome is what a poll means for the owners of the domain, it is saved together with the poll result
+type pollOutcome struct {
+	incidents []*models.Incident
+	reminders []*models.SentReminder
+	alerts    []*models.Alert
+}
+
+// domainOwner is one of the users that track the polled domain, with what the alerts to them depend on
+type domainOwner struct {
+	userID       int64
+	notification *models.Notification
+	schedule     []int // days before expiry the user is reminded at, longest first
+	unresolved   []*models.Incident
+	reminders    *certificateReminders
+	escalation   *int64  // the policy that escalates the new incidents
+	suppressedBy *string // the alerts to the user are recorded but not delivered, see suppressedBy
+	integrations []*models.Integration
+	policies     []*models.EscalationPolicy
+	routing      []*models.RoutingRule // tried in order, see matchRoutingRule
+	tags         []string              // of the domain, the routing rules match them
+}
+
+// filterDomainsOwnersNotif evaluates the incidents and reminders of every user that tracks the polled domain, and returns
+// them with the alerts the poll raised, as the users' notification settings allow. All of it is saved together with the poll result.
+func (args *UpdateDomainRegArgs) filterDomainsOwnersNotif(ctx context.Context, v *DomainNowAndPreviousInfo) (*pollOutcome, error) {
+	users, err := args.Strg.Domain().GetListofUsersThatDomainExists(ctx, v.DomainName)
+	if err != nil {
+		args.Log.Errorf("error getting list of user that has this domain %s", err)
+		return nil, err
+	}
+	var (
+		now     = time.Now()
+		outcome = &pollOutcome{}
+	)
+	for _, userId := range users {
+		domain, err := args.Strg.Domain().GetDomainWithUserIDAndDomainName(ctx, &ssl.DomainTracking{
+			DomainName: v.DomainName,
+			UserID:     userId,
+		})
+		if err != nil {
+			args.Log.Errorf("error getting domain with user id and domain name %s", err)
+			continue
+		}
+		notification, err := args.Strg.Notifications().GetNotificationRowByUserID(ctx, userId)
+		if err != nil {
+			args.Log.Errorf("error getting notification row by userid %d", err)
+			continue
+		}
+		// without the incidents and the sent reminders every problem would look new, better to try the poll again
+		unresolved, err := args.Strg.Incidents().GetUnresolvedIncidents(ctx, userId, v.DomainName)
+		if err != nil {
+			return nil, err
+		}
+		reminders, err := args.loadCertificateReminders(ctx, userId, v)
+		if err != nil {
+			return nil, err
+		}
+		policies, err := args.Strg.Escalations().GetEscalationPoliciesByUserID(ctx, userId)
+		if err != nil {
+			args.Log.Errorf("Failed to get escalation policies of user %d: %s", userId, err)
+		}
+		integrations, err := args.Strg.Integrations().GetIntegrationsByUserID(ctx, userId)
+		if err != nil {
+			args.Log.Errorf("Failed to get integrations of user %d: %s", userId, err)
+		}
+		routing, err := args.Strg.Routing().GetRoutingRulesByUserID(ctx, userId)
+		if err != nil {
+			args.Log.Errorf("Failed to get routing rules of user %d: %s", userId, err)
+		}
+
+		owner := &domainOwner{
+			userID:       userId,
+			notification: notification,
+			schedule:     reminderSchedule(notification, domain),
+			unresolved:   unresolved,
+			reminders:    reminders,
+			escalation:   matchEscalationPolicy(policies, domain),
+			policies:     policies,
+			suppressedBy: args.domainSuppression(ctx, userId, domain, now),
+			integrations: integrations,
+			routing:      routing,
+			tags:         domain.Tags,
+		}
+		args.evaluateIncidents(owner, v, now, outcome)
+		args.alertsForUser(owner, v, now, outcome)
+	}
+	return outcome, nil
+}
+
+// alertsForUser queues the renewal window or the overdue renewal alert for the user, each of them once per certificate
+func (args *UpdateDomainRegArgs) alertsForUser(owner *domainOwner, domainPrInfo *DomainNowAndPreviousInfo, now time.Time, outcome *pollOutcome) {
+	if !owner.notification.ExpiryAlerts {
+		return
+	}
 
+	var tp, kind string
+	switch {
+	case isRenewalWindowOpen(domainPrInfo.RenewalInfo):
+		tp, kind = renewalAlertStr, models.ReminderRenewal
+	case domainPrInfo.RenewalOverdue:
+		tp, kind = overdueAlertStr, models.ReminderOverdue
 	default:
-		err = fmt.Errorf("unknown type %v", tp)
+		return
 	}
 
-	return err
+	reminder, ok := owner.reminders.take(kind, 0, now)
+	if !ok {
+		return
+	}
+	outcome.reminders = append(outcome.reminders, reminder)
+	outcome.alerts = append(outcome.alerts, args.addressAlert(owner, tp, domainPrInfo.DomainName, alertPayload(tp, domainPrInfo), nil)...)
 }
 
-// tp = {change_alert or expiry_alert}
-// Email Notification
-func (args *UpdateDomainRegArgs) sendNotificationToUserByEmail(tp *string) error {
-	if tp == nil {
-		return errors.New("nil notification type")
+// addressAlert addresses the alert to every channel the user turned on, and to every connection of the integrations the user
+// turned on, suppressed while the domain is snoozed or under maintenance. An alert that is not urgent waits for the end of the user's quiet hours,
+// except on the incident tools and the webhooks.
+// The first routing rule of the user that matches the alert narrows it down to the destinations of the rule.
+func (args *UpdateDomainRegArgs) addressAlert(owner *domainOwner, tp string, domain string, payload models.AlertPayload, incident *models.Incident) []*models.Alert {
+	args.Log.Info("Alert ", tp, " ", domain)
+	deliverAt := alertDeliveryTime(owner.notification, tp, time.Now())
+	route := matchRoutingRule(owner.routing, domain, owner.tags, tp, payload)
+
+	channels := make([]string, 0, 2)
+	if owner.notification.EmailAlert && routesTo(route, models.ChannelEmail, nil) {
+		channels = append(channels, models.ChannelEmail)
 	}
-	return nil
+	if owner.notification.TelegramAlert && routesTo(route, models.ChannelTelegram, nil) {
+		channels = append(channels, models.ChannelTelegram)
+	}
+
+	alerts := make([]*models.Alert, 0, len(channels))
+	for _, channel := range channels {
+		alerts = append(alerts, &models.Alert{
+			UserID:        owner.userID,
+			Domain:        domain,
+			Type:          tp,
+			Channel:       channel,
+			Payload:       payload,
+			Incident:      incident,
+			MaxAttempts:   args.maxAlertAttempts(),
+			NextAttemptAt: deliverAt,
+		})
+	}
+	escalated := make(map[int64]bool)
+	if tp == recoveryAlertStr && incident != nil {
+		escalated = escalatedPagingIntegrations(owner.policies, incident)
+	}
+	for _, integration := range owner.integrations {
+		// the page of an escalation step is resolved wherever the recovery is routed
+		if !escalated[integration.ID] && (!integrationEnabled(owner.notification, integration.Kind) || !routesTo(route, integration.Kind, &integration.ID)) {
+			continue
+		}
+		if isPagingKind(integration.Kind) && !isPageable(tp) {
+			continue
+		}
+		integrationDeliverAt := deliverAt
+		if !quietIntegration(integration.Kind) {
+			integrationDeliverAt = time.Now()
+		}
+		if integration.Kind == models.IntegrationSMS {
+			if !isSMSWorthy(tp, payload) {
+				continue
+			}
+			// the on-call phone only gets the last-minute alerts, they don't wait for quiet hours
+			integrationDeliverAt = time.Now()
+		}
+		integrationPayload := payload
+		if integration.Kind == models.IntegrationWebhook {
+			// the endpoint tells a retried event from a new one by its key
+			integrationPayload.EventID = uuid.NewString()
+		}
+		alerts = append(alerts, &models.Alert{
+			UserID:        owner.userID,
+			Domain:        domain,
+			Type:          tp,
+			Channel:       integration.Kind,
+			IntegrationID: &integration.ID,
+			Payload:       integrationPayload,
+			Incident:      incident,
+			MaxAttempts:   args.maxAlertAttempts(),
+			NextAttemptAt: integrationDeliverAt,
+		})
+	}
+	if route != nil {
+		for _, alert := range alerts {
+			alert.RoutedBy = &route.Name
+		}
+	}
+	return suppress(alerts, owner.suppressedBy)
 }
 
-// tp = {change_alert or expiry_alert}
-// Telegram Notification
-func (args *UpdateDomainRegArgs) sendNotificationToUserByTelegram(tp *string, user *models.User, domainPrInfo *DomainNowAndPreviousInfo) error {
-	if tp == nil {
-		return errors.New("nil notification type")
+// alertPayload keeps what the message of the renewal alert needs, so it can be sent long after the poll
+func alertPayload(tp string, domainPrInfo *DomainNowAndPreviousInfo) models.AlertPayload {
+	payload := models.AlertPayload{
+		Expires: domainPrInfo.Current.Expires,
+	}
+	if tp == renewalAlertStr {
+		payload.RenewalWindowStart = &domainPrInfo.RenewalInfo.WindowStart
+		payload.RenewalWindowEnd = &domainPrInfo.RenewalInfo.WindowEnd
+		payload.ExplanationURL = domainPrInfo.RenewalInfo.ExplanationURL
+	}
+	return payload
+}
+
+// sendNotificationChangeOrExpire delivers the alert over its channel
+func (args *UpdateDomainRegArgs) sendNotificationChangeOrExpire(ctx context.Context, alert *models.Alert) error {
+	switch alert.Type {
+	case expiryAlertStr, expiredAlertStr, invalidAlertStr, revokedAlertStr, offlineAlertStr, changeAlertStr, recoveryAlertStr, renewalAlertStr, overdueAlertStr, escalationAlertStr:
+	case domainAddedAlertStr, domainRemovedAlertStr:
+		if alert.Channel != models.ChannelWebhook {
+			return fmt.Errorf("type %v only goes to webhooks", alert.Type)
+		}
+	default:
+		return fmt.Errorf("unknown type %v", alert.Type)
 	}
-	userTg, err := args.Strg.Integrations().GetFromTelegramByUserID(context.Background(), user.ID)
+
+	switch alert.Channel {
+	case models.ChannelEmail:
+		return args.sendNotificationToUserByEmail(ctx, alert)
+	case models.ChannelTelegram:
+		return args.sendNotificationToUserByTelegram(ctx, alert)
+	case models.ChannelSlack:
+		return args.sendNotificationToSlack(ctx, alert)
+	case models.ChannelDiscord:
+		return args.sendNotificationToDiscord(ctx, alert)
+	case models.ChannelTeams:
+		return args.sendNotificationToTeams(ctx, alert)
+	case models.ChannelWebhook:
+		return args.sendNotificationToWebhook(ctx, alert)
+	case models.ChannelPagerDuty:
+		return args.sendNotificationToPagerDuty(ctx, alert)
+	case models.ChannelOpsgenie:
+		return args.sendNotificationToOpsgenie(ctx, alert)
+	case models.ChannelSMS:
+		return args.sendNotificationBySMS(ctx, alert)
+	default:
+		if isPushKind(alert.Channel) {
+			return args.sendNotificationToPushChannel(ctx, alert)
+		}
+		return fmt.Errorf("unknown channel %v", alert.Channel)
+	}
+}
+
+// alert.Type = {change_alert, expiry_alert, renewal_alert, renewal_overdue_alert or escalation_alert}
+// Telegram Notification
+func (args *UpdateDomainRegArgs) sendNotificationToUserByTelegram(ctx context.Context, alert *models.Alert) error {
+	chatID, lang, err := args.telegramChat(ctx, alert)
 	if err != nil {
 		return err
 	}
-	if userTg.ChatID == 0 {
-		return fmt.Errorf("no chat id for telegram notification to user id %v", user.ID)
-	}
 	var msg string
-	if userTg.Lang == "uz" {
-		msg = "Assalomu Alaykum 👋️️️️,\n\nSiz kuzatayotgan domen, " + domainPrInfo.DomainName + ", "
-	} else if userTg.Lang == "ru" {
-		msg = "Здравствуйте 👋️️️️,\n\nВаш отслеживаемый домен, " + domainPrInfo.DomainName + ", "
-	} else if userTg.Lang == "eng" {
-		msg = "Hello 👋️️️️,\n\nYour tracked domain, " + domainPrInfo.DomainName + ", "
+	if lang == "uz" {
+		msg = "Assalomu Alaykum 👋️️️️,\n\nSiz kuzatayotgan domen, " + alert.Domain + ", "
+	} else if lang == "ru" {
+		msg = "Здравствуйте 👋️️️️,\n\nВаш отслеживаемый домен, " + alert.Domain + ", "
+	} else if lang == "eng" {
+		msg = "Hello 👋️️️️,\n\nYour tracked domain, " + alert.Domain + ", "
 	} else {
-		return fmt.Errorf("unsupported language code %s", userTg.Lang)
+		return fmt.Errorf("unsupported language code %s", lang)
 	}
-	switch *tp {
+	switch alert.Type {
 	case expiryAlertStr:
-		fmt.Println(domainPrInfo.DomainName)
-		// ! here is the panic from reading value of Expires.
-		lft := daysUntilExpiration(*domainPrInfo.Current.Expires)
-		if userTg.Lang == "uz" {
+		if alert.Payload.Expires == nil {
+			return errors.New("expiry alert without expiration date")
+		}
+		lft := daysUntilExpiration(*alert.Payload.Expires)
+		if lang == "uz" {
 			msg += fmt.Sprintf("yaqinlashib kelayotgan SSL muddati bor. Faqat [%v] kun qoldi. Zudlik bilan harakat qiling-tafsilotlarni tekshiring [%v].", lft, args.Cfg.BaseUrl)
-		} else if userTg.Lang == "ru" {
+		} else if lang == "ru" {
 			msg += fmt.Sprintf("истекает срок действия SSL. Осталось всего [%v] дней. Действуйте незамедлительно - проверьте подробности на [%v].", lft, args.Cfg.BaseUrl)
-		} else if userTg.Lang == "eng" {
+		} else if lang == "eng" {
 			msg += fmt.Sprintf("has an upcoming SSL expiration. Only [%v] days left. Act promptly - check details at [%v].", lft, args.Cfg.BaseUrl)
 		} else {
-			return fmt.Errorf("unsupported language code %s", userTg.Lang)
+			return fmt.Errorf("unsupported language code %s", lang)
+		}
+	case renewalAlertStr:
+		if alert.Payload.RenewalWindowStart == nil || alert.Payload.RenewalWindowEnd == nil {
+			return errors.New("renewal alert without renewal window")
+		}
+		windowStart := alert.Payload.RenewalWindowStart.Format(time.RFC1123)
+		windowEnd := alert.Payload.RenewalWindowEnd.Format(time.RFC1123)
+		if lang == "uz" {
+			msg += fmt.Sprintf("sertifikat markazi SSL sertifikatini yangilashni tavsiya qilmoqda. Tavsiya etilgan muddat: [%v] - [%v], lekin sertifikat hali almashtirilmagan. Tafsilotlarni tekshiring [%v].", windowStart, windowEnd, args.Cfg.BaseUrl)
+		} else if lang == "ru" {
+			msg += fmt.Sprintf("центр сертификации рекомендует обновить SSL сертификат. Рекомендуемый период: [%v] - [%v], но сертификат ещё не заменён. Проверьте подробности на [%v].", windowStart, windowEnd, args.Cfg.BaseUrl)
+		} else if lang == "eng" {
+			msg += fmt.Sprintf("is inside the renewal window suggested by its certificate authority: [%v] - [%v], but the SSL certificate has not been replaced yet. Check details at [%v].", windowStart, windowEnd, args.Cfg.BaseUrl)
+		} else {
+			return fmt.Errorf("unsupported language code %s", lang)
+		}
+		if alert.Payload.ExplanationURL != nil {
+			msg += "\n\n" + *alert.Payload.ExplanationURL
+		}
+	case overdueAlertStr:
+		if alert.Payload.Expires == nil {
+			return errors.New("overdue alert without expiration date")
+		}
+		lft := daysUntilExpiration(*alert.Payload.Expires)
+		if lang == "uz" {
+			msg += fmt.Sprintf("odatda bu vaqtgacha SSL sertifikatini yangilab bo'lardi, lekin hali yangilanmagan. [%v] kun qoldi. Avtomatik yangilanishni tekshiring - tafsilotlar [%v].", lft, args.Cfg.BaseUrl)
+		} else if lang == "ru" {
+			msg += fmt.Sprintf("обычно к этому времени уже обновляет SSL сертификат, но он ещё не обновлён. Осталось [%v] дней. Проверьте автоматическое обновление - подробности на [%v].", lft, args.Cfg.BaseUrl)
+		} else if lang == "eng" {
+			msg += fmt.Sprintf("usually has its SSL certificate renewed by now, but it has not been renewed yet. [%v] days left. Check your automatic renewal - details at [%v].", lft, args.Cfg.BaseUrl)
+		} else {
+			return fmt.Errorf("unsupported language code %s", lang)
 		}
 	case changeAlertStr:
-		// TODO: write the logic of sending change domain ssl certificate notification!
-		args.Log.Info("sending change alert notification")
+		changes := formatChanges(alert.Payload.Changes)
+		if lang == "uz" {
+			msg += fmt.Sprintf("SSL sertifikatida o'zgarishlar aniqlandi:\n\n%vTafsilotlarni tekshiring [%v].", changes, args.Cfg.BaseUrl)
+		} else if lang == "ru" {
+			msg += fmt.Sprintf("изменения в SSL сертификате:\n\n%vПроверьте подробности на [%v].", changes, args.Cfg.BaseUrl)
+		} else if lang == "eng" {
+			msg += fmt.Sprintf("has changes in its SSL certificate:\n\n%vCheck details at [%v].", changes, args.Cfg.BaseUrl)
+		} else {
+			return fmt.Errorf("unsupported language code %s", lang)
+		}
+	case expiredAlertStr:
+		if lang == "uz" {
+			msg += fmt.Sprintf("SSL sertifikatining muddati tugagan. Tashrif buyuruvchilar xavfsizlik ogohlantirishini ko'rmoqda. Zudlik bilan yangilang - tafsilotlar [%v].", args.Cfg.BaseUrl)
+		} else if lang == "ru" {
+			msg += fmt.Sprintf("срок действия SSL сертификата истёк. Посетители видят предупреждение безопасности. Обновите его немедленно - подробности на [%v].", args.Cfg.BaseUrl)
+		} else if lang == "eng" {
+			msg += fmt.Sprintf("has an expired SSL certificate. Visitors are seeing security warnings. Renew it now - details at [%v].", args.Cfg.BaseUrl)
+		} else {
+			return fmt.Errorf("unsupported language code %s", lang)
+		}
+	case revokedAlertStr:
+		reason := "certificate revoked"
+		if alert.Payload.Error != nil {
+			reason = *alert.Payload.Error
+		}
+		if lang == "uz" {
+			msg += fmt.Sprintf("SSL sertifikati bekor qilingan: %v. Brauzerlar uni rad etishi mumkin, zudlik bilan almashtiring - tafsilotlar [%v].", reason, args.Cfg.BaseUrl)
+		} else if lang == "ru" {
+			msg += fmt.Sprintf("SSL сертификат отозван: %v. Браузеры могут его отклонить, замените его немедленно - подробности на [%v].", reason, args.Cfg.BaseUrl)
+		} else if lang == "eng" {
+			msg += fmt.Sprintf("serves a revoked SSL certificate: %v. Browsers may reject it, replace it now - details at [%v].", reason, args.Cfg.BaseUrl)
+		} else {
+			return fmt.Errorf("unsupported language code %s", lang)
+		}
+	case invalidAlertStr, offlineAlertStr:
+		reason := "unavailable"
+		if alert.Payload.Error != nil {
+			reason = *alert.Payload.Error
+		}
+		if alert.Type == invalidAlertStr {
+			if lang == "uz" {
+				msg += fmt.Sprintf("SSL sertifikati tekshiruvdan o'tmadi: %v. Tafsilotlarni tekshiring [%v].", reason, args.Cfg.BaseUrl)
+			} else if lang == "ru" {
+				msg += fmt.Sprintf("SSL сертификат не прошёл проверку: %v. Проверьте подробности на [%v].", reason, args.Cfg.BaseUrl)
+			} else if lang == "eng" {
+				msg += fmt.Sprintf("serves an SSL certificate that fails verification: %v. Check details at [%v].", reason, args.Cfg.BaseUrl)
+			} else {
+				return fmt.Errorf("unsupported language code %s", lang)
+			}
+		} else {
+			if lang == "uz" {
+				msg += fmt.Sprintf("javob bermayapti: %v. Tafsilotlarni tekshiring [%v].", reason, args.Cfg.BaseUrl)
+			} else if lang == "ru" {
+				msg += fmt.Sprintf("не отвечает: %v. Проверьте подробности на [%v].", reason, args.Cfg.BaseUrl)
+			} else if lang == "eng" {
+				msg += fmt.Sprintf("is not responding: %v. Check details at [%v].", reason, args.Cfg.BaseUrl)
+			} else {
+				return fmt.Errorf("unsupported language code %s", lang)
+			}
+		}
+	case recoveryAlertStr:
+		problem := ProblemTitle(alert.Payload.Problem)
+		if lang == "uz" {
+			msg += fmt.Sprintf("yana sog'lom ✅. Hal qilingan muammo: [%v]. Tafsilotlar [%v].", problem, args.Cfg.BaseUrl)
+		} else if lang == "ru" {
+			msg += fmt.Sprintf("снова в порядке ✅. Решённая проблема: [%v]. Подробности на [%v].", problem, args.Cfg.BaseUrl)
+		} else if lang == "eng" {
+			msg += fmt.Sprintf("has recovered ✅. Resolved problem: [%v]. Details at [%v].", problem, args.Cfg.BaseUrl)
+		} else {
+			return fmt.Errorf("unsupported language code %s", lang)
+		}
+	case escalationAlertStr:
+		problem := ProblemTitle(alert.Payload.Problem)
+		openedAt := ""
+		if alert.Payload.OpenedAt != nil {
+			openedAt = alert.Payload.OpenedAt.Format(time.RFC1123)
+		}
+		if lang == "uz" {
+			msg += fmt.Sprintf("hali hech kim qabul qilmagan muammoga ega: [%v], [%v] dan beri. Eskalatsiya bosqichi %v. Tafsilotlarni tekshiring [%v].", problem, openedAt, alert.Payload.EscalationStep, args.Cfg.BaseUrl)
+		} else if lang == "ru" {
+			msg += fmt.Sprintf("имеет проблему, которую ещё никто не принял: [%v], с [%v]. Шаг эскалации %v. Проверьте подробности на [%v].", problem, openedAt, alert.Payload.EscalationStep, args.Cfg.BaseUrl)
+		} else if lang == "eng" {
+			msg += fmt.Sprintf("has a problem nobody has acknowledged yet: [%v], since [%v]. Escalation step %v. Check details at [%v].", problem, openedAt, alert.Payload.EscalationStep, args.Cfg.BaseUrl)
+		} else {
+			return fmt.Errorf("unsupported language code %s", lang)
+		}
 	default:
-		return errors.New("unknown type " + *tp)
+		return errors.New("unknown type " + alert.Type)
 	}
 
-	message := tgbotapi.NewMessage(userTg.ChatID, msg)
+	message := tgbotapi.NewMessage(chatID, msg)
+	// reminders of an incident stop once it is acknowledged, which can be done right from the message
+	if alert.Payload.AckToken != "" && alert.Type != recoveryAlertStr {
+		message.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(ackButtonText(lang), AckCallbackPrefix+alert.Payload.AckToken),
+			),
+		)
+	}
 	if _, err := args.Bot.Send(message); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// telegramChat returns the chat the alert goes to and the language of its message: the chat of the user,
+// or the chat id of an escalation step, written in the user's language if the user has connected telegram
+func (args *UpdateDomainRegArgs) telegramChat(ctx context.Context, alert *models.Alert) (int64, string, error) {
+	userTg, err := args.Strg.Integrations().GetFromTelegramByUserID(ctx, alert.UserID)
+	if alert.Recipient == nil {
+		if err != nil {
+			return 0, "", err
+		}
+		if userTg.ChatID == 0 {
+			return 0, "", fmt.Errorf("no chat id for telegram notification to user id %v", alert.UserID)
+		}
+		return userTg.ChatID, userTg.Lang, nil
+	}
+
+	chatID, parseErr := strconv.ParseInt(*alert.Recipient, 10, 64)
+	if parseErr != nil {
+		return 0, "", fmt.Errorf("telegram chat id %v is not valid", *alert.Recipient)
+	}
+	lang := "eng"
+	if err == nil && userTg.Lang != "" {
+		lang = userTg.Lang
+	}
+	return chatID, lang, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/SaidovZohid/certalert.info/pkg/ssl"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/SaidovZohid/certalert.info/storage/models"
@@ -11,51 +12,140 @@ import (
 
 var changeAlertStr = "change_alert"
 var expiryAlertStr = "expiry_alert"
+var renewalAlertStr = "renewal_alert"
+var overdueAlertStr = "renewal_overdue_alert"
 
-// returns is lastAlertTime is one day bigger or not. If bigger one day returns true, otherwise false
-func isLastAlertTimeOneDayAgo(lastAlertTime time.Time) bool {
-	// Calculate the duration between current time and last_alert_time
-	duration := time.Since(lastAlertTime)
+// returns the sha256 fingerprint of the polled certificate, nil if the poll got no certificate
+func fingerprintOf(info *ssl.TrackingDomainInfo) *string {
+	if info.EncodedPEM == nil {
+		return nil
+	}
+	fingerprint, err := ssl.FingerprintFromPEM(*info.EncodedPEM)
+	if err != nil {
+		return nil
+	}
+	return &fingerprint
+}
 
-	// if the duration is greater than or equal to 24 hours; return true; return false
-	return duration >= 24*time.Hour
+// returns the certificate of the history with the fingerprint, or the last seen one when the poll got no certificate
+func servedCertificate(certs []*models.Certificate, fingerprint *string) *models.Certificate {
+	var served *models.Certificate
+	for _, cert := range certs {
+		if fingerprint != nil {
+			if cert.Fingerprint == *fingerprint {
+				return cert
+			}
+			continue
+		}
+		if served == nil || cert.LastSeen.After(served.LastSeen) {
+			served = cert
+		}
+	}
+	return served
 }
 
-// checks the expiration and change on ssl. If expiration has returns true, otherwise false. If change has on domain's ssl returns true, otherwise false
-func checkExpiryAndChangeSSLOfDomain(domainPrInfo *DomainNowAndPreviousInfo, notification *models.Notification) (expiryAlert, changeAlert bool) {
-	log.Println(*domainPrInfo.Current)
+// returns the serial number that identifies the certificate, its fingerprint for the certificates seen before serials were recorded
+func certificateSerial(cert *models.Certificate) string {
+	if cert.Serial != nil {
+		return *cert.Serial
+	}
+	return cert.Fingerprint
+}
 
-	// Check if the current expiration date is nil
-	if domainPrInfo.Current.Expires == nil {
-		return domainPrInfo.Current.Expires != domainPrInfo.Prev.Expires, true
+// returns when the certificate of the domain expires. A certificate that fails verification is not read by the poll,
+// then the expiry of the certificate the domain served last is used.
+func certificateExpires(domainPrInfo *DomainNowAndPreviousInfo) *time.Time {
+	if domainPrInfo.Current.Expires != nil {
+		return domainPrInfo.Current.Expires
+	}
+	if domainPrInfo.Certificate != nil {
+		return domainPrInfo.Certificate.Expires
+	}
+	return nil
+}
+
+// returns true when the CA's suggested renewal window is open and the certificate it was issued for is still served
+func isRenewalWindowOpen(renewalInfo *models.RenewalInfo) bool {
+	if renewalInfo == nil {
+		return false
+	}
+	return !time.Now().Before(renewalInfo.WindowStart)
+}
+
+// usualRenewalLeadTime learns how long before expiry the domain's certificates usually get replaced.
+// It is the median of (old certificate expiry - first time its successor was seen) over the observed rotations.
+// ok is false while no rotation has been seen yet.
+func usualRenewalLeadTime(certs []*models.Certificate) (lead time.Duration, ok bool) {
+	leads := make([]time.Duration, 0, len(certs))
+	for i := 1; i < len(certs); i++ {
+		prev, next := certs[i-1], certs[i]
+		if prev.Expires == nil {
+			continue
+		}
+		// replaced only after it had already expired, there is nothing to learn from it
+		if lead := prev.Expires.Sub(next.FirstSeen); lead > 0 {
+			leads = append(leads, lead)
+		}
+	}
+	if len(leads) == 0 {
+		return 0, false
 	}
 
-	// Calculate the expiration date with the notification period
-	expirationDate := domainPrInfo.Current.Expires.AddDate(0, 0, -notification.Before)
+	sort.Slice(leads, func(i, j int) bool { return leads[i] < leads[j] })
+	return leads[len(leads)/2], true
+}
 
-	// Get the current date
-	currentDate := time.Now()
+// returns true when the certificate expiring at expires has gone past the domain's usual renewal point by more than grace
+func isRenewalOverdue(certs []*models.Certificate, expires *time.Time, grace time.Duration) bool {
+	if expires == nil {
+		return false
+	}
+	lead, ok := usualRenewalLeadTime(certs)
+	if !ok {
+		return false
+	}
+	return time.Until(*expires) < lead-grace
+}
 
-	// Check if the certificate has expired
-	expiryAlert = currentDate.After(expirationDate)
+// checks the expiration and change on ssl. If the certificate is inside the reminder schedule returns true, otherwise false. If change the user cares about has on domain's ssl returns true, otherwise false
+func checkExpiryAndChangeSSLOfDomain(domainPrInfo *DomainNowAndPreviousInfo, notification *models.Notification, schedule []int) (expiryAlert, changeAlert bool) {
+	log.Println(*domainPrInfo.Current)
 
-	// Check for changes in certificate details if RemoteAddr is not nil
-	if domainPrInfo.Current.RemoteAddr != nil {
-		changeAlert = hasCertificateDetailsChanged(domainPrInfo.Prev, domainPrInfo.Current)
+	// No certificate was served on this poll, so there is no expiration to count down and nothing to compare
+	if domainPrInfo.Current.Expires == nil {
+		return false, false
 	}
 
+	// Check if the certificate has passed the longest threshold of the schedule
+	_, _, expiryAlert = dueExpiryReminder(*domainPrInfo.Current.Expires, schedule, time.Now())
+
+	// Check for changes in the certificate fields the user wants change alerts for
+	changeAlert = len(alertWorthyChanges(domainPrInfo.Changes, notification.ChangeAlertFields)) > 0
+
 	return expiryAlert, changeAlert
 }
 
-// hasCertificateDetailsChanged checks for changes in certificate details
-func hasCertificateDetailsChanged(prev, current *ssl.TrackingDomainInfo) bool {
-	return *prev.RemoteAddr != *current.RemoteAddr ||
-		*prev.Issuer != *current.Issuer ||
-		*prev.PublicKey != *current.PublicKey ||
-		*prev.DNSNames != *current.DNSNames ||
-		*prev.KeyUsage != *current.KeyUsage ||
-		*prev.ExtKeyUsages != *current.ExtKeyUsages ||
-		*prev.Status != *current.Status
+// alertWorthyChanges keeps only the changes of the fields the user picked for change alerts
+func alertWorthyChanges(changes []ssl.FieldChange, fields []string) []ssl.FieldChange {
+	result := make([]ssl.FieldChange, 0, len(changes))
+	for _, change := range changes {
+		for _, field := range fields {
+			if change.Field == field {
+				result = append(result, change)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// formatChanges renders the changes as one "Field: old → new" line each
+func formatChanges(changes []ssl.FieldChange) string {
+	var lines string
+	for _, change := range changes {
+		lines += fmt.Sprintf("• %s: %s → %s\n", ssl.FieldTitle(change.Field), change.Old, change.New)
+	}
+	return lines
 }
 
 // returns how many days left to ssl of domain expiration
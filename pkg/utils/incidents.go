@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+var expiredAlertStr = "expired_alert"
+var invalidAlertStr = "invalid_alert"
+var revokedAlertStr = "revoked_alert"
+var offlineAlertStr = "offline_alert"
+var recoveryAlertStr = "recovery_alert"
+
+// AckCallbackPrefix starts the callback data of the telegram button that acknowledges an incident, the ack token follows it
+const AckCallbackPrefix = "ack:"
+
+// reminders of an open incident go out this often until it is acknowledged, snoozed or resolved.
+// Expiry incidents are reminded by the reminder schedule instead.
+const incidentReminderInterval = 24 * time.Hour
+
+// problems in the order their incidents are evaluated and their alerts are queued
+var incidentProblems = []string{
+	models.ProblemExpired,
+	models.ProblemExpiring,
+	models.ProblemInvalid,
+	models.ProblemRevoked,
+	models.ProblemOffline,
+	models.ProblemChanged,
+}
+
+var problemAlertTypes = map[string]string{
+	models.ProblemExpiring: expiryAlertStr,
+	models.ProblemExpired:  expiredAlertStr,
+	models.ProblemInvalid:  invalidAlertStr,
+	models.ProblemRevoked:  revokedAlertStr,
+	models.ProblemOffline:  offlineAlertStr,
+	models.ProblemChanged:  changeAlertStr,
+}
+
+func ackButtonText(lang string) string {
+	switch lang {
+	case "uz":
+		return "✅ Qabul qilindi"
+	case "ru":
+		return "✅ Принято"
+	}
+	return "✅ Acknowledge"
+}
+
+// ProblemTitle returns the human readable name of the incident problem
+func ProblemTitle(problem string) string {
+	switch problem {
+	case models.ProblemExpiring:
+		return "Expiring soon"
+	case models.ProblemExpired:
+		return "Expired"
+	case models.ProblemInvalid:
+		return "Invalid certificate"
+	case models.ProblemRevoked:
+		return "Revoked certificate"
+	case models.ProblemOffline:
+		return "Offline"
+	case models.ProblemChanged:
+		return "Certificate changed"
+	}
+	return problem
+}
+
+// currentProblems returns the problems the poll found with the domain, as the user sees them.
+// An expired certificate fails verification too, so it is not counted as invalid as well.
+func currentProblems(domainPrInfo *DomainNowAndPreviousInfo, notification *models.Notification, schedule []int, now time.Time) map[string]bool {
+	problems := make(map[string]bool)
+
+	expires := certificateExpires(domainPrInfo)
+	status := domainPrInfo.Current.Status
+
+	expiryAlert, changeAlert := checkExpiryAndChangeSSLOfDomain(domainPrInfo, notification, schedule)
+	switch {
+	case status != nil && *status == ssl.StatusExpired, expires != nil && now.After(*expires):
+		problems[models.ProblemExpired] = true
+	case expiryAlert:
+		problems[models.ProblemExpiring] = true
+	}
+
+	if status != nil && !problems[models.ProblemExpired] {
+		switch *status {
+		case ssl.StatusInvalid:
+			problems[models.ProblemInvalid] = true
+		case ssl.StatusRevoked:
+			problems[models.ProblemRevoked] = true
+		case ssl.StatusOffline, ssl.StatusUnResponsive:
+			problems[models.ProblemOffline] = true
+		}
+	}
+
+	if changeAlert {
+		problems[models.ProblemChanged] = true
+	}
+
+	return problems
+}
+
+// isProblemAlertOn reports whether the user wants alerts about the problem. Incidents are kept either way.
+func isProblemAlertOn(problem string, notification *models.Notification) bool {
+	switch problem {
+	case models.ProblemExpiring, models.ProblemExpired:
+		return notification.ExpiryAlerts
+	case models.ProblemChanged:
+		return notification.ChangeAlert
+	}
+	return true
+}
+
+// evaluateIncidents opens an incident for every new problem, reminds about the open ones and resolves the ones that cleared,
+// and adds the incidents to save and the alerts they raised to the outcome. The expiry of a certificate is reminded by the
+// owner's reminder schedule, every threshold once per certificate, other problems are reminded once a day.
+// A change incident is not resolved by the poller, every new change while it is unresolved is alerted on,
+// and it stays until the user acknowledges or resolves it.
+func (args *UpdateDomainRegArgs) evaluateIncidents(owner *domainOwner, domainPrInfo *DomainNowAndPreviousInfo, now time.Time, outcome *pollOutcome) {
+	var (
+		problems  = currentProblems(domainPrInfo, owner.notification, owner.schedule, now)
+		byProblem = make(map[string]*models.Incident, len(owner.unresolved))
+	)
+	for _, incident := range owner.unresolved {
+		byProblem[incident.Problem] = incident
+	}
+
+	notify := func(incident *models.Incident, tp string) {
+		incident.LastNotifiedAt = &now
+		if !isProblemAlertOn(incident.Problem, owner.notification) {
+			return
+		}
+		payload := incidentPayload(domainPrInfo, owner.notification, incident)
+		outcome.alerts = append(outcome.alerts, args.addressAlert(owner, tp, domainPrInfo.DomainName, payload, incident)...)
+	}
+
+	// remind sends the expiry reminder the open incident is due for, unless it was sent about the certificate already
+	remind := func(incident *models.Incident) bool {
+		expires := certificateExpires(domainPrInfo)
+		if incident.Status != models.IncidentOpen || expires == nil {
+			return false
+		}
+		kind, days, ok := dueExpiryReminder(*expires, owner.schedule, now)
+		if !ok {
+			return false
+		}
+		reminder, ok := owner.reminders.take(kind, days, now)
+		if !ok {
+			return false
+		}
+		outcome.reminders = append(outcome.reminders, reminder)
+		notify(incident, problemAlertTypes[incident.Problem])
+		return true
+	}
+
+	for _, problem := range incidentProblems {
+		if !problems[problem] {
+			continue
+		}
+		isExpiry := problem == models.ProblemExpiring || problem == models.ProblemExpired
+
+		incident, ok := byProblem[problem]
+		if !ok {
+			incident = &models.Incident{
+				UserID:             owner.userID,
+				Domain:             domainPrInfo.DomainName,
+				Problem:            problem,
+				Status:             models.IncidentOpen,
+				AckToken:           GenerateRandomString(32),
+				OpenedAt:           now,
+				EscalationPolicyID: owner.escalation,
+			}
+			if isExpiry {
+				remind(incident)
+			} else {
+				notify(incident, problemAlertTypes[problem])
+			}
+			outcome.incidents = append(outcome.incidents, incident)
+			continue
+		}
+
+		if problem == models.ProblemChanged {
+			// another change, worth its own alert even if the last one was acknowledged
+			incident.Status = models.IncidentOpen
+			notify(incident, changeAlertStr)
+			outcome.incidents = append(outcome.incidents, incident)
+			continue
+		}
+
+		updated := false
+		if incident.Status == models.IncidentSnoozed && incident.SnoozedUntil != nil && now.After(*incident.SnoozedUntil) {
+			incident.Status = models.IncidentOpen
+			incident.SnoozedUntil = nil
+			updated = true
+		}
+		switch {
+		case isExpiry:
+			updated = remind(incident) || updated
+		case incident.Status == models.IncidentOpen && (incident.LastNotifiedAt == nil || now.Sub(*incident.LastNotifiedAt) >= incidentReminderInterval):
+			notify(incident, problemAlertTypes[problem])
+			updated = true
+		}
+		if updated {
+			outcome.incidents = append(outcome.incidents, incident)
+		}
+	}
+
+	for _, incident := range owner.unresolved {
+		if problems[incident.Problem] || incident.Problem == models.ProblemChanged {
+			continue
+		}
+		incident.Status = models.IncidentResolved
+		incident.ResolvedAt = &now
+		outcome.incidents = append(outcome.incidents, incident)
+
+		// an expiring certificate that expired has not recovered, the expired incident takes over.
+		// The incident tools close the expiring alert all the same, the expired one is opened with its own key.
+		if incident.Problem == models.ProblemExpiring && problems[models.ProblemExpired] {
+			if isProblemAlertOn(incident.Problem, owner.notification) {
+				payload := incidentPayload(domainPrInfo, owner.notification, incident)
+				outcome.alerts = append(outcome.alerts, args.addressPagingResolve(owner, domainPrInfo.DomainName, payload, incident)...)
+			}
+			continue
+		}
+		notify(incident, recoveryAlertStr)
+	}
+}
+
+// incidentPayload keeps what the message about the incident needs
+func incidentPayload(domainPrInfo *DomainNowAndPreviousInfo, notification *models.Notification, incident *models.Incident) models.AlertPayload {
+	payload := models.AlertPayload{
+		Expires:  certificateExpires(domainPrInfo),
+		Status:   domainPrInfo.Current.Status,
+		Error:    domainPrInfo.Current.Error,
+		Problem:  incident.Problem,
+		AckToken: incident.AckToken,
+	}
+	if incident.Problem == models.ProblemChanged {
+		payload.Changes = alertWorthyChanges(domainPrInfo.Changes, notification.ChangeAlertFields)
+	}
+	return payload
+}
@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/discord"
+	"github.com/SaidovZohid/certalert.info/pkg/opsgenie"
+	"github.com/SaidovZohid/certalert.info/pkg/pagerduty"
+	"github.com/SaidovZohid/certalert.info/pkg/slack"
+	"github.com/SaidovZohid/certalert.info/pkg/sms"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/pkg/teams"
+	"github.com/SaidovZohid/certalert.info/pkg/webhook"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+var slackClient = slack.NewClient()
+
+// IntegrationAlertColumns are the notification settings that turn the alerts of an integration kind on and off,
+// a user with several connections of a kind gets the alerts in every one of them
+var IntegrationAlertColumns = map[string]string{
+	models.IntegrationSlack:     "slack_alert",
+	models.IntegrationDiscord:   "discord_alert",
+	models.IntegrationTeams:     "microsoft_team_alert",
+	models.IntegrationWebhook:   "webhook_alert",
+	models.IntegrationPagerDuty: "pagerduty_alert",
+	models.IntegrationOpsgenie:  "opsgenie_alert",
+	models.IntegrationNtfy:      "ntfy_alert",
+	models.IntegrationGotify:    "gotify_alert",
+	models.IntegrationPushover:  "pushover_alert",
+	models.IntegrationMatrix:    "matrix_alert",
+	models.IntegrationSMS:       "sms_alert",
+}
+
+// IntegrationTitle returns the human readable name of the integration kind, or of the channel of an alert
+func IntegrationTitle(kind string) string {
+	switch kind {
+	case models.ChannelEmail:
+		return "Email"
+	case models.ChannelTelegram:
+		return "Telegram"
+	case models.IntegrationSlack:
+		return "Slack"
+	case models.IntegrationDiscord:
+		return "Discord"
+	case models.IntegrationTeams:
+		return "Microsoft Teams"
+	case models.IntegrationWebhook:
+		return "Webhook"
+	case models.IntegrationPagerDuty:
+		return "PagerDuty"
+	case models.IntegrationOpsgenie:
+		return "Opsgenie"
+	case models.IntegrationSMS:
+		return "SMS"
+	}
+	if ch, ok := pushChannels.Get(kind); ok {
+		return ch.Title()
+	}
+	return kind
+}
+
+// IntegrationName returns the kind and the name of the integration with the id, like Slack: #ops
+func IntegrationName(id *int64, integrations []*models.Integration) string {
+	if id == nil {
+		return ""
+	}
+	for _, integration := range integrations {
+		if integration.ID == *id {
+			return IntegrationTitle(integration.Kind) + ": " + integration.Name
+		}
+	}
+	return ""
+}
+
+// integrationEnabled tells if the user turned on the alerts of the integration kind
+func integrationEnabled(notification *models.Notification, kind string) bool {
+	switch kind {
+	case models.IntegrationSlack:
+		return notification.SlackAlert
+	case models.IntegrationDiscord:
+		return notification.DiscordAlert
+	case models.IntegrationTeams:
+		return notification.MicrosoftTeamsAlert
+	case models.IntegrationWebhook:
+		return notification.WebhookAlert
+	case models.IntegrationPagerDuty:
+		return notification.PagerDutyAlert
+	case models.IntegrationOpsgenie:
+		return notification.OpsgenieAlert
+	case models.IntegrationNtfy:
+		return notification.NtfyAlert
+	case models.IntegrationGotify:
+		return notification.GotifyAlert
+	case models.IntegrationPushover:
+		return notification.PushoverAlert
+	case models.IntegrationMatrix:
+		return notification.MatrixAlert
+	case models.IntegrationSMS:
+		return notification.SMSAlert
+	}
+	return false
+}
+
+// ValidateIntegration checks the config of a new integration before it is saved
+func ValidateIntegration(integration *models.Integration) error {
+	switch integration.Kind {
+	case models.IntegrationSlack:
+		if !strings.HasPrefix(integration.Config.WebhookURL, slack.WebhookPrefix) {
+			return fmt.Errorf("a slack webhook url starts with %s", slack.WebhookPrefix)
+		}
+	case models.IntegrationDiscord:
+		if !discord.IsWebhookURL(integration.Config.WebhookURL) {
+			return fmt.Errorf("a discord webhook url starts with %s", discord.WebhookPrefixes[0])
+		}
+	case models.IntegrationTeams:
+		if !teams.IsWebhookURL(integration.Config.WebhookURL) {
+			return errors.New("paste the url of a teams incoming webhook or of a Workflow that posts to a channel")
+		}
+	case models.IntegrationWebhook:
+		if err := webhook.ValidateURL(integration.Config.WebhookURL); err != nil {
+			return err
+		}
+		if integration.Config.Secret == "" {
+			return errors.New("webhook has no signing secret")
+		}
+	case models.IntegrationPagerDuty:
+		if !pagerduty.IsRoutingKey(integration.Config.RoutingKey) {
+			return errors.New("paste the 32 character integration key of an Events API v2 integration of the service")
+		}
+	case models.IntegrationOpsgenie:
+		if integration.Config.APIKey == "" {
+			return errors.New("paste the api key of an API integration of the team")
+		}
+		if !opsgenie.IsRegion(integration.Config.Region) {
+			return errors.New("pick the region of the opsgenie account")
+		}
+	case models.IntegrationSMS:
+		phone, err := sms.NormalizePhone(integration.Config.Phone)
+		if err != nil {
+			return err
+		}
+		integration.Config.Phone = phone
+	default:
+		if ch, ok := pushChannels.Get(integration.Kind); ok {
+			return validatePushIntegration(ch, integration)
+		}
+		return fmt.Errorf("unknown integration %s", integration.Kind)
+	}
+	return nil
+}
+
+// SendIntegrationTest sends a test message to the integration, so the user sees it is connected right
+func SendIntegrationTest(ctx context.Context, cfg *config.Config, integration *models.Integration) error {
+	switch integration.Kind {
+	case models.IntegrationSlack:
+		return slackClient.PostWebhook(ctx, integration.Config.WebhookURL, slackTestMessage(cfg))
+	case models.IntegrationDiscord:
+		return discordClient.PostWebhook(ctx, integration.Config.WebhookURL, discordTestMessage(cfg))
+	case models.IntegrationTeams:
+		return teamsClient.PostWebhook(ctx, integration.Config.WebhookURL, teamsTestMessage(cfg))
+	case models.IntegrationWebhook:
+		return sendWebhookTest(ctx, cfg, integration)
+	case models.IntegrationPagerDuty, models.IntegrationOpsgenie:
+		return sendPagingTest(ctx, cfg, integration)
+	}
+	if ch, ok := pushChannelsFor(cfg).Get(integration.Kind); ok {
+		return sendPushTest(ctx, cfg, ch, integration)
+	}
+	return fmt.Errorf("unknown integration %s", integration.Kind)
+}
+
+// alertIntegration returns the connection the alert goes to
+func (args *UpdateDomainRegArgs) alertIntegration(ctx context.Context, alert *models.Alert) (*models.Integration, error) {
+	if alert.IntegrationID == nil {
+		return nil, errors.New("alert is not addressed to an integration")
+	}
+	integration, err := args.Strg.Integrations().GetIntegrationByID(ctx, alert.UserID, *alert.IntegrationID)
+	if err != nil {
+		return nil, err
+	}
+	if integration.Kind != alert.Channel {
+		return nil, fmt.Errorf("integration %d is %s, not %s", integration.ID, integration.Kind, alert.Channel)
+	}
+	return integration, nil
+}
+
+// alertDomain returns the tracked domain of the alert as it is now, for the details of the message, nil if it is gone
+func (args *UpdateDomainRegArgs) alertDomain(ctx context.Context, alert *models.Alert) *ssl.DomainTracking {
+	domain, err := args.Strg.Domain().GetDomainWithUserIDAndDomainName(ctx, &ssl.DomainTracking{
+		DomainName: alert.Domain,
+		UserID:     alert.UserID,
+	})
+	if err != nil {
+		args.Log.Errorf("Failed to get domain %s of alert %d: %s", alert.Domain, alert.ID, err)
+		return nil
+	}
+	return domain
+}
+
+// domainLink returns the page of the domain, or the list of domains if the domain is not tracked anymore
+func domainLink(cfg *config.Config, domain *ssl.DomainTracking) string {
+	if domain == nil {
+		return cfg.BaseUrl + "/domains"
+	}
+	return fmt.Sprintf("%s/domains/more/%d", cfg.BaseUrl, domain.ID)
+}
+
+// alertStatus returns the status of the domain the alert is about, the colour of chat messages follows it
+func alertStatus(alert *models.Alert, domain *ssl.DomainTracking) string {
+	switch alert.Type {
+	case recoveryAlertStr:
+		return ssl.StatusHealthy
+	case expiredAlertStr:
+		return ssl.StatusExpired
+	case invalidAlertStr:
+		return ssl.StatusInvalid
+	case revokedAlertStr:
+		return ssl.StatusRevoked
+	case expiryAlertStr, renewalAlertStr, overdueAlertStr:
+		return ssl.StatusExpires
+	}
+	if alert.Payload.Status != nil {
+		return *alert.Payload.Status
+	}
+	if domain != nil && domain.Status != nil {
+		return *domain.Status
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/pkg/cron"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// MaxMaintenanceDuration is how long a recurring maintenance window may last
+const MaxMaintenanceDuration = 7 * 24 * time.Hour
+
+// ValidateMaintenanceWindow checks that the window is either one-off or recurring, and that its timezone and cron expression can be read
+func ValidateMaintenanceWindow(window *models.MaintenanceWindow) error {
+	if _, err := time.LoadLocation(window.Timezone); err != nil {
+		return errors.New("unknown timezone " + window.Timezone)
+	}
+	if len(window.Domains) == 0 && len(window.Tags) == 0 {
+		return errors.New("a maintenance window needs domains or tags")
+	}
+
+	if window.Cron == nil {
+		if window.StartsAt == nil || window.EndsAt == nil {
+			return errors.New("a one-off maintenance window needs a start and an end")
+		}
+		if !window.EndsAt.After(*window.StartsAt) {
+			return errors.New("a maintenance window must end after it starts")
+		}
+		return nil
+	}
+
+	if _, err := cron.Parse(*window.Cron); err != nil {
+		return err
+	}
+	if window.Duration == nil || *window.Duration < 1 || time.Duration(*window.Duration)*time.Minute > MaxMaintenanceDuration {
+		return errors.New("a recurring maintenance window lasts from 1 minute to 7 days")
+	}
+	return nil
+}
+
+// isMaintenanceActive reports whether the window is going on at now. A recurring window is going on
+// when one of its starts, read in the window's timezone, falls within the last Duration minutes.
+func isMaintenanceActive(window *models.MaintenanceWindow, now time.Time) bool {
+	if window.Cron == nil {
+		if window.StartsAt == nil || window.EndsAt == nil {
+			return false
+		}
+		return !now.Before(*window.StartsAt) && now.Before(*window.EndsAt)
+	}
+
+	schedule, err := cron.Parse(*window.Cron)
+	if err != nil || window.Duration == nil {
+		return false
+	}
+	loc, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		return false
+	}
+	minute := now.In(loc).Truncate(time.Minute)
+	for i := 0; i < *window.Duration; i++ {
+		if schedule.Matches(minute.Add(-time.Duration(i) * time.Minute)) {
+			return true
+		}
+	}
+	return false
+}
+
+// appliesTo reports whether the window covers the domain by its name or by one of its tags
+func appliesTo(window *models.MaintenanceWindow, domain *ssl.DomainTracking) bool {
+	for _, name := range window.Domains {
+		if name == domain.DomainName {
+			return true
+		}
+	}
+	for _, tag := range window.Tags {
+		for _, domainTag := range domain.Tags {
+			if tag == domainTag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// suppressedBy returns why the alerts about the domain are not delivered at now: its snooze or the active maintenance window
+// that covers it. nil means they are delivered.
+func suppressedBy(windows []*models.MaintenanceWindow, domain *ssl.DomainTracking, now time.Time) *string {
+	if domain.SnoozedUntil != nil && now.Before(*domain.SnoozedUntil) {
+		reason := "snoozed until " + domain.SnoozedUntil.Format("2006-01-02 15:04 MST")
+		return &reason
+	}
+	for _, window := range windows {
+		if appliesTo(window, domain) && isMaintenanceActive(window, now) {
+			reason := "maintenance window " + window.Name
+			return &reason
+		}
+	}
+	return nil
+}
+
+// domainSuppression loads what suppressedBy needs for the user's domain. The alerts are delivered when it can't be loaded,
+// a missed alert is worse than one sent during maintenance.
+func (args *UpdateDomainRegArgs) domainSuppression(ctx context.Context, userID int64, domain *ssl.DomainTracking, now time.Time) *string {
+	windows, err := args.Strg.Maintenance().GetMaintenanceWindowsByUserID(ctx, userID)
+	if err != nil {
+		args.Log.Errorf("Failed to get maintenance windows of user %d: %s", userID, err)
+		return nil
+	}
+	return suppressedBy(windows, domain, now)
+}
+
+// suppress records the alerts as suppressed instead of queueing them for delivery
+func suppress(alerts []*models.Alert, reason *string) []*models.Alert {
+	if reason == nil {
+		return alerts
+	}
+	for _, alert := range alerts {
+		alert.Status = models.AlertSuppressed
+		alert.SuppressedBy = reason
+	}
+	return alerts
+}
@@ -0,0 +1,301 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/opsgenie"
+	"github.com/SaidovZohid/certalert.info/pkg/pagerduty"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+const (
+	pagingSource = "CertAlert"
+	// opsgenie cuts the message of an alert at 130 characters, pagerduty the summary at 1024
+	maxOpsgenieMessage  = 130
+	maxPagerDutySummary = 1024
+)
+
+var (
+	pagerDutyClient = pagerduty.NewClient()
+	opsgenieClient  = opsgenie.NewClient()
+)
+
+// DefaultSeverities are the severities of pagerduty and the priorities of opsgenie the alerts are sent with,
+// unless the user maps them otherwise
+var DefaultSeverities = map[string]map[string]string{
+	models.IntegrationPagerDuty: {
+		models.SeverityCritical: "critical",
+		models.SeverityError:    "error",
+		models.SeverityWarning:  "warning",
+		models.SeverityInfo:     "info",
+	},
+	models.IntegrationOpsgenie: {
+		models.SeverityCritical: "P1",
+		models.SeverityError:    "P2",
+		models.SeverityWarning:  "P3",
+		models.SeverityInfo:     "P5",
+	},
+}
+
+// IntegrationSeverityLevels returns the severities the incident tool of the kind has, nil if it has none
+func IntegrationSeverityLevels(kind string) []string {
+	switch kind {
+	case models.IntegrationPagerDuty:
+		return pagerduty.Severities
+	case models.IntegrationOpsgenie:
+		return opsgenie.Priorities
+	}
+	return nil
+}
+
+// IntegrationSeverity returns the severity of the incident tool the alert severity is sent with
+func IntegrationSeverity(integration *models.Integration, severity string) string {
+	if mapped, ok := integration.Config.Severities[severity]; ok && ValidSeverityLevel(integration.Kind, mapped) {
+		return mapped
+	}
+	return DefaultSeverities[integration.Kind][severity]
+}
+
+// ValidSeverityLevel tells if the incident tool of the kind has the severity
+func ValidSeverityLevel(kind, level string) bool {
+	for _, l := range IntegrationSeverityLevels(kind) {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// isPagingKind tells if the integration kind is an incident tool, which gets the incidents of the user only
+func isPagingKind(kind string) bool {
+	return kind == models.IntegrationPagerDuty || kind == models.IntegrationOpsgenie
+}
+
+// isPageable tells if the alert type opens, reminds about or resolves an incident. The renewal window and the
+// overdue renewal have nothing that resolves them, so they stay out of the incident tools.
+func isPageable(tp string) bool {
+	switch tp {
+	case expiryAlertStr, expiredAlertStr, invalidAlertStr, revokedAlertStr, offlineAlertStr, changeAlertStr, recoveryAlertStr:
+		return true
+	}
+	return false
+}
+
+// pagingDedupKey is the key of the alert in the incident tool, one per domain and problem: the reminders of a problem
+// are grouped into the alert it opened, and its recovery resolves that alert
+func pagingDedupKey(alert *models.Alert) string {
+	return fmt.Sprintf("certalert:%d:%s:%s", alert.UserID, alert.Domain, pagingProblem(alert))
+}
+
+// pagingProblem returns the problem of the incident the alert is about
+func pagingProblem(alert *models.Alert) string {
+	if alert.Payload.Problem != "" {
+		return alert.Payload.Problem
+	}
+	for problem, tp := range problemAlertTypes {
+		if tp == alert.Type {
+			return problem
+		}
+	}
+	return alert.Type
+}
+
+// addressPagingResolve addresses the recovery of the incident to the incident tools only. It closes the expiring alert
+// of a certificate that expired: the incident is over, but the certificate has not recovered, the expired one takes over.
+func (args *UpdateDomainRegArgs) addressPagingResolve(owner *domainOwner, domain string, payload models.AlertPayload, incident *models.Incident) []*models.Alert {
+	alerts := make([]*models.Alert, 0)
+	escalated := escalatedPagingIntegrations(owner.policies, incident)
+	for _, integration := range owner.integrations {
+		if !isPagingKind(integration.Kind) || !(integrationEnabled(owner.notification, integration.Kind) || escalated[integration.ID]) {
+			continue
+		}
+		alerts = append(alerts, &models.Alert{
+			UserID:        owner.userID,
+			Domain:        domain,
+			Type:          recoveryAlertStr,
+			Channel:       integration.Kind,
+			IntegrationID: &integration.ID,
+			Payload:       payload,
+			Incident:      incident,
+			MaxAttempts:   args.maxAlertAttempts(),
+			NextAttemptAt: time.Now(),
+		})
+	}
+	return suppress(alerts, owner.suppressedBy)
+}
+
+// sendNotificationToPagerDuty triggers the alert of the problem in the pagerduty service, or resolves it on a recovery
+func (args *UpdateDomainRegArgs) sendNotificationToPagerDuty(ctx context.Context, alert *models.Alert) error {
+	integration, err := args.alertIntegration(ctx, alert)
+	if err != nil {
+		return err
+	}
+	return pagerDutyClient.Send(ctx, pagerDutyEvent(args.Cfg, integration, alert, args.alertDomain(ctx, alert)))
+}
+
+// pagerDutyEvent builds the event of the alert: a resolve for a recovery, a trigger with the details of the domain otherwise
+func pagerDutyEvent(cfg *config.Config, integration *models.Integration, alert *models.Alert, domain *ssl.DomainTracking) *pagerduty.Event {
+	event := &pagerduty.Event{
+		RoutingKey: integration.Config.RoutingKey,
+		DedupKey:   pagingDedupKey(alert),
+	}
+	if alert.Type == recoveryAlertStr {
+		event.EventAction = pagerduty.ActionResolve
+		return event
+	}
+
+	link := domainLink(cfg, domain)
+	event.EventAction = pagerduty.ActionTrigger
+	event.Client = pagingSource
+	event.ClientURL = link
+	event.Links = []*pagerduty.Link{{Href: link, Text: "View domain"}}
+	if alert.Payload.AckToken != "" {
+		event.Links = append(event.Links, &pagerduty.Link{Href: cfg.BaseUrl + "/incidents/ack/" + alert.Payload.AckToken, Text: "Acknowledge in CertAlert"})
+	}
+	event.Payload = &pagerduty.Payload{
+		Summary:       truncate(fmt.Sprintf("%s %s", alert.Domain, alertHeadline(alert)), maxPagerDutySummary),
+		Source:        alert.Domain,
+		Severity:      IntegrationSeverity(integration, AlertSeverity(alert)),
+		Component:     "ssl-certificate",
+		Class:         pagingProblem(alert),
+		CustomDetails: pagingDetails(alert, domain),
+	}
+	if !alert.CreatedAt.IsZero() {
+		event.Payload.Timestamp = alert.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if domain != nil && len(domain.Tags) > 0 {
+		event.Payload.Group = domain.Tags[0]
+	}
+	return event
+}
+
+// sendNotificationToOpsgenie creates the alert of the problem in opsgenie, or closes it on a recovery
+func (args *UpdateDomainRegArgs) sendNotificationToOpsgenie(ctx context.Context, alert *models.Alert) error {
+	integration, err := args.alertIntegration(ctx, alert)
+	if err != nil {
+		return err
+	}
+	if alert.Type == recoveryAlertStr {
+		return opsgenieClient.CloseAlert(ctx, integration.Config.Region, integration.Config.APIKey, pagingDedupKey(alert), &opsgenie.Close{
+			Source: pagingSource,
+			Note:   fmt.Sprintf("%s %s.", alert.Domain, alertHeadline(alert)),
+		})
+	}
+	return opsgenieClient.CreateAlert(ctx, integration.Config.Region, integration.Config.APIKey, opsgenieAlert(args.Cfg, integration, alert, args.alertDomain(ctx, alert)))
+}
+
+// opsgenieAlert builds the alert of the problem with the details of the domain
+func opsgenieAlert(cfg *config.Config, integration *models.Integration, alert *models.Alert, domain *ssl.DomainTracking) *opsgenie.Alert {
+	link := domainLink(cfg, domain)
+	description := fmt.Sprintf("%s %s.\n\n%s", alert.Domain, alertHeadline(alert), link)
+	if alert.Payload.AckToken != "" {
+		description += "\nAcknowledge in CertAlert: " + cfg.BaseUrl + "/incidents/ack/" + alert.Payload.AckToken
+	}
+	og := &opsgenie.Alert{
+		Message:     truncate(fmt.Sprintf("%s: %s", AlertTypeTitle(alert.Type), alert.Domain), maxOpsgenieMessage),
+		Alias:       pagingDedupKey(alert),
+		Description: description,
+		Tags:        []string{"certalert", pagingProblem(alert)},
+		Details:     pagingDetails(alert, domain),
+		Entity:      alert.Domain,
+		Source:      pagingSource,
+		Priority:    IntegrationSeverity(integration, AlertSeverity(alert)),
+	}
+	if domain != nil {
+		og.Tags = append(og.Tags, domain.Tags...)
+	}
+	return og
+}
+
+// pagingDetails are the details of the domain shown in the alert of the incident tool
+func pagingDetails(alert *models.Alert, domain *ssl.DomainTracking) map[string]string {
+	details := map[string]string{
+		"domain":   alert.Domain,
+		"alert":    AlertTypeTitle(alert.Type),
+		"severity": AlertSeverity(alert),
+	}
+	if status := alertStatus(alert, domain); status != "" {
+		details["status"] = status
+	}
+	expires := alert.Payload.Expires
+	if expires == nil && domain != nil {
+		expires = domain.Expires
+	}
+	if expires != nil {
+		details["expires"] = expires.UTC().Format(time.RFC3339)
+		details["days_left"] = strconv.Itoa(daysUntilExpiration(*expires))
+	}
+	if domain != nil {
+		if domain.Issuer != nil {
+			details["issuer"] = *domain.Issuer
+		}
+		if domain.DNSNames != nil && *domain.DNSNames != "" {
+			details["sans"] = *domain.DNSNames
+		}
+		if ip := remoteIP(domain.RemoteAddr); ip != "" {
+			details["ip"] = ip
+		}
+	}
+	if alert.Payload.Error != nil {
+		details["error"] = *alert.Payload.Error
+	}
+	if len(alert.Payload.Changes) > 0 {
+		changes := make([]string, 0, len(alert.Payload.Changes))
+		for _, change := range alert.Payload.Changes {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", ssl.FieldTitle(change.Field), change.Old, change.New))
+		}
+		details["changes"] = strings.Join(changes, "\n")
+	}
+	return details
+}
+
+// sendPagingTest opens a test alert in the incident tool and resolves it right away
+func sendPagingTest(ctx context.Context, cfg *config.Config, integration *models.Integration) error {
+	key := "certalert:test:" + GenerateRandomString(8)
+	switch integration.Kind {
+	case models.IntegrationPagerDuty:
+		err := pagerDutyClient.Send(ctx, &pagerduty.Event{
+			RoutingKey:  integration.Config.RoutingKey,
+			EventAction: pagerduty.ActionTrigger,
+			DedupKey:    key,
+			Client:      pagingSource,
+			ClientURL:   cfg.BaseUrl + "/integrations",
+			Payload: &pagerduty.Payload{
+				Summary:  "CertAlert test alert, resolved right away",
+				Source:   "certalert",
+				Severity: IntegrationSeverity(integration, models.SeverityInfo),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return pagerDutyClient.Send(ctx, &pagerduty.Event{
+			RoutingKey:  integration.Config.RoutingKey,
+			EventAction: pagerduty.ActionResolve,
+			DedupKey:    key,
+		})
+	case models.IntegrationOpsgenie:
+		err := opsgenieClient.CreateAlert(ctx, integration.Config.Region, integration.Config.APIKey, &opsgenie.Alert{
+			Message:     "CertAlert test alert",
+			Alias:       key,
+			Description: "This team is connected to CertAlert. The alert is closed right away.",
+			Tags:        []string{"certalert", "test"},
+			Source:      pagingSource,
+			Priority:    IntegrationSeverity(integration, models.SeverityInfo),
+		})
+		if err != nil {
+			return err
+		}
+		return opsgenieClient.CloseAlert(ctx, integration.Config.Region, integration.Config.APIKey, key, &opsgenie.Close{
+			Source: pagingSource,
+			Note:   "Test alert",
+		})
+	}
+	return fmt.Errorf("unknown integration %s", integration.Kind)
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// The polls are capped per IP address and per registrable domain over all the instances, so they do not pile up on
+// one server or one site. Every poll job carries both keys and the lease of the shared queue counts the leased jobs
+// that share them, see PollJobStorageI.LeasePollJob.
+
+// remoteIP returns the IP address of the host:port the domain answered from at the last poll, empty if unknown
+func remoteIP(remoteAddr *string) string {
+	if remoteAddr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(*remoteAddr)
+	if err != nil {
+		return *remoteAddr
+	}
+	return host
+}
+
+// registrableDomain returns the public suffix plus one label of the domain, like example.co.uk for www.example.co.uk
+func registrableDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	site, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return domain
+	}
+	return site
+}
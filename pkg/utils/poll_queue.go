@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+)
+
+const (
+	defaultPollWorkers     = 50
+	defaultPollJobLease    = 2 * time.Minute
+	defaultPollJobAttempts = 3
+	defaultPollJobBackoff  = 30 * time.Second
+	// how long an idle worker waits before asking the queue again
+	pollWorkerIdleWait = 5 * time.Second
+	// failed poll jobs are kept this long for inspection
+	failedPollJobRetention = 7 * 24 * time.Hour
+)
+
+// RunPollWorkers runs the poll workers of this instance until ctx is canceled. Every worker leases one job
+// at a time from the queue shared by all instances, so workers run on every instance, not only the leader.
+// Once ctx is canceled no new jobs are leased, and the jobs in flight get ShutdownTimeout to finish
+// polling and alerting before they are canceled too. A canceled job is taken over when its lease runs out.
+func (args *UpdateDomainRegArgs) RunPollWorkers(ctx context.Context) {
+	workers := args.Cfg.Scheduler.Workers
+	if workers <= 0 {
+		workers = defaultPollWorkers
+	}
+
+	instanceID := newInstanceID()
+	args.Log.Info("Starting poll workers -> ", workers, " instance -> ", instanceID)
+
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-stopped:
+			return
+		case <-ctx.Done():
+		}
+		args.Log.Info("Poll workers are finishing the jobs in flight")
+		select {
+		case <-stopped:
+		case <-time.After(args.Cfg.ShutdownTimeout):
+			args.Log.Info("Shutdown timeout passed, canceling the poll jobs in flight")
+			cancelJobs()
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(workerID string) {
+			defer wg.Done()
+			args.runPollWorker(ctx, jobCtx, workerID)
+		}(fmt.Sprintf("%s-%d", instanceID, i))
+	}
+	wg.Wait()
+	close(stopped)
+
+	args.Log.Info("Poll workers stopped")
+}
+
+// runPollWorker leases and processes poll jobs until ctx is canceled. The jobs run with jobCtx.
+func (args *UpdateDomainRegArgs) runPollWorker(ctx, jobCtx context.Context, workerID string) {
+	for ctx.Err() == nil {
+		job, err := args.Strg.PollJobs().LeasePollJob(ctx, workerID, args.pollJobLease(), args.Cfg.Scheduler.MaxPerIP, args.Cfg.Scheduler.MaxPerDomain)
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				args.Log.Errorf("Failed to lease poll job: %s", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollWorkerIdleWait):
+			}
+			continue
+		}
+
+		domain, err := args.Strg.Domain().GetDomainInfoByName(jobCtx, job.Domain)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// nobody tracks the domain anymore
+			if err := args.Strg.PollJobs().CompletePollJob(jobCtx, job.ID, workerID); err != nil {
+				args.Log.Errorf("Failed to complete poll job of %s: %s", job.Domain, err)
+			}
+			continue
+		}
+		if err != nil {
+			args.Log.Errorf("Failed to get %s from storage: %s", job.Domain, err)
+			args.retryPollJob(jobCtx, job.ID, workerID, job.Attempts, job.MaxAttempts, err)
+			continue
+		}
+
+		info, err := args.pollDomain(jobCtx, domain)
+		if err != nil {
+			args.Log.Errorf("Failed to poll %s, attempt %d of %d: %s", job.Domain, job.Attempts, job.MaxAttempts, err)
+			args.retryPollJob(jobCtx, job.ID, workerID, job.Attempts, job.MaxAttempts, err)
+			continue
+		}
+
+		outcome, err := args.filterDomainsOwnersNotif(jobCtx, info)
+		if err == nil {
+			err = args.Strg.Alerts().SavePollResultWithAlerts(jobCtx, &ssl.DomainTracking{
+				DomainName:         info.DomainName,
+				TrackingDomainInfo: *info.Current,
+			}, outcome.incidents, outcome.reminders, outcome.alerts)
+		}
+		if err != nil {
+			args.Log.Errorf("Failed to save poll result of %s: %s", job.Domain, err)
+			args.retryPollJob(jobCtx, job.ID, workerID, job.Attempts, job.MaxAttempts, err)
+			continue
+		}
+
+		if err := args.Strg.PollJobs().CompletePollJob(jobCtx, job.ID, workerID); err != nil {
+			args.Log.Errorf("Failed to complete poll job of %s: %s", job.Domain, err)
+		}
+	}
+}
+
+// retryPollJob puts the job back in the queue with a backoff that grows with the attempts, or marks it failed when none are left
+func (args *UpdateDomainRegArgs) retryPollJob(ctx context.Context, id int64, workerID string, attempts, maxAttempts int, pollErr error) {
+	if attempts >= maxAttempts {
+		if err := args.Strg.PollJobs().FailPollJob(ctx, id, workerID, pollErr.Error()); err != nil {
+			args.Log.Errorf("Failed to mark poll job %d failed: %s", id, err)
+		}
+		return
+	}
+
+	backoff := args.Cfg.Scheduler.JobRetryBackoff
+	if backoff <= 0 {
+		backoff = defaultPollJobBackoff
+	}
+	runAt := time.Now().Add(backoff * time.Duration(attempts*attempts))
+
+	if err := args.Strg.PollJobs().RetryPollJob(ctx, id, workerID, runAt, pollErr.Error()); err != nil {
+		args.Log.Errorf("Failed to retry poll job %d: %s", id, err)
+	}
+}
+
+// cleanupPollJobs fails the jobs abandoned by crashed workers with no attempts left, and deletes old failed jobs
+func (args *UpdateDomainRegArgs) cleanupPollJobs(ctx context.Context) {
+	failed, err := args.Strg.PollJobs().FailExhaustedPollJobs(ctx)
+	if err != nil {
+		args.Log.Errorf("Failed to fail exhausted poll jobs: %s", err)
+	} else if failed > 0 {
+		args.Log.Info("Marked abandoned poll jobs failed -> ", failed)
+	}
+
+	deleted, err := args.Strg.PollJobs().DeleteFailedPollJobsBefore(ctx, time.Now().Add(-failedPollJobRetention))
+	if err != nil {
+		args.Log.Errorf("Failed to delete old failed poll jobs: %s", err)
+	} else if deleted > 0 {
+		args.Log.Info("Deleted old failed poll jobs -> ", deleted)
+	}
+}
+
+func (args *UpdateDomainRegArgs) pollJobLease() time.Duration {
+	if args.Cfg.Scheduler.JobLease <= 0 {
+		return defaultPollJobLease
+	}
+	return args.Cfg.Scheduler.JobLease
+}
+
+func (args *UpdateDomainRegArgs) maxPollAttempts() int {
+	if args.Cfg.Scheduler.JobMaxAttempts <= 0 {
+		return defaultPollJobAttempts
+	}
+	return args.Cfg.Scheduler.JobMaxAttempts
+}
+
+// jitter returns a random delay up to fraction of interval, so domains that fall due together are not polled together
+func jitter(interval time.Duration, fraction float64) time.Duration {
+	max := time.Duration(float64(interval) * fraction)
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// newInstanceID names this instance in the leases it takes, so a lease can be traced back to the host holding it
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "certalert"
+	}
+	suffix, err := GenerateRandomCode(6)
+	if err != nil {
+		return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), suffix)
+}
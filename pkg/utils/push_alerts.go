@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/channels"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/pkg/webhook"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// the servers of ntfy, gotify and matrix are often self-hosted, they are reached like the webhooks of the users
+var (
+	pushChannels        = channels.NewRegistry(webhook.NewHTTPClient(false))
+	privatePushChannels = channels.NewRegistry(webhook.NewHTTPClient(true))
+)
+
+// channelMessageData is what the templates of a push channel are executed with
+type channelMessageData struct {
+	Domain   string
+	Alert    string // the title of the alert type, like Expiry
+	Event    string // the type of the event, like expiring, the same as the webhooks get
+	Headline string // what happened, like "has an expired SSL certificate"
+	Severity string
+	Status   string
+	Expires  string
+	DaysLeft int
+	Issuer   string
+	IP       string
+	Error    string
+	Changes  []string // like "Issuer: old → new"
+	Link     string   // the page of the domain
+	AckLink  string   // acknowledges the incident, empty if the alert has no incident
+}
+
+// PushChannelKinds returns the kinds of the push channels, in the order the integrations page shows them
+func PushChannelKinds() []string {
+	return pushChannels.Kinds()
+}
+
+// isPushKind tells if the integration kind is a push channel
+func isPushKind(kind string) bool {
+	_, ok := pushChannels.Get(kind)
+	return ok
+}
+
+func pushChannelsFor(cfg *config.Config) *channels.Registry {
+	if cfg.Webhooks.AllowPrivateNetworks {
+		return privatePushChannels
+	}
+	return pushChannels
+}
+
+// channelConfig returns the settings of the connection the channel reads
+func channelConfig(integration *models.Integration) *channels.Config {
+	return &channels.Config{
+		ServerURL: integration.Config.ServerURL,
+		Topic:     integration.Config.Topic,
+		Token:     integration.Config.Token,
+		UserKey:   integration.Config.UserKey,
+		RoomID:    integration.Config.RoomID,
+	}
+}
+
+// validatePushIntegration checks the settings and the templates of a new push connection, keeping the defaults the channel filled in
+func validatePushIntegration(ch channels.Channel, integration *models.Integration) error {
+	cfg := channelConfig(integration)
+	if err := ch.Validate(cfg); err != nil {
+		return err
+	}
+	integration.Config.ServerURL = cfg.ServerURL
+	integration.Config.RoomID = cfg.RoomID
+	return ValidateChannelTemplates(integration.Config.TitleTemplate, integration.Config.MessageTemplate)
+}
+
+// ChannelTemplates returns the templates of the push connection, the defaults of its channel for the ones not set
+func ChannelTemplates(integration *models.Integration) (string, string) {
+	ch, ok := pushChannels.Get(integration.Kind)
+	if !ok {
+		return "", ""
+	}
+	title, body := ch.DefaultTemplates()
+	if integration.Config.TitleTemplate != "" {
+		title = integration.Config.TitleTemplate
+	}
+	if integration.Config.MessageTemplate != "" {
+		body = integration.Config.MessageTemplate
+	}
+	return title, body
+}
+
+// ValidateChannelTemplates checks the templates parse and execute with a sample alert
+func ValidateChannelTemplates(title, body string) error {
+	if _, err := executeChannelTemplate(title, sampleMessageData()); err != nil {
+		return fmt.Errorf("title template: %w", err)
+	}
+	if _, err := executeChannelTemplate(body, sampleMessageData()); err != nil {
+		return fmt.Errorf("message template: %w", err)
+	}
+	return nil
+}
+
+func executeChannelTemplate(text string, data *channelMessageData) (string, error) {
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// renderChannelMessage renders the message of the connection. A template that fails, like one saved before a field
+// it uses was renamed, falls back to the default of the channel, so the alert is not lost.
+func renderChannelMessage(ch channels.Channel, integration *models.Integration, data *channelMessageData) (*channels.Message, error) {
+	defaultTitle, defaultBody := ch.DefaultTemplates()
+	titleTmpl, bodyTmpl := ChannelTemplates(integration)
+
+	title, err := executeChannelTemplate(titleTmpl, data)
+	if err != nil {
+		if title, err = executeChannelTemplate(defaultTitle, data); err != nil {
+			return nil, err
+		}
+	}
+	body, err := executeChannelTemplate(bodyTmpl, data)
+	if err != nil {
+		if body, err = executeChannelTemplate(defaultBody, data); err != nil {
+			return nil, err
+		}
+	}
+	return &channels.Message{
+		Title:    title,
+		Body:     body,
+		Severity: data.Severity,
+		URL:      data.Link,
+	}, nil
+}
+
+// alertMessageData returns what the templates show about the alert, with the domain as it is now
+func alertMessageData(cfg *config.Config, alert *models.Alert, domain *ssl.DomainTracking) *channelMessageData {
+	data := &channelMessageData{
+		Domain:   alert.Domain,
+		Alert:    AlertTypeTitle(alert.Type),
+		Event:    WebhookEventType(alert.Type),
+		Headline: alertHeadline(alert),
+		Severity: AlertSeverity(alert),
+		Status:   alertStatus(alert, domain),
+		Link:     domainLink(cfg, domain),
+	}
+	if alert.Payload.AckToken != "" && alert.Type != recoveryAlertStr {
+		data.AckLink = cfg.BaseUrl + "/incidents/ack/" + alert.Payload.AckToken
+	}
+	expires := alert.Payload.Expires
+	if expires == nil && domain != nil {
+		expires = domain.Expires
+	}
+	if expires != nil {
+		data.Expires = expires.UTC().Format("02 Jan 2006 15:04 MST")
+		data.DaysLeft = daysUntilExpiration(*expires)
+	}
+	if domain != nil {
+		if domain.Issuer != nil {
+			data.Issuer = *domain.Issuer
+		}
+		data.IP = remoteIP(domain.RemoteAddr)
+	}
+	if alert.Payload.Error != nil {
+		data.Error = *alert.Payload.Error
+	}
+	for _, change := range alert.Payload.Changes {
+		data.Changes = append(data.Changes, fmt.Sprintf("%s: %s → %s", ssl.FieldTitle(change.Field), change.Old, change.New))
+	}
+	return data
+}
+
+// sampleMessageData is the alert the templates are checked and tested with
+func sampleMessageData() *channelMessageData {
+	expires := time.Now().Add(7 * 24 * time.Hour)
+	return &channelMessageData{
+		Domain:   "example.com",
+		Alert:    AlertTypeTitle(expiryAlertStr),
+		Event:    WebhookEventType(expiryAlertStr),
+		Headline: "has an upcoming SSL expiration, only 7 days left",
+		Severity: models.SeverityWarning,
+		Status:   ssl.StatusExpires,
+		Expires:  expires.UTC().Format("02 Jan 2006 15:04 MST"),
+		DaysLeft: 7,
+		Issuer:   "Let's Encrypt",
+		IP:       "93.184.216.34",
+		Changes:  []string{"Issuer: R3 → R10"},
+		Link:     "https://example.com",
+	}
+}
+
+// sendNotificationToPushChannel renders the templates of the connection the alert is addressed to and pushes the message
+func (args *UpdateDomainRegArgs) sendNotificationToPushChannel(ctx context.Context, alert *models.Alert) error {
+	ch, ok := pushChannelsFor(args.Cfg).Get(alert.Channel)
+	if !ok {
+		return fmt.Errorf("unknown channel %v", alert.Channel)
+	}
+	integration, err := args.alertIntegration(ctx, alert)
+	if err != nil {
+		return err
+	}
+	msg, err := renderChannelMessage(ch, integration, alertMessageData(args.Cfg, alert, args.alertDomain(ctx, alert)))
+	if err != nil {
+		return err
+	}
+	msg.ID = "certalert-alert-" + strconv.FormatInt(alert.ID, 10)
+	return ch.Send(ctx, channelConfig(integration), msg)
+}
+
+// sendPushTest pushes the sample alert rendered with the templates of the connection, so the user sees how alerts look
+func sendPushTest(ctx context.Context, cfg *config.Config, ch channels.Channel, integration *models.Integration) error {
+	data := sampleMessageData()
+	data.Link = cfg.BaseUrl + "/domains"
+	msg, err := renderChannelMessage(ch, integration, data)
+	if err != nil {
+		return err
+	}
+	msg.ID = "certalert-test-" + GenerateRandomString(16)
+	msg.Title = "[Test] " + msg.Title
+	return ch.Send(ctx, channelConfig(integration), msg)
+}
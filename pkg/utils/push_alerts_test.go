@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestValidateChannelTemplates(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		body  string
+		valid bool
+	}{
+		{"plain text", "Alert", "Check your domain", true},
+		{"fields", "{{.Alert}}: {{.Domain}}", "{{.Headline}}{{range .Changes}}\n- {{.}}{{end}}", true},
+		{"empty", "", "", true},
+		{"bad title syntax", "{{.Alert", "body", false},
+		{"unknown title field", "{{.Nope}}", "body", false},
+		{"unknown body field", "title", "{{.Domain.Name}}", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateChannelTemplates(tt.title, tt.body)
+			if (err == nil) != tt.valid {
+				t.Errorf("ValidateChannelTemplates(%q, %q) = %v, want valid %v", tt.title, tt.body, err, tt.valid)
+			}
+		})
+	}
+
+	for _, kind := range PushChannelKinds() {
+		ch, _ := pushChannels.Get(kind)
+		title, body := ch.DefaultTemplates()
+		if err := ValidateChannelTemplates(title, body); err != nil {
+			t.Errorf("default templates of %s: %v", kind, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// urgentAlertTypes go out during quiet hours too: visitors of the domain are turned away by their browsers right now.
+// That is the case for an expired or a revoked certificate, and for an invalid one as well, like a certificate of another
+// name or of an untrusted issuer. A revoked certificate still passes verification, the poll finds it through OCSP.
+var urgentAlertTypes = map[string]bool{
+	expiredAlertStr: true,
+	invalidAlertStr: true,
+	revokedAlertStr: true,
+}
+
+// ParseClock reads the 15:04 time of day as minutes after midnight
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.New("time of day must look like 22:00")
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// FormatClock writes the minutes after midnight as a 15:04 time of day
+func FormatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// notificationLocation returns the timezone the user's alerts are timed in, UTC when it can't be loaded
+func notificationLocation(notification *models.Notification) *time.Location {
+	loc, err := time.LoadLocation(notification.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// quietHoursEnd returns when the quiet hours the user is in at now end. ok is false outside of quiet hours.
+// Quiet hours may run over midnight, e.g. from 22:00 to 07:00.
+func quietHoursEnd(notification *models.Notification, now time.Time) (end time.Time, ok bool) {
+	if notification.QuietHoursStart == nil || notification.QuietHoursEnd == nil {
+		return time.Time{}, false
+	}
+	start, stop := *notification.QuietHoursStart, *notification.QuietHoursEnd
+	if start == stop {
+		return time.Time{}, false
+	}
+
+	local := now.In(notificationLocation(notification))
+	minute := local.Hour()*60 + local.Minute()
+	quiet := start <= minute && minute < stop
+	if start > stop {
+		quiet = minute >= start || minute < stop
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	end = time.Date(local.Year(), local.Month(), local.Day(), stop/60, stop%60, 0, 0, local.Location())
+	if !end.After(local) {
+		end = time.Date(local.Year(), local.Month(), local.Day()+1, stop/60, stop%60, 0, 0, local.Location())
+	}
+	return end, true
+}
+
+// quietIntegration tells if the quiet hours hold back the alerts of the integration kind. The incident tools have
+// on-call schedules of their own and must get the recovery that resolves a page right away, and webhooks feed
+// systems rather than people, so only the integrations people read wait.
+func quietIntegration(kind string) bool {
+	return !isPagingKind(kind) && kind != models.IntegrationWebhook
+}
+
+// alertDeliveryTime returns when the alert of the type goes out. Alerts that are not urgent wait for the end of
+// the user's quiet hours, so the ones raised during the night go out together in the morning.
+func alertDeliveryTime(notification *models.Notification, tp string, now time.Time) time.Time {
+	if urgentAlertTypes[tp] {
+		return now
+	}
+	if end, ok := quietHoursEnd(notification, now); ok {
+		// kept in a timestamp without timezone, like the other times of the outbox
+		return end.UTC()
+	}
+	return now
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// ReminderDayOptions are the days before expiry a reminder schedule can be made of
+var ReminderDayOptions = []int{60, 30, 20, 14, 7, 3, 1}
+
+// ValidReminderDays keeps the days that are reminder day options, longest first
+func ValidReminderDays(days []int) []int {
+	result := make([]int, 0, len(days))
+	for _, option := range ReminderDayOptions {
+		for _, day := range days {
+			if day == option {
+				result = append(result, option)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// reminderSchedule returns the days before expiry the user is reminded at about the domain, longest first.
+// The domain's own schedule wins over the user's.
+func reminderSchedule(notification *models.Notification, domain *ssl.DomainTracking) []int {
+	days := notification.ReminderDays
+	if domain.ReminderDays != nil {
+		days = domain.ReminderDays
+	}
+
+	schedule := append([]int(nil), days...)
+	sort.Sort(sort.Reverse(sort.IntSlice(schedule)))
+	return schedule
+}
+
+// dueExpiryReminder returns the reminder the certificate expiring at expires is due for at now: the shortest
+// threshold of the schedule it is already inside of, and once it expired, one reminder for every day after expiry.
+// ok is false while the certificate is outside of the schedule.
+func dueExpiryReminder(expires time.Time, schedule []int, now time.Time) (kind string, days int, ok bool) {
+	left := expires.Sub(now)
+	if left <= 0 {
+		return models.ReminderExpired, int(-left / (24 * time.Hour)), true
+	}
+
+	for i := len(schedule) - 1; i >= 0; i-- {
+		if left <= time.Duration(schedule[i])*24*time.Hour {
+			return models.ReminderExpiry, schedule[i], true
+		}
+	}
+	return "", 0, false
+}
+
+// certificateReminders keeps which reminders a user already got about the certificate the domain serves
+type certificateReminders struct {
+	userID int64
+	domain string
+	serial string
+	sent   map[string]bool
+}
+
+func reminderKey(kind string, days int) string {
+	return fmt.Sprintf("%s:%d", kind, days)
+}
+
+// loadCertificateReminders loads the reminders the user got about the certificate the poll found
+func (args *UpdateDomainRegArgs) loadCertificateReminders(ctx context.Context, userID int64, domainPrInfo *DomainNowAndPreviousInfo) (*certificateReminders, error) {
+	reminders := &certificateReminders{
+		userID: userID,
+		domain: domainPrInfo.DomainName,
+		sent:   make(map[string]bool),
+	}
+	if domainPrInfo.Certificate == nil {
+		return reminders, nil
+	}
+	reminders.serial = certificateSerial(domainPrInfo.Certificate)
+
+	sent, err := args.Strg.Reminders().GetSentReminders(ctx, userID, reminders.domain, reminders.serial)
+	if err != nil {
+		return nil, err
+	}
+	for _, reminder := range sent {
+		reminders.sent[reminderKey(reminder.Kind, reminder.Days)] = true
+	}
+	return reminders, nil
+}
+
+// take returns the record that marks the reminder as sent, ok is false if the user already got it about the certificate
+func (r *certificateReminders) take(kind string, days int, now time.Time) (reminder *models.SentReminder, ok bool) {
+	key := reminderKey(kind, days)
+	if r.sent[key] {
+		return nil, false
+	}
+	r.sent[key] = true
+
+	return &models.SentReminder{
+		UserID: r.userID,
+		Domain: r.domain,
+		Serial: r.serial,
+		Kind:   kind,
+		Days:   days,
+		SentAt: now,
+	}, true
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// RoutableAlertTypes are the alert types a routing rule can match, in the order the routing page shows them.
+// Escalations go to the recipients of their step and the events of added and removed domains to the webhooks, no rule routes them.
+var RoutableAlertTypes = []string{
+	expiryAlertStr,
+	expiredAlertStr,
+	invalidAlertStr,
+	revokedAlertStr,
+	offlineAlertStr,
+	changeAlertStr,
+	recoveryAlertStr,
+	renewalAlertStr,
+	overdueAlertStr,
+}
+
+// RoutingChannels are the channels a routing rule can send to besides the integrations
+var RoutingChannels = []string{models.ChannelEmail, models.ChannelTelegram}
+
+// ParseDomainPatterns splits the comma separated domain patterns of a routing rule, like *.example.com
+func ParseDomainPatterns(value string) ([]string, error) {
+	patterns := make([]string, 0)
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil || strings.ContainsAny(pattern, " /") {
+			return nil, fmt.Errorf("%s is not a domain pattern, use * for any part of the name, like *.example.com", pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// ValidateRoutingRule checks the conditions of the rule are known and that it sends the alerts it matches somewhere,
+// to the channels and to the integrations of its user
+func ValidateRoutingRule(rule *models.RoutingRule, integrations []*models.Integration) error {
+	if rule.Name == "" {
+		return errors.New("a routing rule needs a name")
+	}
+	for _, tp := range rule.AlertTypes {
+		if !containsString(RoutableAlertTypes, tp) {
+			return fmt.Errorf("unknown alert type %s", tp)
+		}
+	}
+	for _, severity := range rule.Severities {
+		if !containsString(models.Severities, severity) {
+			return fmt.Errorf("unknown severity %s", severity)
+		}
+	}
+	for _, channel := range rule.Channels {
+		if !containsString(RoutingChannels, channel) {
+			return fmt.Errorf("unknown channel %s", channel)
+		}
+	}
+	for _, id := range rule.IntegrationIDs {
+		found := false
+		for _, integration := range integrations {
+			if integration.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("integration of the rule is not found")
+		}
+	}
+	if len(rule.Channels) == 0 && len(rule.IntegrationIDs) == 0 {
+		return errors.New("pick the channels or the integrations the alerts of the rule go to")
+	}
+	return nil
+}
+
+// matchRoutingRule returns the first rule that matches the alert, nil when no rule does and the alert goes everywhere.
+// A recovery matches like the alert of the problem it ends too, so it reaches the tools that were alerted and they resolve it.
+// A rule left without destinations, once its integrations are disconnected, is skipped rather than losing the alerts it matches.
+func matchRoutingRule(rules []*models.RoutingRule, domain string, tags []string, tp string, payload models.AlertPayload) *models.RoutingRule {
+	types := []string{tp}
+	severities := []string{AlertSeverity(&models.Alert{Type: tp, Payload: payload})}
+	if problemType, ok := problemAlertTypes[payload.Problem]; ok && tp == recoveryAlertStr {
+		types = append(types, problemType)
+		severities = append(severities, AlertSeverity(&models.Alert{Type: problemType, Payload: payload}))
+	}
+
+	for _, rule := range rules {
+		if len(rule.Channels) == 0 && len(rule.IntegrationIDs) == 0 {
+			continue
+		}
+		if len(rule.Domains) > 0 && !matchesDomainPattern(rule.Domains, domain) {
+			continue
+		}
+		if len(rule.Tags) > 0 && !containsAny(rule.Tags, tags) {
+			continue
+		}
+		if len(rule.AlertTypes) > 0 && !containsAny(rule.AlertTypes, types) {
+			continue
+		}
+		if len(rule.Severities) > 0 && !containsAny(rule.Severities, severities) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// routesTo tells if the alerts the rule matches go to the channel, or to the integration when it is not nil.
+// Without a rule they go everywhere.
+func routesTo(rule *models.RoutingRule, channel string, integrationID *int64) bool {
+	if rule == nil {
+		return true
+	}
+	if integrationID != nil {
+		for _, id := range rule.IntegrationIDs {
+			if id == *integrationID {
+				return true
+			}
+		}
+		return false
+	}
+	return containsString(rule.Channels, channel)
+}
+
+// RoutingDestinations returns the names of the channels and the integrations the rule sends to, for the routing page
+func RoutingDestinations(rule *models.RoutingRule, integrations []*models.Integration) []string {
+	destinations := make([]string, 0, len(rule.Channels)+len(rule.IntegrationIDs))
+	for _, channel := range rule.Channels {
+		destinations = append(destinations, IntegrationTitle(channel))
+	}
+	for i := range rule.IntegrationIDs {
+		if name := IntegrationName(&rule.IntegrationIDs[i], integrations); name != "" {
+			destinations = append(destinations, name)
+		}
+	}
+	return destinations
+}
+
+func matchesDomainPattern(patterns []string, domain string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, domain); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsString(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"sort"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+var priorityRanks = map[string]int{
+	ssl.PriorityLow:    0,
+	ssl.PriorityNormal: 1,
+	ssl.PriorityHigh:   2,
+}
+
+// domainSchedule is the polling plan of one domain name, merged over all the users that track it
+type domainSchedule struct {
+	domain     string
+	interval   time.Duration
+	priority   int
+	lastPollAt *time.Time
+	status     *string
+	expires    *time.Time
+	remoteAddr *string
+}
+
+// CheckIntervalBounds returns the shortest and the longest check interval the user's plan allows
+func CheckIntervalBounds(cfg *config.Config, minCheckInterval *int) (min, max time.Duration) {
+	min = cfg.Scheduler.DefaultMinCheckInterval
+	if minCheckInterval != nil {
+		min = time.Duration(*minCheckInterval) * time.Minute
+	}
+	return min, cfg.Scheduler.MaxCheckInterval
+}
+
+// IsValidPriority reports whether the priority is one of the known priority classes
+func IsValidPriority(priority string) bool {
+	_, ok := priorityRanks[priority]
+	return ok
+}
+
+// rowInterval returns the check interval of one tracking row, bounded by its owner's plan
+func rowInterval(cfg *config.Config, row *models.DomainSchedule) time.Duration {
+	interval := cfg.PullUpdateDomainInterval
+	if row.CheckInterval != nil {
+		interval = time.Duration(*row.CheckInterval) * time.Minute
+	}
+
+	min, max := CheckIntervalBounds(cfg, row.MinCheckInterval)
+	if interval < min {
+		interval = min
+	}
+	if max > 0 && interval > max {
+		interval = max
+	}
+	return interval
+}
+
+// mergeSchedules groups the tracking rows by domain name. The shortest interval and the highest priority among the owners win.
+func mergeSchedules(cfg *config.Config, rows []*models.DomainSchedule) map[string]*domainSchedule {
+	schedules := make(map[string]*domainSchedule, len(rows))
+	for _, row := range rows {
+		interval := rowInterval(cfg, row)
+		priority := priorityRanks[row.Priority]
+
+		schedule, ok := schedules[row.DomainName]
+		if !ok {
+			schedules[row.DomainName] = &domainSchedule{
+				domain:     row.DomainName,
+				interval:   interval,
+				priority:   priority,
+				lastPollAt: row.LastPollAt,
+				status:     row.Status,
+				expires:    row.Expires,
+				remoteAddr: row.RemoteAddr,
+			}
+			continue
+		}
+		if interval < schedule.interval {
+			schedule.interval = interval
+		}
+		if priority > schedule.priority {
+			schedule.priority = priority
+		}
+		if schedule.remoteAddr == nil {
+			schedule.remoteAddr = row.RemoteAddr
+		}
+		// a row added after the last poll has a newer last_poll_at, the domain is due by the oldest one
+		if schedule.lastPollAt == nil || (row.LastPollAt != nil && row.LastPollAt.Before(*schedule.lastPollAt)) {
+			schedule.lastPollAt = row.LastPollAt
+		}
+	}
+	return schedules
+}
+
+// isFailingStatus reports whether the last poll found the domain broken
+func isFailingStatus(status *string) bool {
+	if status == nil {
+		return true
+	}
+	switch *status {
+	case ssl.StatusInvalid, ssl.StatusRevoked, ssl.StatusOffline, ssl.StatusUnResponsive, ssl.StatusExpired:
+		return true
+	}
+	return false
+}
+
+// nextPollAt returns when the domain is due. Failing domains and domains close to expiry are polled more often,
+// but not more often than the adaptive floor unless their own interval is already shorter.
+func (s *domainSchedule) nextPollAt(cfg *config.Config, now time.Time) time.Time {
+	if s.lastPollAt == nil {
+		return now
+	}
+
+	interval := s.interval
+	switch {
+	case isFailingStatus(s.status):
+		interval /= 4
+	case s.expires != nil && s.expires.Sub(now) < 7*24*time.Hour:
+		interval /= 4
+	case s.expires != nil && s.expires.Sub(now) < 30*24*time.Hour:
+		interval /= 2
+	}
+
+	floor := cfg.Scheduler.AdaptiveMinInterval
+	if s.interval < floor {
+		floor = s.interval
+	}
+	if interval < floor {
+		interval = floor
+	}
+
+	return s.lastPollAt.Add(interval)
+}
+
+// dueDomain is a domain name whose check interval has passed
+type dueDomain struct {
+	name       string
+	priority   int
+	dueAt      time.Time
+	interval   time.Duration
+	remoteAddr *string
+}
+
+// dueDomains returns the domains due at now, high priority first and then the longest overdue first
+func dueDomains(cfg *config.Config, rows []*models.DomainSchedule, now time.Time) []dueDomain {
+	due := make([]dueDomain, 0)
+	for _, schedule := range mergeSchedules(cfg, rows) {
+		dueAt := schedule.nextPollAt(cfg, now)
+		if dueAt.After(now) {
+			continue
+		}
+		due = append(due, dueDomain{
+			name:       schedule.domain,
+			priority:   schedule.priority,
+			dueAt:      dueAt,
+			interval:   schedule.interval,
+			remoteAddr: schedule.remoteAddr,
+		})
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].priority != due[j].priority {
+			return due[i].priority > due[j].priority
+		}
+		return due[i].dueAt.Before(due[j].dueAt)
+	})
+
+	return due
+}
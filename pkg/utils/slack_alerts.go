@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/slack"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// slack cuts the text of a section at 3000 characters
+const maxSlackSectionText = 3000
+
+// sendNotificationToSlack posts the alert to the slack channel it is addressed to
+func (args *UpdateDomainRegArgs) sendNotificationToSlack(ctx context.Context, alert *models.Alert) error {
+	integration, err := args.alertIntegration(ctx, alert)
+	if err != nil {
+		return err
+	}
+	msg := slackAlertMessage(args.Cfg, alert, args.alertDomain(ctx, alert))
+	return slackClient.PostWebhook(ctx, integration.Config.WebhookURL, msg)
+}
+
+// alertEmoji marks the alert type in chat messages
+func alertEmoji(tp string) string {
+	switch tp {
+	case expiryAlertStr:
+		return "⏳"
+	case expiredAlertStr:
+		return "🔴"
+	case invalidAlertStr:
+		return "⚠️"
+	case revokedAlertStr:
+		return "⛔"
+	case offlineAlertStr:
+		return "🔌"
+	case changeAlertStr:
+		return "🔄"
+	case recoveryAlertStr:
+		return "✅"
+	case renewalAlertStr:
+		return "🔁"
+	case overdueAlertStr:
+		return "⏰"
+	case escalationAlertStr:
+		return "🚨"
+	}
+	return "🔔"
+}
+
+// slackAlertMessage builds the Block Kit message of the alert: what happened, the details of the domain,
+// the changes of a change alert and a button to the domain's page
+func slackAlertMessage(cfg *config.Config, alert *models.Alert, domain *ssl.DomainTracking) *slack.Message {
+	link := domainLink(cfg, domain)
+	headline := alertHeadline(alert)
+	name := slack.Escape(alert.Domain)
+
+	fields := []*slack.Text{
+		slack.Markdown(fmt.Sprintf("*Domain*\n<%s|%s>", link, name)),
+		slack.Markdown("*Alert*\n" + AlertTypeTitle(alert.Type)),
+	}
+	expires := alert.Payload.Expires
+	if expires == nil && domain != nil {
+		expires = domain.Expires
+	}
+	if expires != nil {
+		fields = append(fields, slack.Markdown("*Expires*\n"+expiryCountdown(*expires)))
+	}
+	if alert.Payload.RenewalWindowStart != nil && alert.Payload.RenewalWindowEnd != nil {
+		fields = append(fields, slack.Markdown(fmt.Sprintf("*Renewal window*\n%s - %s",
+			alert.Payload.RenewalWindowStart.Format("02 Jan 2006"), alert.Payload.RenewalWindowEnd.Format("02 Jan 2006"))))
+	}
+	if domain != nil {
+		if domain.Issuer != nil {
+			fields = append(fields, slack.Markdown("*Issuer*\n"+slack.Escape(*domain.Issuer)))
+		}
+		if ip := remoteIP(domain.RemoteAddr); ip != "" {
+			fields = append(fields, slack.Markdown("*IP address*\n"+ip))
+		}
+	}
+	if alert.Payload.Error != nil {
+		fields = append(fields, slack.Markdown("*Error*\n"+slack.Escape(*alert.Payload.Error)))
+	}
+
+	blocks := []*slack.Block{
+		slack.Header(fmt.Sprintf("%s %s: %s", alertEmoji(alert.Type), AlertTypeTitle(alert.Type), alert.Domain)),
+		slack.Section(slack.Markdown(fmt.Sprintf("*%s* %s.", name, slack.Escape(headline))), fields...),
+	}
+	if len(alert.Payload.Changes) > 0 {
+		var changes strings.Builder
+		for _, change := range alert.Payload.Changes {
+			changes.WriteString(fmt.Sprintf("• *%s*: `%s` → `%s`\n", ssl.FieldTitle(change.Field), slack.Escape(change.Old), slack.Escape(change.New)))
+		}
+		blocks = append(blocks, slack.Section(slack.Markdown(truncate(changes.String(), maxSlackSectionText))))
+	}
+
+	buttons := []*slack.Element{slack.Button("View", link, "primary")}
+	// reminders of an incident stop once it is acknowledged, which can be done right from the message
+	if alert.Payload.AckToken != "" && alert.Type != recoveryAlertStr {
+		buttons = append(buttons, slack.Button("Acknowledge", cfg.BaseUrl+"/incidents/ack/"+alert.Payload.AckToken, ""))
+	}
+	if alert.Payload.ExplanationURL != nil {
+		buttons = append(buttons, slack.Button("Explanation", *alert.Payload.ExplanationURL, ""))
+	}
+	blocks = append(blocks,
+		slack.Actions(buttons...),
+		slack.Context(slack.Markdown("CertAlert · "+time.Now().UTC().Format(time.RFC1123))),
+	)
+
+	return &slack.Message{
+		Text:   fmt.Sprintf("%s %s %s", alertEmoji(alert.Type), name, slack.Escape(headline)),
+		Blocks: blocks,
+	}
+}
+
+// slackTestMessage is sent when the user tests the connection
+func slackTestMessage(cfg *config.Config) *slack.Message {
+	text := "This channel is connected to CertAlert. Alerts about your tracked domains will show up here."
+	return &slack.Message{
+		Text: "CertAlert test message",
+		Blocks: []*slack.Block{
+			slack.Header("🔔 CertAlert test message"),
+			slack.Section(slack.Markdown(text)),
+			slack.Actions(slack.Button("View domains", cfg.BaseUrl+"/domains", "primary")),
+		},
+	}
+}
+
+// expiryCountdown returns the expiration date with the days left, like "02 Jan 2006, 12 days left"
+func expiryCountdown(expires time.Time) string {
+	date := expires.UTC().Format("02 Jan 2006 15:04 MST")
+	if !time.Now().Before(expires) {
+		return date + ", expired"
+	}
+	days := daysUntilExpiration(expires)
+	if days == 1 {
+		return date + ", 1 day left"
+	}
+	return fmt.Sprintf("%s, %d days left", date, days)
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/sms"
+	"github.com/SaidovZohid/certalert.info/storage"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// an expiry alert is texted to the on-call phones this close to the expiry, the earlier ones go to the other channels only
+const smsExpiryDays = 1
+
+// ErrSMSCapReached is returned once the user got every text message of the month, retrying it before the next month is no use
+var ErrSMSCapReached = errors.New("monthly sms cap is reached")
+
+// isSMSWorthy tells if the alert is texted to the on-call phones: a certificate about to expire within a day, or expired
+func isSMSWorthy(tp string, payload models.AlertPayload) bool {
+	switch tp {
+	case expiredAlertStr:
+		return true
+	case expiryAlertStr:
+		return payload.Expires != nil && daysUntilExpiration(*payload.Expires) <= smsExpiryDays
+	}
+	return false
+}
+
+// SMSMonthlyCap returns how many text messages the user gets a month
+func SMSMonthlyCap(cfg *config.Config, user *models.User) int {
+	if user != nil && user.MaxSMSPerMonth != nil {
+		return *user.MaxSMSPerMonth
+	}
+	return cfg.SMS.MonthlyCap
+}
+
+// SendSMS texts the phone of the user, counted against the monthly cap of the user. A failed send does not count.
+func SendSMS(ctx context.Context, strg storage.StorageI, cfg *config.Config, provider sms.Provider, userID int64, to, body string) error {
+	if provider == nil {
+		return sms.ErrNotConfigured
+	}
+	user, err := strg.User().GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	ok, err := strg.SMS().ReserveSMS(ctx, userID, SMSMonthlyCap(cfg, user))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSMSCapReached
+	}
+	if err := provider.Send(ctx, to, body); err != nil {
+		if releaseErr := strg.SMS().ReleaseSMS(ctx, userID); releaseErr != nil {
+			return fmt.Errorf("%w, and the message is still counted: %s", err, releaseErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// SMSVerificationBody is the text message with the code that verifies the phone
+func SMSVerificationBody(code string) string {
+	return "Your CertAlert verification code is " + code + ". It confirms this phone gets the SSL alerts of your account."
+}
+
+// smsAlertBody keeps the message short, a text message over 160 characters is billed as several
+func smsAlertBody(cfg *config.Config, alert *models.Alert) string {
+	body := fmt.Sprintf("CertAlert: %s %s.", alert.Domain, alertHeadline(alert))
+	if alert.Type == expiryAlertStr && alert.Payload.Expires != nil {
+		body = fmt.Sprintf("CertAlert: %s SSL certificate expires %s.", alert.Domain, alert.Payload.Expires.UTC().Format("02 Jan 15:04 MST"))
+	}
+	if alert.Payload.AckToken != "" {
+		return body + " Ack: " + cfg.BaseUrl + "/incidents/ack/" + alert.Payload.AckToken
+	}
+	return body
+}
+
+// sendNotificationBySMS texts the alert to the on-call phone it is addressed to
+func (args *UpdateDomainRegArgs) sendNotificationBySMS(ctx context.Context, alert *models.Alert) error {
+	integration, err := args.alertIntegration(ctx, alert)
+	if err != nil {
+		return err
+	}
+	return SendSMS(ctx, args.Strg, args.Cfg, args.SMS, alert.UserID, integration.Config.Phone, smsAlertBody(args.Cfg, alert))
+}
+
+// SendSMSTest texts a test message to the on-call phone, it counts against the monthly cap like the alerts
+func SendSMSTest(ctx context.Context, strg storage.StorageI, cfg *config.Config, provider sms.Provider, integration *models.Integration) error {
+	return SendSMS(ctx, strg, cfg, provider, integration.UserID, integration.Config.Phone,
+		"CertAlert test: this phone gets a text when a certificate expires within a day or has expired.")
+}
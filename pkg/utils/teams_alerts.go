@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/pkg/teams"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+var teamsClient = teams.NewClient()
+
+// sendNotificationToTeams posts the alert card to the teams channel it is addressed to
+func (args *UpdateDomainRegArgs) sendNotificationToTeams(ctx context.Context, alert *models.Alert) error {
+	integration, err := args.alertIntegration(ctx, alert)
+	if err != nil {
+		return err
+	}
+	msg := teamsAlertMessage(args.Cfg, alert, args.alertDomain(ctx, alert))
+	return teamsClient.PostWebhook(ctx, integration.Config.WebhookURL, msg)
+}
+
+// teamsColor returns the text colour of the card for the status of the domain, cards only have a few named colours
+func teamsColor(status string) string {
+	switch status {
+	case ssl.StatusExpired, ssl.StatusInvalid, ssl.StatusRevoked:
+		return "Attention"
+	case ssl.StatusExpires, ssl.StatusOffline, ssl.StatusUnResponsive:
+		return "Warning"
+	case ssl.StatusHealthy:
+		return "Good"
+	}
+	return "Default"
+}
+
+// teamsAlertMessage builds the Adaptive Card of the alert: what happened, the countdown to the expiry,
+// the facts of the certificate, the changes of a change alert and links to the domain's page
+func teamsAlertMessage(cfg *config.Config, alert *models.Alert, domain *ssl.DomainTracking) *teams.Message {
+	link := domainLink(cfg, domain)
+	color := teamsColor(alertStatus(alert, domain))
+
+	title := teams.Text(fmt.Sprintf("%s %s: %s", alertEmoji(alert.Type), AlertTypeTitle(alert.Type), alert.Domain))
+	title.Size, title.Weight, title.Color = "Large", "Bolder", color
+	body := []interface{}{
+		title,
+		teams.Text(fmt.Sprintf("**%s** %s.", alert.Domain, alertHeadline(alert))),
+	}
+
+	expires := alert.Payload.Expires
+	if expires == nil && domain != nil {
+		expires = domain.Expires
+	}
+	if expires != nil {
+		countdown := teams.Text(expiryDaysLeft(*expires))
+		countdown.Size, countdown.Weight, countdown.Color = "ExtraLarge", "Bolder", color
+		until := teams.Text("until " + expires.UTC().Format("02 Jan 2006 15:04 MST"))
+		until.IsSubtle, until.Spacing = true, "None"
+		body = append(body, countdown, until)
+	}
+
+	facts := []*teams.Fact{
+		{Title: "Alert", Value: AlertTypeTitle(alert.Type)},
+	}
+	if status := alertStatus(alert, domain); status != "" {
+		facts = append(facts, &teams.Fact{Title: "Status", Value: status})
+	}
+	if alert.Payload.RenewalWindowStart != nil && alert.Payload.RenewalWindowEnd != nil {
+		facts = append(facts, &teams.Fact{
+			Title: "Renewal window",
+			Value: fmt.Sprintf("%s - %s", alert.Payload.RenewalWindowStart.Format("02 Jan 2006"), alert.Payload.RenewalWindowEnd.Format("02 Jan 2006")),
+		})
+	}
+	if domain != nil {
+		if domain.Issuer != nil {
+			facts = append(facts, &teams.Fact{Title: "Issuer", Value: *domain.Issuer})
+		}
+		if domain.DNSNames != nil && *domain.DNSNames != "" {
+			facts = append(facts, &teams.Fact{Title: "SANs", Value: *domain.DNSNames})
+		}
+		if ip := remoteIP(domain.RemoteAddr); ip != "" {
+			facts = append(facts, &teams.Fact{Title: "IP address", Value: ip})
+		}
+	}
+	if alert.Payload.Error != nil {
+		facts = append(facts, &teams.Fact{Title: "Error", Value: *alert.Payload.Error})
+	}
+	body = append(body, teams.Facts(facts...))
+
+	if len(alert.Payload.Changes) > 0 {
+		heading := teams.Text("Changes")
+		heading.Weight = "Bolder"
+		changes := make([]*teams.Fact, 0, len(alert.Payload.Changes))
+		for _, change := range alert.Payload.Changes {
+			changes = append(changes, &teams.Fact{Title: ssl.FieldTitle(change.Field), Value: change.Old + " → " + change.New})
+		}
+		body = append(body, heading, teams.Facts(changes...))
+	}
+
+	actions := []*teams.Action{teams.OpenURL("View domain", link)}
+	// reminders of an incident stop once it is acknowledged, which can be done right from the card
+	if alert.Payload.AckToken != "" && alert.Type != recoveryAlertStr {
+		actions = append(actions, teams.OpenURL("Acknowledge", cfg.BaseUrl+"/incidents/ack/"+alert.Payload.AckToken))
+	}
+	if alert.Payload.ExplanationURL != nil {
+		actions = append(actions, teams.OpenURL("Explanation", *alert.Payload.ExplanationURL))
+	}
+
+	footer := teams.Text("CertAlert · " + time.Now().UTC().Format(time.RFC1123))
+	footer.Size, footer.IsSubtle = "Small", true
+	body = append(body, footer)
+
+	return teams.NewMessage(&teams.Card{
+		Body:    body,
+		Actions: actions,
+		MSTeams: &teams.MSTeams{Width: "Full"},
+	})
+}
+
+// teamsTestMessage is sent when the user tests the connection
+func teamsTestMessage(cfg *config.Config) *teams.Message {
+	title := teams.Text("🔔 CertAlert test message")
+	title.Size, title.Weight = "Large", "Bolder"
+	return teams.NewMessage(&teams.Card{
+		Body: []interface{}{
+			title,
+			teams.Text("This channel is connected to CertAlert. Alerts about your tracked domains will show up here."),
+		},
+		Actions: []*teams.Action{teams.OpenURL("View domains", cfg.BaseUrl+"/domains")},
+		MSTeams: &teams.MSTeams{Width: "Full"},
+	})
+}
+
+// expiryDaysLeft returns the countdown to the expiry, like "12 days left"
+func expiryDaysLeft(expires time.Time) string {
+	if !time.Now().Before(expires) {
+		return "Expired"
+	}
+	days := daysUntilExpiration(expires)
+	if days == 1 {
+		return "1 day left"
+	}
+	return fmt.Sprintf("%d days left", days)
+}
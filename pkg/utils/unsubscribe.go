@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/SaidovZohid/certalert.info/config"
+)
+
+var ErrInvalidUnsubscribeToken = errors.New("unsubscribe token is not valid")
+
+// UnsubscribeToken returns the token of the unsubscribe link in the user's alert emails.
+// It is signed, so the link works without a login and can't be made up for another user.
+func UnsubscribeToken(cfg *config.Config, userID int64) string {
+	id := strconv.FormatInt(userID, 10)
+	return id + "." + unsubscribeSignature(cfg, id)
+}
+
+// ParseUnsubscribeToken returns the user the unsubscribe token was made for
+func ParseUnsubscribeToken(cfg *config.Config, token string) (int64, error) {
+	id, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(unsubscribeSignature(cfg, id))) {
+		return 0, ErrInvalidUnsubscribeToken
+	}
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidUnsubscribeToken
+	}
+	return userID, nil
+}
+
+func unsubscribeSignature(cfg *config.Config, id string) string {
+	mac := hmac.New(sha256.New, []byte(cfg.JwtAccessTokenSecretKey))
+	mac.Write([]byte("unsubscribe:" + id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/pkg/webhook"
+	"github.com/SaidovZohid/certalert.info/storage"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+// events of the user's own actions, they only go to webhooks
+var domainAddedAlertStr = "domain_added"
+var domainRemovedAlertStr = "domain_removed"
+
+var (
+	webhookClient        = webhook.NewClient(false)
+	privateWebhookClient = webhook.NewClient(true)
+)
+
+// webhookEvent is the json body of a webhook event
+type webhookEvent struct {
+	ID        string           `json:"id"` // the idempotency key, the same on every attempt and redelivery
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Domain    webhookDomain    `json:"domain"`
+	Data      webhookEventData `json:"data"`
+}
+
+// webhookDomain is the domain as it is when the event is delivered, or as it was when it was removed
+type webhookDomain struct {
+	ID      int64      `json:"id,omitempty"`
+	Name    string     `json:"name"`
+	URL     string     `json:"url"`
+	Status  *string    `json:"status,omitempty"`
+	Issuer  *string    `json:"issuer,omitempty"`
+	SANs    []string   `json:"sans,omitempty"`
+	IP      string     `json:"ip,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+	Tags    []string   `json:"tags,omitempty"`
+}
+
+// webhookEventData is what the event is about, as it was when it was raised
+type webhookEventData struct {
+	Expires            *time.Time        `json:"expires,omitempty"`
+	DaysLeft           *int              `json:"days_left,omitempty"`
+	Changes            []ssl.FieldChange `json:"changes,omitempty"`
+	Problem            string            `json:"problem,omitempty"`
+	Error              *string           `json:"error,omitempty"`
+	RenewalWindowStart *time.Time        `json:"renewal_window_start,omitempty"`
+	RenewalWindowEnd   *time.Time        `json:"renewal_window_end,omitempty"`
+	IncidentID         *int64            `json:"incident_id,omitempty"`
+	EscalationStep     int               `json:"escalation_step,omitempty"`
+}
+
+// WebhookEventType returns the name of the event the alert type is sent as
+func WebhookEventType(tp string) string {
+	switch tp {
+	case expiryAlertStr:
+		return "expiring"
+	case expiredAlertStr:
+		return "expired"
+	case changeAlertStr:
+		return "changed"
+	case recoveryAlertStr:
+		return "recovered"
+	case invalidAlertStr:
+		return "invalid"
+	case revokedAlertStr:
+		return "revoked"
+	case offlineAlertStr:
+		return "offline"
+	case renewalAlertStr:
+		return "renewal_window"
+	case overdueAlertStr:
+		return "renewal_overdue"
+	case escalationAlertStr:
+		return "escalated"
+	case domainAddedAlertStr:
+		return "added"
+	case domainRemovedAlertStr:
+		return "removed"
+	}
+	return tp
+}
+
+// NewWebhookSecret returns the secret a new endpoint signs its events with
+func NewWebhookSecret() string {
+	return "whsec_" + GenerateRandomString(32)
+}
+
+// sendNotificationToWebhook posts the signed event of the alert to the endpoint it is addressed to
+func (args *UpdateDomainRegArgs) sendNotificationToWebhook(ctx context.Context, alert *models.Alert) error {
+	integration, err := args.alertIntegration(ctx, alert)
+	if err != nil {
+		return err
+	}
+	event := webhookEventOf(args.Cfg, alert, args.alertDomain(ctx, alert))
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return webhookClientFor(args.Cfg).Post(ctx, integration.Config.WebhookURL, integration.Config.Secret, event.ID, event.Type, body)
+}
+
+func webhookClientFor(cfg *config.Config) *webhook.Client {
+	if cfg.Webhooks.AllowPrivateNetworks {
+		return privateWebhookClient
+	}
+	return webhookClient
+}
+
+// webhookEventOf builds the event of the alert
+func webhookEventOf(cfg *config.Config, alert *models.Alert, domain *ssl.DomainTracking) *webhookEvent {
+	event := &webhookEvent{
+		ID:        alert.Payload.EventID,
+		Type:      WebhookEventType(alert.Type),
+		CreatedAt: alert.CreatedAt.UTC(),
+		Domain: webhookDomain{
+			Name:    alert.Domain,
+			URL:     domainLink(cfg, domain),
+			Status:  alert.Payload.Status,
+			Expires: alert.Payload.Expires,
+		},
+		Data: webhookEventData{
+			Expires:            alert.Payload.Expires,
+			Changes:            alert.Payload.Changes,
+			Problem:            alert.Payload.Problem,
+			Error:              alert.Payload.Error,
+			RenewalWindowStart: alert.Payload.RenewalWindowStart,
+			RenewalWindowEnd:   alert.Payload.RenewalWindowEnd,
+			IncidentID:         alert.IncidentID,
+			EscalationStep:     alert.Payload.EscalationStep,
+		},
+	}
+	if event.ID == "" {
+		// queued before the events had keys, the alert is unique all the same
+		event.ID = "alert-" + strconv.FormatInt(alert.ID, 10)
+	}
+	if alert.Payload.Expires != nil {
+		days := daysUntilExpiration(*alert.Payload.Expires)
+		event.Data.DaysLeft = &days
+	}
+	if domain != nil {
+		event.Domain.ID = domain.ID
+		event.Domain.Status = domain.Status
+		event.Domain.Issuer = domain.Issuer
+		event.Domain.IP = remoteIP(domain.RemoteAddr)
+		event.Domain.Expires = domain.Expires
+		event.Domain.Tags = domain.Tags
+		if domain.DNSNames != nil && *domain.DNSNames != "" {
+			event.Domain.SANs = strings.Split(*domain.DNSNames, ", ")
+		}
+	}
+	return event
+}
+
+// webhookTestEvent is sent when the user tests the endpoint
+func webhookTestEvent(cfg *config.Config) *webhookEvent {
+	return &webhookEvent{
+		ID:        uuid.NewString(),
+		Type:      "test",
+		CreatedAt: time.Now().UTC(),
+		Domain: webhookDomain{
+			Name: "example.com",
+			URL:  cfg.BaseUrl + "/domains",
+		},
+	}
+}
+
+// sendWebhookTest posts a signed test event to the endpoint
+func sendWebhookTest(ctx context.Context, cfg *config.Config, integration *models.Integration) error {
+	event := webhookTestEvent(cfg)
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return webhookClientFor(cfg).Post(ctx, integration.Config.WebhookURL, integration.Config.Secret, event.ID, event.Type, body)
+}
+
+// QueueDomainAdded queues the added event of the domain to the webhooks of its owner
+func QueueDomainAdded(ctx context.Context, strg storage.StorageI, cfg *config.Config, domain *ssl.DomainTracking) error {
+	return queueDomainEvent(ctx, strg, cfg, domain, domainAddedAlertStr)
+}
+
+// QueueDomainRemoved queues the removed event of the domain to the webhooks of its owner, with the domain as it was
+func QueueDomainRemoved(ctx context.Context, strg storage.StorageI, cfg *config.Config, domain *ssl.DomainTracking) error {
+	return queueDomainEvent(ctx, strg, cfg, domain, domainRemovedAlertStr)
+}
+
+func queueDomainEvent(ctx context.Context, strg storage.StorageI, cfg *config.Config, domain *ssl.DomainTracking, tp string) error {
+	notification, err := strg.Notifications().GetNotificationRowByUserID(ctx, domain.UserID)
+	if err != nil {
+		return err
+	}
+	if !notification.WebhookAlert {
+		return nil
+	}
+	integrations, err := strg.Integrations().GetIntegrationsByUserID(ctx, domain.UserID)
+	if err != nil {
+		return err
+	}
+
+	alerts := make([]*models.Alert, 0)
+	for _, integration := range integrations {
+		if integration.Kind != models.IntegrationWebhook {
+			continue
+		}
+		alerts = append(alerts, &models.Alert{
+			UserID:        domain.UserID,
+			Domain:        domain.DomainName,
+			Type:          tp,
+			Channel:       models.ChannelWebhook,
+			IntegrationID: &integration.ID,
+			Payload: models.AlertPayload{
+				Expires: domain.Expires,
+				Status:  domain.Status,
+				EventID: uuid.NewString(),
+			},
+			MaxAttempts:   alertAttempts(cfg),
+			NextAttemptAt: time.Now().UTC(),
+		})
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+	return strg.Alerts().QueueAlerts(ctx, alerts)
+}
@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// headers of an event
+const (
+	HeaderEvent          = "X-CertAlert-Event"
+	HeaderDelivery       = "X-CertAlert-Delivery"
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderTimestamp      = "X-CertAlert-Timestamp"
+	HeaderSignature      = "X-CertAlert-Signature"
+)
+
+var ErrPrivateAddress = errors.New("webhook endpoint resolves to a private address")
+
+// Sign returns the signature of the event: the hex HMAC-SHA256 of "timestamp.body" with the secret of the endpoint.
+// Signing the timestamp with the body lets the receiver turn down an old event replayed to it.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of an event, the way a receiver does
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// ValidateURL checks the url of an endpoint
+func ValidateURL(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return errors.New("webhook url is not valid")
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return errors.New("webhook url must start with https:// or http://")
+	}
+	return nil
+}
+
+// Client posts signed events to the endpoints
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient returns a client that refuses to connect to loopback, private and link-local addresses,
+// unless allowPrivate is set for a self-hosted install whose endpoints live on its own network
+func NewClient(allowPrivate bool) *Client {
+	return &Client{
+		httpClient: NewHTTPClient(allowPrivate),
+	}
+}
+
+// NewHTTPClient returns the http client of NewClient, for the other requests to urls the users give
+func NewHTTPClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = refusePrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   15 * time.Second,
+		Transport: transport,
+		// a redirect could lead anywhere, the endpoint has to answer itself
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Post posts the event to the endpoint, signed with the secret. Any answer but a 2xx is an error.
+func (c *Client) Post(ctx context.Context, endpoint, secret, eventID, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CertAlert-Webhook/1.0")
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderDelivery, eventID)
+	req.Header.Set(HeaderIdempotencyKey, eventID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("webhook: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		answer, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook answered %s: %s", resp.Status, strings.TrimSpace(string(answer)))
+	}
+	return nil
+}
+
+// refusePrivate is the dial control that keeps the events from reaching the network the service runs in
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return ErrPrivateAddress
+	}
+	return nil
+}
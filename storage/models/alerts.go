@@ -0,0 +1,120 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+)
+
+type AlertStorageI interface {
+	SavePollResultWithAlerts(ctx context.Context, domainInfo *ssl.DomainTracking, incidents []*Incident, reminders []*SentReminder, alerts []*Alert) error
+	LeaseDueAlerts(ctx context.Context, workerID string, lease time.Duration, limit int) ([]*Alert, error)
+	MarkAlertSent(ctx context.Context, id int64, workerID string) error
+	RetryAlert(ctx context.Context, id int64, workerID string, nextAttemptAt time.Time, lastError string) error
+	DeadLetterAlert(ctx context.Context, id int64, workerID string, lastError string) error
+	DeadLetterExhaustedAlerts(ctx context.Context) (int64, error)
+	RequeueDeadAlert(ctx context.Context, userID int64, id int64) error
+	CreateAlertDelivery(ctx context.Context, delivery *AlertDelivery) error
+	GetAlertDeliveriesByUserID(ctx context.Context, userID int64, limit int) ([]*AlertDelivery, error)
+	GetSuppressedAlertsByUserID(ctx context.Context, userID int64, limit int) ([]*Alert, error)
+	QueueAlerts(ctx context.Context, alerts []*Alert) error
+	GetAlertDeliveriesByIntegrationID(ctx context.Context, userID int64, integrationID int64, limit int) ([]*AlertDelivery, error)
+	RedeliverAlert(ctx context.Context, userID int64, integrationID int64, id int64) error
+}
+
+// statuses of an alert in the outbox
+const (
+	AlertPending    = "pending"
+	AlertSending    = "sending"
+	AlertSent       = "sent"
+	AlertDead       = "dead"       // ran out of attempts, kept until the user retries it
+	AlertSuppressed = "suppressed" // raised during a maintenance window or a snooze, recorded but never delivered
+)
+
+// results of an attempt to deliver an alert
+const (
+	DeliverySent   = "sent"
+	DeliveryFailed = "failed"
+)
+
+// channels an alert can be delivered to
+const (
+	ChannelEmail     = "email"
+	ChannelTelegram  = "telegram"
+	ChannelSlack     = "slack"
+	ChannelDiscord   = "discord"
+	ChannelTeams     = "teams"
+	ChannelWebhook   = "webhook"
+	ChannelPagerDuty = "pagerduty"
+	ChannelOpsgenie  = "opsgenie"
+	ChannelSMS       = "sms"
+)
+
+// severities of an alert, from the worst, see utils.AlertSeverity
+const (
+	SeverityCritical = "critical"
+	SeverityError    = "error"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+var Severities = []string{SeverityCritical, SeverityError, SeverityWarning, SeverityInfo}
+
+// Alert is one alert to one user over one channel, written to the outbox together with the poll result
+// that caused it and delivered later by the alert dispatcher.
+type Alert struct {
+	ID            int64
+	UserID        int64
+	Domain        string
+	Type          string // expiry_alert, expired_alert, invalid_alert, revoked_alert, offline_alert, change_alert, recovery_alert, renewal_alert, renewal_overdue_alert, escalation_alert, domain_added or domain_removed
+	Channel       string
+	Recipient     *string // email address or telegram chat id of an escalation step, nil means the user
+	IntegrationID *int64  // the connection of the user the alert goes to, for the channels that can have several
+	IncidentID    *int64
+	Incident      *Incident // the incident saved with the alert, its id is not known before
+	Payload       AlertPayload
+	Status        string
+	Attempts      int
+	MaxAttempts   int
+	NextAttemptAt time.Time
+	LastError     *string
+	SuppressedBy  *string // the maintenance window or the snooze the alert was suppressed by
+	RoutedBy      *string // the routing rule that addressed the alert, nil when no rule matched it
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+// AlertPayload is what the message of the alert is built from, as it was when the alert was raised
+type AlertPayload struct {
+	Expires            *time.Time        `json:"expires,omitempty"`
+	RenewalWindowStart *time.Time        `json:"renewal_window_start,omitempty"`
+	RenewalWindowEnd   *time.Time        `json:"renewal_window_end,omitempty"`
+	ExplanationURL     *string           `json:"explanation_url,omitempty"`
+	Changes            []ssl.FieldChange `json:"changes,omitempty"`
+	Status             *string           `json:"status,omitempty"`
+	Error              *string           `json:"error,omitempty"`
+	Problem            string            `json:"problem,omitempty"`   // of the incident, the one that cleared for a recovery alert
+	AckToken           string            `json:"ack_token,omitempty"` // acknowledges the incident from the message
+	OpenedAt           *time.Time        `json:"opened_at,omitempty"` // of the incident, for an escalation alert
+	EscalationStep     int               `json:"escalation_step,omitempty"`
+	EventID            string            `json:"event_id,omitempty"` // idempotency key of a webhook event, the same on every attempt
+}
+
+// AlertDelivery is one attempt to deliver an alert
+type AlertDelivery struct {
+	ID        int64
+	AlertID   int64
+	Attempt   int
+	Status    string // DeliverySent or DeliveryFailed
+	Error     *string
+	CreatedAt time.Time
+
+	// of the alert, filled when listing
+	Domain      string
+	Type        string
+	Channel     string
+	AlertStatus string
+	EventID     string  // of a webhook event
+	RoutedBy    *string // the routing rule that addressed the alert
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type CertificateStorageI interface {
+	SaveSeenCertificate(ctx context.Context, cert *Certificate) error
+	GetCertificatesByDomain(ctx context.Context, domain string) ([]*Certificate, error)
+}
+
+// Certificate is one distinct certificate that a domain has served, from the first to the last poll it was seen in
+type Certificate struct {
+	ID          int64
+	Domain      string
+	Fingerprint string  // sha256 of the DER certificate
+	Serial      *string // hex serial number, nil for the certificates seen before it was recorded
+	Issuer      *string
+	Issued      *time.Time
+	Expires     *time.Time
+	FirstSeen   time.Time
+	LastSeen    time.Time
+}
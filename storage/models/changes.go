@@ -0,0 +1,21 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type ChangeStorageI interface {
+	CreateCertificateChanges(ctx context.Context, changes []*CertificateChange) error
+	GetCertificateChangesByDomain(ctx context.Context, domain string, limit int) ([]*CertificateChange, error)
+}
+
+// CertificateChange is the old and new value of one certificate field, detected between two polls of a domain
+type CertificateChange struct {
+	ID         int64
+	Domain     string
+	Field      string
+	OldValue   string
+	NewValue   string
+	DetectedAt time.Time
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type EscalationStorageI interface {
+	CreateEscalationPolicy(ctx context.Context, policy *EscalationPolicy) error
+	GetEscalationPoliciesByUserID(ctx context.Context, userID int64) ([]*EscalationPolicy, error)
+	DeleteEscalationPolicy(ctx context.Context, userID int64, id int64) error
+	AddEscalationStep(ctx context.Context, userID int64, step *EscalationStep) error
+	DeleteEscalationStep(ctx context.Context, userID int64, id int64) error
+	GetDueEscalations(ctx context.Context, limit int) ([]*DueEscalation, error)
+	EscalateIncident(ctx context.Context, incident *Incident, step *EscalationStep, alerts []*Alert) (bool, error)
+}
+
+// EscalationPolicy alerts more people, step by step, about an incident of its domains that nobody acts on
+type EscalationPolicy struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	Domains   []string // domain names the policy is attached to
+	Tags      []string // domains with any of these tags get the policy too
+	Steps     []*EscalationStep
+	CreatedAt time.Time
+}
+
+// EscalationStep is taken once the incident has been open for Delay minutes and is not acknowledged yet
+type EscalationStep struct {
+	ID            int64
+	PolicyID      int64
+	Position      int
+	Delay         int      // minutes after the incident opened
+	Channel       string   // email, telegram, or the kind of the integration the step pages
+	Recipients    []string // email addresses or telegram chat ids, empty means the owner
+	IntegrationID *int64   // the integration the step pages instead of the recipients
+}
+
+// DueEscalation is an open incident together with the escalation step that is due for it
+type DueEscalation struct {
+	Incident *Incident
+	Step     *EscalationStep
+}
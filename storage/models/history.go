@@ -0,0 +1,25 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type HistoryStorageI interface {
+	CreatePollSnapshot(ctx context.Context, snapshot *PollSnapshot) error
+	GetStatusChangesByDomain(ctx context.Context, domain string, limit int) ([]*PollSnapshot, error)
+	DeletePollSnapshotsBefore(ctx context.Context, before time.Time) (int64, error)
+	DownsamplePollSnapshots(ctx context.Context, before time.Time, bucket time.Duration) (int64, error)
+}
+
+// PollSnapshot is the append-only record of one poll result of a domain
+type PollSnapshot struct {
+	ID          int64
+	Domain      string
+	Status      *string
+	Latency     *int
+	Error       *string
+	RemoteAddr  *string
+	Fingerprint *string
+	PolledAt    time.Time
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type IncidentStorageI interface {
+	GetUnresolvedIncidents(ctx context.Context, userID int64, domain string) ([]*Incident, error)
+	GetIncidentsByUserID(ctx context.Context, userID int64, limit int) ([]*Incident, error)
+	AcknowledgeIncident(ctx context.Context, userID int64, id int64) error
+	GetIncidentByAckToken(ctx context.Context, token string) (*Incident, error)
+	AcknowledgeIncidentByToken(ctx context.Context, token string) (*Incident, error)
+	SnoozeIncident(ctx context.Context, userID int64, id int64, until time.Time) error
+	ResolveIncident(ctx context.Context, userID int64, id int64) error
+}
+
+// problems an incident can be about
+const (
+	ProblemExpiring = "expiring"
+	ProblemExpired  = "expired"
+	ProblemInvalid  = "invalid"
+	ProblemRevoked  = "revoked"
+	ProblemOffline  = "offline"
+	ProblemChanged  = "changed"
+)
+
+// statuses of an incident
+const (
+	IncidentOpen         = "open"
+	IncidentAcknowledged = "acknowledged" // someone is on it, no more reminders
+	IncidentSnoozed      = "snoozed"      // no reminders until SnoozedUntil, then open again
+	IncidentResolved     = "resolved"
+)
+
+// Incident is one problem of one domain as seen by one of its owners, from the poll that found it to the poll that saw it clear.
+type Incident struct {
+	ID             int64
+	UserID         int64
+	Domain         string
+	Problem        string
+	Status         string
+	AckToken       string // lets the incident be acknowledged from an email link or a telegram button
+	OpenedAt       time.Time
+	AcknowledgedAt *time.Time
+	SnoozedUntil   *time.Time
+	ResolvedAt     *time.Time
+	LastNotifiedAt *time.Time
+
+	EscalationPolicyID *int64 // the policy that escalates the incident while nobody acts on it
+	EscalationStep     int    // position of the last escalation step taken, 0 before the first one
+}
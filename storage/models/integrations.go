@@ -9,6 +9,11 @@ type IntegrationsStorageI interface {
 	GetFromTelegramByTGID(ctx context.Context, TGUserID int64) (*TelegramUser, error)
 	LinkTelegramAccountToWebsiteAccount(ctx context.Context, user *TelegramUser) error
 	GetFromTelegramByUserID(ctx context.Context, userID int64) (*TelegramUser, error)
+	CreateIntegration(ctx context.Context, integration *Integration) error
+	GetIntegrationsByUserID(ctx context.Context, userID int64) ([]*Integration, error)
+	GetIntegrationByID(ctx context.Context, userID int64, id int64) (*Integration, error)
+	DeleteIntegration(ctx context.Context, userID int64, id int64) error
+	UpdateIntegrationConfig(ctx context.Context, integration *Integration) error
 }
 
 type TelegramUser struct {
@@ -20,3 +25,55 @@ type TelegramUser struct {
 	Step           string
 	CreatedAt      time.Time
 }
+
+// kinds of integrations
+const (
+	IntegrationSlack     = "slack"
+	IntegrationDiscord   = "discord"
+	IntegrationTeams     = "teams"
+	IntegrationWebhook   = "webhook"
+	IntegrationPagerDuty = "pagerduty"
+	IntegrationOpsgenie  = "opsgenie"
+	// push channels, see channels.Registry
+	IntegrationNtfy     = "ntfy"
+	IntegrationGotify   = "gotify"
+	IntegrationPushover = "pushover"
+	IntegrationMatrix   = "matrix"
+	// on-call phones, connected once the code texted to them is entered
+	IntegrationSMS = "sms"
+)
+
+// Integration is a connection of the user to a chat or an incident tool the alerts go to
+type Integration struct {
+	ID        int64
+	UserID    int64
+	Kind      string
+	Name      string
+	Config    IntegrationConfig
+	CreatedAt time.Time
+}
+
+// IntegrationConfig is how the alerts reach the integration, the fields used depend on its kind
+type IntegrationConfig struct {
+	WebhookURL string `json:"webhook_url,omitempty"` // slack, discord, the incoming webhook or the Workflow of teams, the endpoint of a webhook
+	TeamID     string `json:"team_id,omitempty"`     // slack workspace of an app install
+	TeamName   string `json:"team_name,omitempty"`
+	Channel    string `json:"channel,omitempty"`
+	Secret     string `json:"secret,omitempty"`      // the events to a webhook are signed with it
+	RoutingKey string `json:"routing_key,omitempty"` // integration key of a pagerduty service
+	APIKey     string `json:"api_key,omitempty"`     // key of an opsgenie api integration
+	Region     string `json:"region,omitempty"`      // of the opsgenie account, us or eu
+	// severity or priority of the incident tool by the severity of the alert, missing ones take the default mapping
+	Severities map[string]string `json:"severities,omitempty"`
+	// push channels
+	ServerURL string `json:"server_url,omitempty"` // ntfy server, gotify server, matrix homeserver
+	Topic     string `json:"topic,omitempty"`      // ntfy topic
+	Token     string `json:"token,omitempty"`      // ntfy access token, gotify or pushover application token, matrix access token
+	UserKey   string `json:"user_key,omitempty"`   // pushover user or group key
+	RoomID    string `json:"room_id,omitempty"`    // matrix room
+	// text/templates of the message of a push channel, empty ones take the default of the channel
+	TitleTemplate   string `json:"title_template,omitempty"`
+	MessageTemplate string `json:"message_template,omitempty"`
+	// on-call phone of the sms alerts, in the E.164 format
+	Phone string `json:"phone,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type MaintenanceStorageI interface {
+	CreateMaintenanceWindow(ctx context.Context, window *MaintenanceWindow) error
+	GetMaintenanceWindowsByUserID(ctx context.Context, userID int64) ([]*MaintenanceWindow, error)
+	DeleteMaintenanceWindow(ctx context.Context, userID int64, id int64) error
+}
+
+// MaintenanceWindow is planned work on the user's domains. Alerts about its domains are recorded
+// as suppressed instead of being delivered while it is active. It is either one-off, from StartsAt
+// to EndsAt, or recurring, starting at every match of Cron in Timezone and lasting Duration minutes.
+type MaintenanceWindow struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	Domains   []string
+	Tags      []string
+	StartsAt  *time.Time
+	EndsAt    *time.Time
+	Cron      *string
+	Duration  *int
+	Timezone  string
+	CreatedAt time.Time
+}
@@ -8,16 +8,31 @@ type NotificationStorageI interface {
 	CreateNotificationRow(ctx context.Context, userID int64) error
 	UpdateTheAlertIntegrations(ctx context.Context, userID int64, nameField string, value bool) error
 	GetNotificationRowByUserID(ctx context.Context, userID int64) (*Notification, error)
+	UpdateChangeAlertFields(ctx context.Context, userID int64, fields []string) error
+	UpdateReminderDays(ctx context.Context, userID int64, days []int) error
+	UpdateQuietHours(ctx context.Context, userID int64, timezone string, start, end *int) error
 }
 
 type Notification struct {
 	UserID              int64
-	ExpiryAlerts        bool // default true in db
-	ChangeAlert         bool // default true in db
-	Before              int
-	EmailAlert          bool // default true in db
-	TelegramAlert       bool // false
-	SlackAlert          bool // false
-	DiscordAlert        bool // false
-	MicrosoftTeamsAlert bool // false
+	ExpiryAlerts        bool     // default true in db
+	ChangeAlert         bool     // default true in db
+	ReminderDays        []int    // days before expiry the expiry reminders go out at
+	EmailAlert          bool     // default true in db
+	TelegramAlert       bool     // false
+	SlackAlert          bool     // false
+	DiscordAlert        bool     // false
+	MicrosoftTeamsAlert bool     // false
+	WebhookAlert        bool     // false
+	PagerDutyAlert      bool     // false
+	OpsgenieAlert       bool     // false
+	NtfyAlert           bool     // false
+	GotifyAlert         bool     // false
+	PushoverAlert       bool     // false
+	MatrixAlert         bool     // false
+	SMSAlert            bool     // false
+	ChangeAlertFields   []string // certificate fields whose change is worth an alert, see ssl.ChangeFields
+	Timezone            string   // alerts are timed in it, default UTC in db
+	QuietHoursStart     *int     // minutes after midnight, nil means no quiet hours
+	QuietHoursEnd       *int
 }
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type PollJobStorageI interface {
+	EnqueuePollJobs(ctx context.Context, jobs []*PollJob) (int64, error)
+	LeasePollJob(ctx context.Context, workerID string, lease time.Duration, maxPerIP, maxPerSite int) (*PollJob, error)
+	CompletePollJob(ctx context.Context, id int64, workerID string) error
+	RetryPollJob(ctx context.Context, id int64, workerID string, runAt time.Time, lastError string) error
+	FailPollJob(ctx context.Context, id int64, workerID string, lastError string) error
+	FailExhaustedPollJobs(ctx context.Context) (int64, error)
+	DeleteFailedPollJobsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// statuses of a poll job
+const (
+	PollJobPending = "pending"
+	PollJobLeased  = "leased"
+	PollJobFailed  = "failed"
+)
+
+// PollJob is one queued poll of a domain name. Completed jobs are deleted, failed ones are kept for inspection.
+type PollJob struct {
+	ID          int64
+	Domain      string
+	Priority    int
+	IP          string // the domain answered from at its last poll, empty if unknown
+	Site        string // registrable domain of the domain name, like example.co.uk
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LeasedBy    *string
+	LeaseUntil  *time.Time
+	LastError   *string
+	CreatedAt   time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type ReminderStorageI interface {
+	GetSentReminders(ctx context.Context, userID int64, domain string, serial string) ([]*SentReminder, error)
+}
+
+// kinds of the reminders about a certificate
+const (
+	ReminderExpiry  = "expiry"  // Days before expiry
+	ReminderExpired = "expired" // Days after expiry
+	ReminderRenewal = "renewal"
+	ReminderOverdue = "overdue"
+)
+
+// SentReminder is one reminder a user got about one certificate of a domain. Each goes out once per certificate.
+type SentReminder struct {
+	ID     int64
+	UserID int64
+	Domain string
+	Serial string // serial number of the certificate, its fingerprint if the serial is not known
+	Kind   string
+	Days   int
+	SentAt time.Time
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type RenewalInfoStorageI interface {
+	UpsertRenewalInfo(ctx context.Context, info *RenewalInfo) error
+	GetRenewalInfoByDomain(ctx context.Context, domain string) (*RenewalInfo, error)
+}
+
+// RenewalInfo is the ARI suggested renewal window stored for the certificate currently served by a domain
+type RenewalInfo struct {
+	Domain         string
+	CertID         string
+	WindowStart    time.Time
+	WindowEnd      time.Time
+	ExplanationURL *string
+	RetryAfter     time.Time
+	CheckedAt      time.Time
+}
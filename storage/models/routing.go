@@ -0,0 +1,30 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type RoutingStorageI interface {
+	CreateRoutingRule(ctx context.Context, rule *RoutingRule) error
+	GetRoutingRulesByUserID(ctx context.Context, userID int64) ([]*RoutingRule, error)
+	DeleteRoutingRule(ctx context.Context, userID int64, id int64) error
+	MoveRoutingRule(ctx context.Context, userID int64, id int64, up bool) error
+}
+
+// RoutingRule sends the alerts it matches to its destinations only. The rules of a user are tried in order and the first
+// one that matches routes the alert, an alert no rule matches goes to every channel and integration that is turned on.
+// A rule matches an alert when every condition it has matches, empty conditions match every alert.
+type RoutingRule struct {
+	ID             int64
+	UserID         int64
+	Name           string
+	Position       int
+	Domains        []string // domain name patterns, like *.example.com
+	Tags           []string // domains with any of these tags
+	AlertTypes     []string
+	Severities     []string
+	Channels       []string // email and telegram
+	IntegrationIDs []int64
+	CreatedAt      time.Time
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"context"
+)
+
+type SMSStorageI interface {
+	// ReserveSMS counts a text message to the user this month, false when the user has got limit of them already
+	ReserveSMS(ctx context.Context, userID int64, limit int) (bool, error)
+	// ReleaseSMS gives back the reserved message of a send that failed
+	ReleaseSMS(ctx context.Context, userID int64) error
+	GetSMSSentThisMonth(ctx context.Context, userID int64) (int, error)
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"time"
 
 	"github.com/SaidovZohid/certalert.info/pkg/ssl"
 )
@@ -15,7 +16,25 @@ type DomainStorageI interface {
 	UpdateExistingDomainInfo(ctx context.Context, domainInfo *ssl.DomainTracking) error
 	DeleteTrackingDomain(ctx context.Context, userID int64, domainId int64) error
 	GetListofDomainsThatExists(ctx context.Context) ([]*ssl.DomainTracking, error)
+	GetDomainInfoByName(ctx context.Context, domain string) (*ssl.DomainTracking, error)
 	UpdateAllTheSameDomainsInfo(ctx context.Context, domainInfo *ssl.DomainTracking) error
 	GetListofUsersThatDomainExists(ctx context.Context, domain string) ([]int64, error)
 	UpdateTheLastAlertTime(ctx context.Context, userID int64, domain string) error
+	UpdateDomainSchedule(ctx context.Context, userID int64, domainID int64, checkInterval *int, priority string) error
+	GetDomainSchedules(ctx context.Context) ([]*DomainSchedule, error)
+	UpdateDomainReminderDays(ctx context.Context, userID int64, domainID int64, days []int) error
+	UpdateDomainTags(ctx context.Context, userID int64, domainID int64, tags []string) error
+	UpdateDomainSnooze(ctx context.Context, userID int64, domainID int64, until *time.Time) error
+}
+
+// DomainSchedule is the polling settings of one tracking row together with the plan limit of its owner
+type DomainSchedule struct {
+	DomainName       string
+	CheckInterval    *int // minutes
+	Priority         string
+	MinCheckInterval *int // minutes, plan limit of the owner
+	LastPollAt       *time.Time
+	Status           *string
+	Expires          *time.Time
+	RemoteAddr       *string // host:port the domain answered from at its last poll
 }
@@ -24,6 +24,8 @@ type User struct {
 	Password           string
 	LastPollAt         *time.Time
 	MaxDomainsTracking *int
+	MinCheckInterval   *int // minutes, nil means the default plan limit
+	MaxSMSPerMonth     *int // nil means the default cap, SMS_MONTHLY_CAP
 	UserAcceptedTerms  *bool
 	SignUpMethod       string
 	CreatedAt          time.Time
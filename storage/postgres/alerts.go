@@ -0,0 +1,452 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/pkg/ssl"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type alertRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewAlerts(db *pgxpool.Pool, log logger.Logger) models.AlertStorageI {
+	return &alertRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// SavePollResultWithAlerts saves the polled info of the domain, the incidents it opened, reminded or resolved,
+// the reminders it sent, and queues the alerts it raised, all in one transaction, so an alert is never lost,
+// sent twice or sent for a poll result that was not saved. The last alert time of every alerted user is moved in the same transaction.
+func (a *alertRepo) SavePollResultWithAlerts(ctx context.Context, domainInfo *ssl.DomainTracking, incidents []*models.Incident, reminders []*models.SentReminder, alerts []*models.Alert) error {
+	tx, err := a.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateAllTheSameDomainsInfo(ctx, tx, domainInfo); err != nil {
+		return err
+	}
+
+	for _, incident := range incidents {
+		if err := saveIncident(ctx, tx, incident); err != nil {
+			return err
+		}
+	}
+
+	for _, reminder := range reminders {
+		if err := saveSentReminder(ctx, tx, reminder); err != nil {
+			return err
+		}
+	}
+
+	alerted := make(map[int64]bool)
+	for _, alert := range alerts {
+		if err := insertAlert(ctx, tx, alert); err != nil {
+			return err
+		}
+		alerted[alert.UserID] = true
+	}
+
+	for userID := range alerted {
+		if err := updateTheLastAlertTime(ctx, tx, userID, domainInfo.DomainName); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// insertAlert writes the alert to the outbox, linked to its incident. A suppressed alert is recorded but never leased.
+func insertAlert(ctx context.Context, db querier, alert *models.Alert) error {
+	payload, err := json.Marshal(alert.Payload)
+	if err != nil {
+		return err
+	}
+	if alert.Incident != nil {
+		alert.IncidentID = &alert.Incident.ID
+	}
+	if alert.Status == "" {
+		alert.Status = models.AlertPending
+	}
+
+	query := `
+		INSERT INTO alerts (
+			user_id,
+			domain,
+			type,
+			channel,
+			recipient,
+			payload,
+			max_attempts,
+			next_attempt_at,
+			incident_id,
+			status,
+			suppressed_by,
+			integration_id,
+			routed_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id
+	`
+	return db.QueryRow(ctx, query, alert.UserID, alert.Domain, alert.Type, alert.Channel, alert.Recipient, payload, alert.MaxAttempts, alert.NextAttemptAt, alert.IncidentID, alert.Status, alert.SuppressedBy, alert.IntegrationID, alert.RoutedBy).Scan(&alert.ID)
+}
+
+// LeaseDueAlerts hands up to limit alerts that are due to the worker until the lease runs out.
+// An alert whose lease ran out without being marked is due again while it has attempts left.
+func (a *alertRepo) LeaseDueAlerts(ctx context.Context, workerID string, lease time.Duration, limit int) ([]*models.Alert, error) {
+	query := `
+		UPDATE alerts SET
+			status = 'sending',
+			leased_by = $1,
+			lease_until = NOW() + $2 * INTERVAL '1 second',
+			attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM alerts
+			WHERE (status = 'pending' AND next_attempt_at <= NOW()) OR
+				(status = 'sending' AND lease_until < NOW() AND attempts < max_attempts)
+			ORDER BY next_attempt_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT $3
+		)
+		RETURNING
+			id,
+			user_id,
+			domain,
+			type,
+			channel,
+			recipient,
+			integration_id,
+			incident_id,
+			payload,
+			status,
+			attempts,
+			max_attempts,
+			next_attempt_at,
+			last_error,
+			created_at,
+			sent_at
+	`
+	rows, err := a.db.Query(ctx, query, workerID, lease.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]*models.Alert, 0)
+	for rows.Next() {
+		var (
+			alert   models.Alert
+			payload []byte
+		)
+		err := rows.Scan(
+			&alert.ID,
+			&alert.UserID,
+			&alert.Domain,
+			&alert.Type,
+			&alert.Channel,
+			&alert.Recipient,
+			&alert.IntegrationID,
+			&alert.IncidentID,
+			&payload,
+			&alert.Status,
+			&alert.Attempts,
+			&alert.MaxAttempts,
+			&alert.NextAttemptAt,
+			&alert.LastError,
+			&alert.CreatedAt,
+			&alert.SentAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payload, &alert.Payload); err != nil {
+			a.log.Errorf("Failed to read payload of alert %d: %s", alert.ID, err)
+		}
+		alerts = append(alerts, &alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+func (a *alertRepo) MarkAlertSent(ctx context.Context, id int64, workerID string) error {
+	query := `
+		UPDATE alerts SET
+			status = 'sent',
+			lease_until = NULL,
+			sent_at = NOW()
+		WHERE id = $1 AND leased_by = $2 AND status = 'sending'
+	`
+	_, err := a.db.Exec(ctx, query, id, workerID)
+	return err
+}
+
+func (a *alertRepo) RetryAlert(ctx context.Context, id int64, workerID string, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE alerts SET
+			status = 'pending',
+			leased_by = NULL,
+			lease_until = NULL,
+			next_attempt_at = $1,
+			last_error = $2
+		WHERE id = $3 AND leased_by = $4 AND status = 'sending'
+	`
+	_, err := a.db.Exec(ctx, query, nextAttemptAt, lastError, id, workerID)
+	return err
+}
+
+func (a *alertRepo) DeadLetterAlert(ctx context.Context, id int64, workerID string, lastError string) error {
+	query := `
+		UPDATE alerts SET
+			status = 'dead',
+			lease_until = NULL,
+			last_error = $1
+		WHERE id = $2 AND leased_by = $3 AND status = 'sending'
+	`
+	_, err := a.db.Exec(ctx, query, lastError, id, workerID)
+	return err
+}
+
+// DeadLetterExhaustedAlerts marks the alerts whose last lease ran out with no attempts left as dead.
+func (a *alertRepo) DeadLetterExhaustedAlerts(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE alerts SET
+			status = 'dead',
+			lease_until = NULL,
+			last_error = COALESCE(last_error, 'lease expired')
+		WHERE status = 'sending' AND lease_until < NOW() AND attempts >= max_attempts
+	`
+	tag, err := a.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// RequeueDeadAlert gives a dead alert of the user a new round of attempts
+func (a *alertRepo) RequeueDeadAlert(ctx context.Context, userID int64, id int64) error {
+	query := `
+		UPDATE alerts SET
+			status = 'pending',
+			attempts = 0,
+			leased_by = NULL,
+			next_attempt_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = 'dead'
+	`
+	_, err := a.db.Exec(ctx, query, id, userID)
+	return err
+}
+
+func (a *alertRepo) CreateAlertDelivery(ctx context.Context, delivery *models.AlertDelivery) error {
+	query := `
+		INSERT INTO alert_deliveries (
+			alert_id,
+			attempt,
+			status,
+			error
+		) VALUES ($1, $2, $3, $4)
+	`
+	_, err := a.db.Exec(ctx, query, delivery.AlertID, delivery.Attempt, delivery.Status, delivery.Error)
+	return err
+}
+
+// GetAlertDeliveriesByUserID returns the latest delivery attempts of the user's alerts, newest first
+func (a *alertRepo) GetAlertDeliveriesByUserID(ctx context.Context, userID int64, limit int) ([]*models.AlertDelivery, error) {
+	query := `
+		SELECT
+			d.id,
+			d.alert_id,
+			d.attempt,
+			d.status,
+			d.error,
+			d.created_at,
+			a.domain,
+			a.type,
+			a.channel,
+			a.status,
+			a.routed_by
+		FROM alert_deliveries d
+		JOIN alerts a ON a.id = d.alert_id
+		WHERE a.user_id = $1
+		ORDER BY d.created_at DESC
+		LIMIT $2
+	`
+	rows, err := a.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.AlertDelivery, 0)
+	for rows.Next() {
+		var delivery models.AlertDelivery
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.AlertID,
+			&delivery.Attempt,
+			&delivery.Status,
+			&delivery.Error,
+			&delivery.CreatedAt,
+			&delivery.Domain,
+			&delivery.Type,
+			&delivery.Channel,
+			&delivery.AlertStatus,
+			&delivery.RoutedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// GetSuppressedAlertsByUserID returns the latest alerts of the user that were recorded instead of delivered, newest first
+func (a *alertRepo) GetSuppressedAlertsByUserID(ctx context.Context, userID int64, limit int) ([]*models.Alert, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			domain,
+			type,
+			channel,
+			status,
+			suppressed_by,
+			created_at
+		FROM alerts
+		WHERE user_id = $1 AND status = 'suppressed'
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := a.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]*models.Alert, 0)
+	for rows.Next() {
+		var alert models.Alert
+		err := rows.Scan(
+			&alert.ID,
+			&alert.UserID,
+			&alert.Domain,
+			&alert.Type,
+			&alert.Channel,
+			&alert.Status,
+			&alert.SuppressedBy,
+			&alert.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, &alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+// QueueAlerts writes alerts that are not raised by a poll to the outbox, like the events of added and removed domains
+func (a *alertRepo) QueueAlerts(ctx context.Context, alerts []*models.Alert) error {
+	tx, err := a.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, alert := range alerts {
+		if err := insertAlert(ctx, tx, alert); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetAlertDeliveriesByIntegrationID returns the latest delivery attempts of the alerts to the integration, newest first
+func (a *alertRepo) GetAlertDeliveriesByIntegrationID(ctx context.Context, userID int64, integrationID int64, limit int) ([]*models.AlertDelivery, error) {
+	query := `
+		SELECT
+			d.id,
+			d.alert_id,
+			d.attempt,
+			d.status,
+			d.error,
+			d.created_at,
+			a.domain,
+			a.type,
+			a.channel,
+			a.status,
+			COALESCE(a.payload->>'event_id', ''),
+			a.routed_by
+		FROM alert_deliveries d
+		JOIN alerts a ON a.id = d.alert_id
+		WHERE a.user_id = $1 AND a.integration_id = $2
+		ORDER BY d.created_at DESC
+		LIMIT $3
+	`
+	rows, err := a.db.Query(ctx, query, userID, integrationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.AlertDelivery, 0)
+	for rows.Next() {
+		var delivery models.AlertDelivery
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.AlertID,
+			&delivery.Attempt,
+			&delivery.Status,
+			&delivery.Error,
+			&delivery.CreatedAt,
+			&delivery.Domain,
+			&delivery.Type,
+			&delivery.Channel,
+			&delivery.AlertStatus,
+			&delivery.EventID,
+			&delivery.RoutedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// RedeliverAlert delivers a sent or a dead event of the user's webhook once more, with a new round of attempts.
+// It returns pgx.ErrNoRows when the alert is not an event of that webhook or is still being delivered.
+func (a *alertRepo) RedeliverAlert(ctx context.Context, userID int64, integrationID int64, id int64) error {
+	query := `
+		UPDATE alerts SET
+			status = 'pending',
+			attempts = 0,
+			leased_by = NULL,
+			lease_until = NULL,
+			next_attempt_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND integration_id = $3 AND channel = 'webhook' AND status IN ('sent', 'dead')
+	`
+	tag, err := a.db.Exec(ctx, query, id, userID, integrationID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type certificateRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewCertificates(db *pgxpool.Pool, log logger.Logger) models.CertificateStorageI {
+	return &certificateRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// SaveSeenCertificate inserts the certificate the first time it is seen for the domain, afterwards only last_seen moves.
+func (c *certificateRepo) SaveSeenCertificate(ctx context.Context, cert *models.Certificate) error {
+	query := `
+		INSERT INTO certificates (
+			domain,
+			fingerprint,
+			serial,
+			issuer,
+			issued,
+			expires,
+			first_seen,
+			last_seen
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT (domain, fingerprint) DO UPDATE SET
+			serial = COALESCE(certificates.serial, EXCLUDED.serial),
+			last_seen = EXCLUDED.last_seen
+		RETURNING id, first_seen, last_seen
+	`
+	err := c.db.QueryRow(
+		ctx,
+		query,
+		cert.Domain,
+		cert.Fingerprint,
+		cert.Serial,
+		cert.Issuer,
+		cert.Issued,
+		cert.Expires,
+		cert.LastSeen,
+	).Scan(
+		&cert.ID,
+		&cert.FirstSeen,
+		&cert.LastSeen,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetCertificatesByDomain returns the certificates of the domain, oldest first.
+func (c *certificateRepo) GetCertificatesByDomain(ctx context.Context, domain string) ([]*models.Certificate, error) {
+	query := `
+		SELECT
+			id,
+			domain,
+			fingerprint,
+			serial,
+			issuer,
+			issued,
+			expires,
+			first_seen,
+			last_seen
+		FROM certificates
+		WHERE domain = $1
+		ORDER BY first_seen
+	`
+	res, err := c.db.Query(ctx, query, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	response := make([]*models.Certificate, 0)
+	for res.Next() {
+		var cert models.Certificate
+		err := res.Scan(
+			&cert.ID,
+			&cert.Domain,
+			&cert.Fingerprint,
+			&cert.Serial,
+			&cert.Issuer,
+			&cert.Issued,
+			&cert.Expires,
+			&cert.FirstSeen,
+			&cert.LastSeen,
+		)
+		if err != nil {
+			c.log.Error(err)
+			continue
+		}
+		response = append(response, &cert)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type changeRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewChanges(db *pgxpool.Pool, log logger.Logger) models.ChangeStorageI {
+	return &changeRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (c *changeRepo) CreateCertificateChanges(ctx context.Context, changes []*models.CertificateChange) error {
+	query := `
+		INSERT INTO certificate_changes (
+			domain,
+			field,
+			old_value,
+			new_value,
+			detected_at
+		) VALUES ($1, $2, $3, $4, $5)
+	`
+	batch := &pgx.Batch{}
+	for _, change := range changes {
+		batch.Queue(query, change.Domain, change.Field, change.OldValue, change.NewValue, change.DetectedAt)
+	}
+
+	res := c.db.SendBatch(ctx, batch)
+	defer res.Close()
+
+	for range changes {
+		if _, err := res.Exec(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *changeRepo) GetCertificateChangesByDomain(ctx context.Context, domain string, limit int) ([]*models.CertificateChange, error) {
+	query := `
+		SELECT
+			id,
+			domain,
+			field,
+			old_value,
+			new_value,
+			detected_at
+		FROM certificate_changes
+		WHERE domain = $1
+		ORDER BY detected_at DESC, id
+		LIMIT $2
+	`
+	res, err := c.db.Query(ctx, query, domain, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	response := make([]*models.CertificateChange, 0)
+	for res.Next() {
+		var change models.CertificateChange
+		err := res.Scan(
+			&change.ID,
+			&change.Domain,
+			&change.Field,
+			&change.OldValue,
+			&change.NewValue,
+			&change.DetectedAt,
+		)
+		if err != nil {
+			c.log.Error(err)
+			continue
+		}
+		response = append(response, &change)
+	}
+
+	return response, nil
+}
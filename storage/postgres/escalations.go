@@ -0,0 +1,267 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type escalationRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewEscalations(db *pgxpool.Pool, log logger.Logger) models.EscalationStorageI {
+	return &escalationRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (e *escalationRepo) CreateEscalationPolicy(ctx context.Context, policy *models.EscalationPolicy) error {
+	query := `
+		INSERT INTO escalation_policies (
+			user_id,
+			name,
+			domains,
+			tags
+		) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return e.db.QueryRow(ctx, query, policy.UserID, policy.Name, policy.Domains, policy.Tags).Scan(&policy.ID, &policy.CreatedAt)
+}
+
+// GetEscalationPoliciesByUserID returns the policies of the user, oldest first, each with its steps in order
+func (e *escalationRepo) GetEscalationPoliciesByUserID(ctx context.Context, userID int64) ([]*models.EscalationPolicy, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			domains,
+			tags,
+			created_at
+		FROM escalation_policies
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+	rows, err := e.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]*models.EscalationPolicy, 0)
+	byID := make(map[int64]*models.EscalationPolicy)
+	for rows.Next() {
+		var policy models.EscalationPolicy
+		err := rows.Scan(
+			&policy.ID,
+			&policy.UserID,
+			&policy.Name,
+			&policy.Domains,
+			&policy.Tags,
+			&policy.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		policy.Steps = make([]*models.EscalationStep, 0)
+		policies = append(policies, &policy)
+		byID[policy.ID] = &policy
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT
+			s.id,
+			s.policy_id,
+			s.position,
+			s.delay,
+			s.channel,
+			s.recipients,
+			s.integration_id
+		FROM escalation_steps s
+		JOIN escalation_policies p ON p.id = s.policy_id
+		WHERE p.user_id = $1
+		ORDER BY s.position
+	`
+	stepRows, err := e.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer stepRows.Close()
+
+	for stepRows.Next() {
+		var step models.EscalationStep
+		err := stepRows.Scan(
+			&step.ID,
+			&step.PolicyID,
+			&step.Position,
+			&step.Delay,
+			&step.Channel,
+			&step.Recipients,
+			&step.IntegrationID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if policy, ok := byID[step.PolicyID]; ok {
+			policy.Steps = append(policy.Steps, &step)
+		}
+	}
+
+	return policies, stepRows.Err()
+}
+
+func (e *escalationRepo) DeleteEscalationPolicy(ctx context.Context, userID int64, id int64) error {
+	query := `DELETE FROM escalation_policies WHERE id = $1 AND user_id = $2`
+	_, err := e.db.Exec(ctx, query, id, userID)
+	return err
+}
+
+// AddEscalationStep appends the step to the end of the user's policy
+func (e *escalationRepo) AddEscalationStep(ctx context.Context, userID int64, step *models.EscalationStep) error {
+	query := `
+		INSERT INTO escalation_steps (
+			policy_id,
+			position,
+			delay,
+			channel,
+			recipients,
+			integration_id
+		)
+		SELECT
+			p.id,
+			COALESCE((SELECT MAX(position) FROM escalation_steps WHERE policy_id = p.id), 0) + 1,
+			$1,
+			$2,
+			$3,
+			$4
+		FROM escalation_policies p
+		WHERE p.id = $5 AND p.user_id = $6
+		RETURNING id, position
+	`
+	return e.db.QueryRow(ctx, query, step.Delay, step.Channel, step.Recipients, step.IntegrationID, step.PolicyID, userID).Scan(&step.ID, &step.Position)
+}
+
+func (e *escalationRepo) DeleteEscalationStep(ctx context.Context, userID int64, id int64) error {
+	query := `
+		DELETE FROM escalation_steps s
+		USING escalation_policies p
+		WHERE s.id = $1 AND p.id = s.policy_id AND p.user_id = $2
+	`
+	_, err := e.db.Exec(ctx, query, id, userID)
+	return err
+}
+
+// GetDueEscalations returns the open incidents whose next escalation step is due, with that step
+func (e *escalationRepo) GetDueEscalations(ctx context.Context, limit int) ([]*models.DueEscalation, error) {
+	query := `
+		SELECT ` + incidentColumns + `,
+			step_id,
+			step_position,
+			step_delay,
+			step_channel,
+			step_recipients,
+			step_integration_id
+		FROM incidents i
+		JOIN LATERAL (
+			SELECT
+				id AS step_id,
+				position AS step_position,
+				delay AS step_delay,
+				channel AS step_channel,
+				recipients AS step_recipients,
+				integration_id AS step_integration_id
+			FROM escalation_steps
+			WHERE policy_id = i.escalation_policy_id AND position > i.escalation_step
+			ORDER BY position
+			LIMIT 1
+		) s ON true
+		WHERE i.status = 'open' AND i.opened_at + s.step_delay * INTERVAL '1 minute' <= NOW()
+		ORDER BY i.opened_at
+		LIMIT $1
+	`
+	rows, err := e.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := make([]*models.DueEscalation, 0)
+	for rows.Next() {
+		var (
+			incident models.Incident
+			step     models.EscalationStep
+		)
+		err := rows.Scan(
+			&incident.ID,
+			&incident.UserID,
+			&incident.Domain,
+			&incident.Problem,
+			&incident.Status,
+			&incident.AckToken,
+			&incident.OpenedAt,
+			&incident.AcknowledgedAt,
+			&incident.SnoozedUntil,
+			&incident.ResolvedAt,
+			&incident.LastNotifiedAt,
+			&incident.EscalationPolicyID,
+			&incident.EscalationStep,
+			&step.ID,
+			&step.Position,
+			&step.Delay,
+			&step.Channel,
+			&step.Recipients,
+			&step.IntegrationID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		step.PolicyID = *incident.EscalationPolicyID
+		due = append(due, &models.DueEscalation{
+			Incident: &incident,
+			Step:     &step,
+		})
+	}
+
+	return due, rows.Err()
+}
+
+// EscalateIncident marks the step as taken and queues its alerts in one transaction. It returns false
+// without queuing anything when the incident moved on meanwhile: another instance took the step,
+// or someone acknowledged, snoozed or resolved it.
+func (e *escalationRepo) EscalateIncident(ctx context.Context, incident *models.Incident, step *models.EscalationStep, alerts []*models.Alert) (bool, error) {
+	tx, err := e.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE incidents SET
+			escalation_step = $1
+		WHERE id = $2 AND escalation_step = $3 AND status = 'open'
+	`
+	tag, err := tx.Exec(ctx, query, step.Position, incident.ID, incident.EscalationStep)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	for _, alert := range alerts {
+		if err := insertAlert(ctx, tx, alert); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
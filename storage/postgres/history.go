@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type historyRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewHistory(db *pgxpool.Pool, log logger.Logger) models.HistoryStorageI {
+	return &historyRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (h *historyRepo) CreatePollSnapshot(ctx context.Context, snapshot *models.PollSnapshot) error {
+	query := `
+		INSERT INTO poll_history (
+			domain,
+			status,
+			latency,
+			error,
+			remote_address,
+			fingerprint,
+			polled_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	err := h.db.QueryRow(
+		ctx,
+		query,
+		snapshot.Domain,
+		snapshot.Status,
+		snapshot.Latency,
+		snapshot.Error,
+		snapshot.RemoteAddr,
+		snapshot.Fingerprint,
+		snapshot.PolledAt,
+	).Scan(
+		&snapshot.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetStatusChangesByDomain returns the snapshots where the status differs from the previous poll, newest first.
+func (h *historyRepo) GetStatusChangesByDomain(ctx context.Context, domain string, limit int) ([]*models.PollSnapshot, error) {
+	query := `
+		SELECT
+			id,
+			domain,
+			status,
+			latency,
+			error,
+			remote_address,
+			fingerprint,
+			polled_at
+		FROM (
+			SELECT
+				*,
+				LAG(status) OVER (ORDER BY polled_at) AS prev_status,
+				ROW_NUMBER() OVER (ORDER BY polled_at) AS row_number
+			FROM poll_history
+			WHERE domain = $1
+		) history
+		WHERE row_number = 1 OR status IS DISTINCT FROM prev_status
+		ORDER BY polled_at DESC
+		LIMIT $2
+	`
+	res, err := h.db.Query(ctx, query, domain, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	response := make([]*models.PollSnapshot, 0)
+	for res.Next() {
+		var snapshot models.PollSnapshot
+		err := res.Scan(
+			&snapshot.ID,
+			&snapshot.Domain,
+			&snapshot.Status,
+			&snapshot.Latency,
+			&snapshot.Error,
+			&snapshot.RemoteAddr,
+			&snapshot.Fingerprint,
+			&snapshot.PolledAt,
+		)
+		if err != nil {
+			h.log.Error(err)
+			continue
+		}
+		response = append(response, &snapshot)
+	}
+
+	return response, nil
+}
+
+func (h *historyRepo) DeletePollSnapshotsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM poll_history WHERE polled_at < $1`
+
+	tag, err := h.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// DownsamplePollSnapshots thins out the snapshots older than before, so that per domain only the first snapshot
+// of each bucket and the snapshots where status or certificate changed are kept.
+func (h *historyRepo) DownsamplePollSnapshots(ctx context.Context, before time.Time, bucket time.Duration) (int64, error) {
+	query := `
+		DELETE FROM poll_history WHERE id IN (
+			SELECT id FROM (
+				SELECT
+					id,
+					polled_at,
+					status,
+					fingerprint,
+					LAG(status) OVER (PARTITION BY domain ORDER BY polled_at) AS prev_status,
+					LAG(fingerprint) OVER (PARTITION BY domain ORDER BY polled_at) AS prev_fingerprint,
+					ROW_NUMBER() OVER (
+						PARTITION BY domain, FLOOR(EXTRACT(EPOCH FROM polled_at) / $2)
+						ORDER BY polled_at
+					) AS bucket_row
+				FROM poll_history
+			) history
+			WHERE polled_at < $1
+				AND bucket_row > 1
+				AND status IS NOT DISTINCT FROM prev_status
+				AND fingerprint IS NOT DISTINCT FROM prev_fingerprint
+		)
+	`
+	tag, err := h.db.Exec(ctx, query, before, bucket.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type incidentRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewIncidents(db *pgxpool.Pool, log logger.Logger) models.IncidentStorageI {
+	return &incidentRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+const incidentColumns = `
+	id,
+	user_id,
+	domain,
+	problem,
+	status,
+	ack_token,
+	opened_at,
+	acknowledged_at,
+	snoozed_until,
+	resolved_at,
+	last_notified_at,
+	escalation_policy_id,
+	escalation_step
+`
+
+func scanIncident(row pgx.Row) (*models.Incident, error) {
+	var incident models.Incident
+	err := row.Scan(
+		&incident.ID,
+		&incident.UserID,
+		&incident.Domain,
+		&incident.Problem,
+		&incident.Status,
+		&incident.AckToken,
+		&incident.OpenedAt,
+		&incident.AcknowledgedAt,
+		&incident.SnoozedUntil,
+		&incident.ResolvedAt,
+		&incident.LastNotifiedAt,
+		&incident.EscalationPolicyID,
+		&incident.EscalationStep,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+func (i *incidentRepo) GetUnresolvedIncidents(ctx context.Context, userID int64, domain string) ([]*models.Incident, error) {
+	query := `SELECT ` + incidentColumns + ` FROM incidents WHERE user_id = $1 AND domain = $2 AND status <> 'resolved'`
+
+	rows, err := i.db.Query(ctx, query, userID, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	incidents := make([]*models.Incident, 0)
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, incident)
+	}
+
+	return incidents, rows.Err()
+}
+
+// GetIncidentsByUserID returns the unresolved incidents of the user first, then the latest resolved ones
+func (i *incidentRepo) GetIncidentsByUserID(ctx context.Context, userID int64, limit int) ([]*models.Incident, error) {
+	query := `
+		SELECT ` + incidentColumns + ` FROM incidents WHERE user_id = $1
+		ORDER BY status = 'resolved', opened_at DESC
+		LIMIT $2
+	`
+	rows, err := i.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	incidents := make([]*models.Incident, 0)
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, incident)
+	}
+
+	return incidents, rows.Err()
+}
+
+func (i *incidentRepo) AcknowledgeIncident(ctx context.Context, userID int64, id int64) error {
+	query := `
+		UPDATE incidents SET
+			status = 'acknowledged',
+			acknowledged_at = NOW(),
+			snoozed_until = NULL
+		WHERE id = $1 AND user_id = $2 AND status IN ('open', 'snoozed')
+	`
+	_, err := i.db.Exec(ctx, query, id, userID)
+	return err
+}
+
+// GetIncidentByAckToken returns the incident the token was issued for. It returns pgx.ErrNoRows for an unknown token.
+func (i *incidentRepo) GetIncidentByAckToken(ctx context.Context, token string) (*models.Incident, error) {
+	query := `SELECT ` + incidentColumns + ` FROM incidents WHERE ack_token = $1`
+
+	return scanIncident(i.db.QueryRow(ctx, query, token))
+}
+
+// AcknowledgeIncidentByToken acknowledges the incident the token was issued for. It returns pgx.ErrNoRows for an unknown token.
+func (i *incidentRepo) AcknowledgeIncidentByToken(ctx context.Context, token string) (*models.Incident, error) {
+	query := `
+		UPDATE incidents SET
+			status = CASE WHEN status IN ('open', 'snoozed') THEN 'acknowledged' ELSE status END,
+			acknowledged_at = CASE WHEN status IN ('open', 'snoozed') THEN NOW() ELSE acknowledged_at END,
+			snoozed_until = CASE WHEN status IN ('open', 'snoozed') THEN NULL ELSE snoozed_until END
+		WHERE ack_token = $1
+		RETURNING ` + incidentColumns
+
+	return scanIncident(i.db.QueryRow(ctx, query, token))
+}
+
+func (i *incidentRepo) SnoozeIncident(ctx context.Context, userID int64, id int64, until time.Time) error {
+	query := `
+		UPDATE incidents SET
+			status = 'snoozed',
+			snoozed_until = $1
+		WHERE id = $2 AND user_id = $3 AND status <> 'resolved'
+	`
+	_, err := i.db.Exec(ctx, query, until, id, userID)
+	return err
+}
+
+func (i *incidentRepo) ResolveIncident(ctx context.Context, userID int64, id int64) error {
+	query := `
+		UPDATE incidents SET
+			status = 'resolved',
+			resolved_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status <> 'resolved'
+	`
+	_, err := i.db.Exec(ctx, query, id, userID)
+	return err
+}
+
+// saveIncident inserts a new incident or saves what a poll changed on an existing one.
+// An acknowledgement that came in while the poll ran is kept unless the poll resolved the incident.
+func saveIncident(ctx context.Context, db querier, incident *models.Incident) error {
+	if incident.ID == 0 {
+		query := `
+			INSERT INTO incidents (
+				user_id,
+				domain,
+				problem,
+				status,
+				ack_token,
+				opened_at,
+				last_notified_at,
+				escalation_policy_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`
+		return db.QueryRow(ctx, query, incident.UserID, incident.Domain, incident.Problem, incident.Status, incident.AckToken, incident.OpenedAt, incident.LastNotifiedAt, incident.EscalationPolicyID).Scan(&incident.ID)
+	}
+
+	query := `
+		UPDATE incidents SET
+			status = CASE WHEN status = 'acknowledged' AND $1 <> 'resolved' THEN status ELSE $1 END,
+			snoozed_until = $2,
+			resolved_at = $3,
+			last_notified_at = $4
+		WHERE id = $5 AND status <> 'resolved'
+	`
+	_, err := db.Exec(ctx, query, incident.Status, incident.SnoozedUntil, incident.ResolvedAt, incident.LastNotifiedAt, incident.ID)
+	return err
+}
@@ -2,7 +2,9 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 
 	"github.com/SaidovZohid/certalert.info/pkg/logger"
@@ -108,3 +110,117 @@ func (i *integrationsRepo) LinkTelegramAccountToWebsiteAccount(ctx context.Conte
 
 	return nil
 }
+
+func (i *integrationsRepo) CreateIntegration(ctx context.Context, integration *models.Integration) error {
+	config, err := json.Marshal(integration.Config)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO integrations (
+			user_id,
+			kind,
+			name,
+			config
+		) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return i.db.QueryRow(ctx, query, integration.UserID, integration.Kind, integration.Name, config).Scan(&integration.ID, &integration.CreatedAt)
+}
+
+// GetIntegrationsByUserID returns the integrations of the user, oldest first
+func (i *integrationsRepo) GetIntegrationsByUserID(ctx context.Context, userID int64) ([]*models.Integration, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			kind,
+			name,
+			config,
+			created_at
+		FROM integrations
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+	rows, err := i.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	integrations := make([]*models.Integration, 0)
+	for rows.Next() {
+		integration, err := scanIntegration(rows)
+		if err != nil {
+			return nil, err
+		}
+		integrations = append(integrations, integration)
+	}
+
+	return integrations, rows.Err()
+}
+
+func (i *integrationsRepo) GetIntegrationByID(ctx context.Context, userID int64, id int64) (*models.Integration, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			kind,
+			name,
+			config,
+			created_at
+		FROM integrations
+		WHERE id = $1 AND user_id = $2
+	`
+	return scanIntegration(i.db.QueryRow(ctx, query, id, userID))
+}
+
+// DeleteIntegration deletes the integration of the user and takes it off the destinations of the user's routing rules
+func (i *integrationsRepo) DeleteIntegration(ctx context.Context, userID int64, id int64) error {
+	query := `
+		WITH deleted AS (
+			DELETE FROM integrations WHERE id = $1 AND user_id = $2
+			RETURNING id
+		)
+		UPDATE routing_rules SET integration_ids = array_remove(integration_ids, deleted.id)
+		FROM deleted
+		WHERE routing_rules.user_id = $2 AND deleted.id = ANY(routing_rules.integration_ids)
+	`
+	_, err := i.db.Exec(ctx, query, id, userID)
+	return err
+}
+
+// UpdateIntegrationConfig saves the config of the integration of the user
+func (i *integrationsRepo) UpdateIntegrationConfig(ctx context.Context, integration *models.Integration) error {
+	config, err := json.Marshal(integration.Config)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE integrations SET config = $1 WHERE id = $2 AND user_id = $3`
+	_, err = i.db.Exec(ctx, query, config, integration.ID, integration.UserID)
+	return err
+}
+
+func scanIntegration(row pgx.Row) (*models.Integration, error) {
+	var (
+		integration models.Integration
+		config      []byte
+	)
+	err := row.Scan(
+		&integration.ID,
+		&integration.UserID,
+		&integration.Kind,
+		&integration.Name,
+		&config,
+		&integration.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(config, &integration.Config); err != nil {
+		return nil, err
+	}
+	return &integration, nil
+}
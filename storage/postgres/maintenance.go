@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type maintenanceRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewMaintenance(db *pgxpool.Pool, log logger.Logger) models.MaintenanceStorageI {
+	return &maintenanceRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (m *maintenanceRepo) CreateMaintenanceWindow(ctx context.Context, window *models.MaintenanceWindow) error {
+	query := `
+		INSERT INTO maintenance_windows (
+			user_id,
+			name,
+			domains,
+			tags,
+			starts_at,
+			ends_at,
+			cron,
+			duration,
+			timezone
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+	return m.db.QueryRow(
+		ctx,
+		query,
+		window.UserID,
+		window.Name,
+		window.Domains,
+		window.Tags,
+		window.StartsAt,
+		window.EndsAt,
+		window.Cron,
+		window.Duration,
+		window.Timezone,
+	).Scan(&window.ID, &window.CreatedAt)
+}
+
+func (m *maintenanceRepo) GetMaintenanceWindowsByUserID(ctx context.Context, userID int64) ([]*models.MaintenanceWindow, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			domains,
+			tags,
+			starts_at,
+			ends_at,
+			cron,
+			duration,
+			timezone,
+			created_at
+		FROM maintenance_windows
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+	rows, err := m.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := make([]*models.MaintenanceWindow, 0)
+	for rows.Next() {
+		var window models.MaintenanceWindow
+		err := rows.Scan(
+			&window.ID,
+			&window.UserID,
+			&window.Name,
+			&window.Domains,
+			&window.Tags,
+			&window.StartsAt,
+			&window.EndsAt,
+			&window.Cron,
+			&window.Duration,
+			&window.Timezone,
+			&window.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, &window)
+	}
+
+	return windows, rows.Err()
+}
+
+func (m *maintenanceRepo) DeleteMaintenanceWindow(ctx context.Context, userID int64, id int64) error {
+	query := `DELETE FROM maintenance_windows WHERE id = $1 AND user_id = $2`
+	_, err := m.db.Exec(ctx, query, id, userID)
+	return err
+}
@@ -51,23 +51,47 @@ func (n *notificationRepo) GetNotificationRowByUserID(ctx context.Context, userI
 		user_id,
 		expiry_alerts,
 		change_alerts,
-		before,
+		reminder_days,
 		email_alert,
 		telegram_alert,
 		slack_alert,
 		discord_alert,
-		microsoft_team_alert
+		microsoft_team_alert,
+		webhook_alert,
+		pagerduty_alert,
+		opsgenie_alert,
+		ntfy_alert,
+		gotify_alert,
+		pushover_alert,
+		matrix_alert,
+		sms_alert,
+		change_alert_fields,
+		timezone,
+		quiet_hours_start,
+		quiet_hours_end
 	FROM notifications WHERE user_id=$1`
 	err := n.db.QueryRow(ctx, query, userID).Scan(
 		&notification.UserID,
 		&notification.ExpiryAlerts,
 		&notification.ChangeAlert,
-		&notification.Before,
+		&notification.ReminderDays,
 		&notification.EmailAlert,
 		&notification.TelegramAlert,
 		&notification.SlackAlert,
 		&notification.DiscordAlert,
 		&notification.MicrosoftTeamsAlert,
+		&notification.WebhookAlert,
+		&notification.PagerDutyAlert,
+		&notification.OpsgenieAlert,
+		&notification.NtfyAlert,
+		&notification.GotifyAlert,
+		&notification.PushoverAlert,
+		&notification.MatrixAlert,
+		&notification.SMSAlert,
+		&notification.ChangeAlertFields,
+		&notification.Timezone,
+		&notification.QuietHoursStart,
+		&notification.QuietHoursEnd,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -78,3 +102,37 @@ func (n *notificationRepo) GetNotificationRowByUserID(ctx context.Context, userI
 
 	return &notification, nil
 }
+
+func (n *notificationRepo) UpdateChangeAlertFields(ctx context.Context, userID int64, fields []string) error {
+	query := `UPDATE notifications SET change_alert_fields = $1 WHERE user_id = $2`
+	if _, err := n.db.Exec(ctx, query, fields, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (n *notificationRepo) UpdateReminderDays(ctx context.Context, userID int64, days []int) error {
+	query := `UPDATE notifications SET reminder_days = $1 WHERE user_id = $2`
+	if _, err := n.db.Exec(ctx, query, days, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateQuietHours saves the timezone of the user's alerts and their quiet hours, nil start and end turn the quiet hours off
+func (n *notificationRepo) UpdateQuietHours(ctx context.Context, userID int64, timezone string, start, end *int) error {
+	query := `
+		UPDATE notifications SET
+			timezone = $1,
+			quiet_hours_start = $2,
+			quiet_hours_end = $3
+		WHERE user_id = $4
+	`
+	if _, err := n.db.Exec(ctx, query, timezone, start, end, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,271 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type pollJobRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewPollJobs(db *pgxpool.Pool, log logger.Logger) models.PollJobStorageI {
+	return &pollJobRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// EnqueuePollJobs queues the jobs, skipping domains that already have a pending or leased job.
+// It returns how many jobs were queued.
+func (p *pollJobRepo) EnqueuePollJobs(ctx context.Context, jobs []*models.PollJob) (int64, error) {
+	query := `
+		INSERT INTO poll_jobs (
+			domain,
+			priority,
+			status,
+			max_attempts,
+			run_at,
+			ip,
+			site
+		) VALUES ($1, $2, 'pending', $3, $4, NULLIF($5, ''), NULLIF($6, ''))
+		ON CONFLICT (domain) WHERE status IN ('pending', 'leased') DO NOTHING
+	`
+	batch := &pgx.Batch{}
+	for _, job := range jobs {
+		batch.Queue(query, job.Domain, job.Priority, job.MaxAttempts, job.RunAt, job.IP, job.Site)
+	}
+
+	res := p.db.SendBatch(ctx, batch)
+	defer res.Close()
+
+	var queued int64
+	for range jobs {
+		tag, err := res.Exec()
+		if err != nil {
+			return queued, err
+		}
+		queued += tag.RowsAffected()
+	}
+
+	return queued, nil
+}
+
+// advisory lock classes of the caps, the key is the hash of the IP address or of the registrable domain. Only the leases
+// of one IP address or one site wait for each other, so two workers do not both take its last free slot.
+const (
+	pollIPLock   = 71034825
+	pollSiteLock = 71034826
+)
+
+// how many times a lease looks for another job when the cap of its candidate was taken meanwhile
+const pollLeaseRetries = 3
+
+// errPollCapTaken is returned when another worker took the last free slot of the candidate's IP address or site
+var errPollCapTaken = errors.New("poll cap taken")
+
+// LeasePollJob hands the next runnable job to the worker until the lease runs out. A job whose lease ran out
+// without being completed is runnable again. A job is not runnable while maxPerIP jobs of its IP address or maxPerSite
+// jobs of its registrable domain are leased, by any instance; zero turns a limit off.
+// It returns pgx.ErrNoRows when there is nothing to do.
+func (p *pollJobRepo) LeasePollJob(ctx context.Context, workerID string, lease time.Duration, maxPerIP, maxPerSite int) (*models.PollJob, error) {
+	for i := 0; i < pollLeaseRetries; i++ {
+		job, err := p.leasePollJob(ctx, workerID, lease, maxPerIP, maxPerSite)
+		if errors.Is(err, errPollCapTaken) {
+			continue
+		}
+		return job, err
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// leasePollJob picks the next runnable job under the caps, then locks its IP address and site and counts their leases
+// again, the count of the pick may be stale by then. It returns errPollCapTaken when a cap filled up meanwhile.
+func (p *pollJobRepo) leasePollJob(ctx context.Context, workerID string, lease time.Duration, maxPerIP, maxPerSite int) (*models.PollJob, error) {
+	var (
+		job      models.PollJob
+		id       int64
+		ip, site *string
+	)
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT j.id, j.ip, j.site FROM poll_jobs j
+		WHERE j.attempts < j.max_attempts AND (
+			(j.status = 'pending' AND j.run_at <= NOW()) OR
+			(j.status = 'leased' AND j.lease_until < NOW())
+		) AND (
+			$1 <= 0 OR j.ip IS NULL OR (
+				SELECT COUNT(*) FROM poll_jobs l
+				WHERE l.status = 'leased' AND l.lease_until >= NOW() AND l.ip = j.ip
+			) < $1
+		) AND (
+			$2 <= 0 OR j.site IS NULL OR (
+				SELECT COUNT(*) FROM poll_jobs l
+				WHERE l.status = 'leased' AND l.lease_until >= NOW() AND l.site = j.site
+			) < $2
+		)
+		ORDER BY j.priority DESC, j.run_at
+		FOR UPDATE SKIP LOCKED
+		LIMIT 1
+	`
+	if err := tx.QueryRow(ctx, query, maxPerIP, maxPerSite).Scan(&id, &ip, &site); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, pgx.ErrNoRows
+		}
+		return nil, err
+	}
+
+	// the IP address is locked before the site by every worker, so two leases never wait for each other in a circle
+	if maxPerIP > 0 && ip != nil {
+		if err := lockPollCap(ctx, tx, pollIPLock, "ip", *ip, maxPerIP); err != nil {
+			return nil, err
+		}
+	}
+	if maxPerSite > 0 && site != nil {
+		if err := lockPollCap(ctx, tx, pollSiteLock, "site", *site, maxPerSite); err != nil {
+			return nil, err
+		}
+	}
+
+	query = `
+		UPDATE poll_jobs SET
+			status = 'leased',
+			leased_by = $1,
+			lease_until = NOW() + $2 * INTERVAL '1 second',
+			attempts = attempts + 1
+		WHERE id = $3
+		RETURNING
+			id,
+			domain,
+			priority,
+			ip,
+			site,
+			status,
+			attempts,
+			max_attempts,
+			run_at,
+			leased_by,
+			lease_until,
+			last_error,
+			created_at
+	`
+	err = tx.QueryRow(ctx, query, workerID, lease.Seconds(), id).Scan(
+		&job.ID,
+		&job.Domain,
+		&job.Priority,
+		&ip,
+		&site,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.LeasedBy,
+		&job.LeaseUntil,
+		&job.LastError,
+		&job.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if ip != nil {
+		job.IP = *ip
+	}
+	if site != nil {
+		job.Site = *site
+	}
+
+	return &job, tx.Commit(ctx)
+}
+
+// lockPollCap takes the lock of the IP address or site until the end of the transaction and checks it still has a free
+// slot. The count runs after the lock, so it sees every lease committed by the workers that held the lock before.
+func lockPollCap(ctx context.Context, tx pgx.Tx, class int32, column, key string, max int) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", class, key); err != nil {
+		return err
+	}
+	var leased int
+	query := `SELECT COUNT(*) FROM poll_jobs WHERE status = 'leased' AND lease_until >= NOW() AND ` + column + ` = $1`
+	if err := tx.QueryRow(ctx, query, key).Scan(&leased); err != nil {
+		return err
+	}
+	if leased >= max {
+		return errPollCapTaken
+	}
+	return nil
+}
+
+// CompletePollJob deletes the job, as long as the worker still holds its lease.
+func (p *pollJobRepo) CompletePollJob(ctx context.Context, id int64, workerID string) error {
+	query := `DELETE FROM poll_jobs WHERE id = $1 AND leased_by = $2 AND status = 'leased'`
+
+	_, err := p.db.Exec(ctx, query, id, workerID)
+	return err
+}
+
+// RetryPollJob puts the job back in the queue to run again at runAt.
+func (p *pollJobRepo) RetryPollJob(ctx context.Context, id int64, workerID string, runAt time.Time, lastError string) error {
+	query := `
+		UPDATE poll_jobs SET
+			status = 'pending',
+			leased_by = NULL,
+			lease_until = NULL,
+			run_at = $1,
+			last_error = $2
+		WHERE id = $3 AND leased_by = $4 AND status = 'leased'
+	`
+	_, err := p.db.Exec(ctx, query, runAt, lastError, id, workerID)
+	return err
+}
+
+func (p *pollJobRepo) FailPollJob(ctx context.Context, id int64, workerID string, lastError string) error {
+	query := `
+		UPDATE poll_jobs SET
+			status = 'failed',
+			lease_until = NULL,
+			last_error = $1
+		WHERE id = $2 AND leased_by = $3 AND status = 'leased'
+	`
+	_, err := p.db.Exec(ctx, query, lastError, id, workerID)
+	return err
+}
+
+// FailExhaustedPollJobs marks the jobs whose last lease ran out with no attempts left as failed.
+func (p *pollJobRepo) FailExhaustedPollJobs(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE poll_jobs SET
+			status = 'failed',
+			last_error = COALESCE(last_error, 'lease expired')
+		WHERE status = 'leased' AND lease_until < NOW() AND attempts >= max_attempts
+	`
+	tag, err := p.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (p *pollJobRepo) DeleteFailedPollJobsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM poll_jobs WHERE status = 'failed' AND created_at < $1`
+
+	tag, err := p.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
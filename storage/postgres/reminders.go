@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type reminderRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewReminders(db *pgxpool.Pool, log logger.Logger) models.ReminderStorageI {
+	return &reminderRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// GetSentReminders returns the reminders the user already got about the certificate of the domain
+func (r *reminderRepo) GetSentReminders(ctx context.Context, userID int64, domain string, serial string) ([]*models.SentReminder, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			domain,
+			serial,
+			kind,
+			days,
+			sent_at
+		FROM sent_reminders
+		WHERE user_id = $1 AND domain = $2 AND serial = $3
+	`
+	rows, err := r.db.Query(ctx, query, userID, domain, serial)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := make([]*models.SentReminder, 0)
+	for rows.Next() {
+		var reminder models.SentReminder
+		err := rows.Scan(
+			&reminder.ID,
+			&reminder.UserID,
+			&reminder.Domain,
+			&reminder.Serial,
+			&reminder.Kind,
+			&reminder.Days,
+			&reminder.SentAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, &reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+// saveSentReminder records the reminder, a reminder that is already recorded is left as it is
+func saveSentReminder(ctx context.Context, db querier, reminder *models.SentReminder) error {
+	query := `
+		INSERT INTO sent_reminders (
+			user_id,
+			domain,
+			serial,
+			kind,
+			days,
+			sent_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, domain, serial, kind, days) DO NOTHING
+	`
+	_, err := db.Exec(ctx, query, reminder.UserID, reminder.Domain, reminder.Serial, reminder.Kind, reminder.Days, reminder.SentAt)
+	return err
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type renewalInfoRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewRenewalInfo(db *pgxpool.Pool, log logger.Logger) models.RenewalInfoStorageI {
+	return &renewalInfoRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *renewalInfoRepo) UpsertRenewalInfo(ctx context.Context, info *models.RenewalInfo) error {
+	query := `
+		INSERT INTO renewal_info (
+			domain,
+			cert_id,
+			window_start,
+			window_end,
+			explanation_url,
+			retry_after,
+			checked_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (domain) DO UPDATE SET
+			cert_id = EXCLUDED.cert_id,
+			window_start = EXCLUDED.window_start,
+			window_end = EXCLUDED.window_end,
+			explanation_url = EXCLUDED.explanation_url,
+			retry_after = EXCLUDED.retry_after,
+			checked_at = EXCLUDED.checked_at
+	`
+	_, err := r.db.Exec(
+		ctx,
+		query,
+		info.Domain,
+		info.CertID,
+		info.WindowStart,
+		info.WindowEnd,
+		info.ExplanationURL,
+		info.RetryAfter,
+		info.CheckedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *renewalInfoRepo) GetRenewalInfoByDomain(ctx context.Context, domain string) (*models.RenewalInfo, error) {
+	var info models.RenewalInfo
+
+	query := `
+		SELECT
+			domain,
+			cert_id,
+			window_start,
+			window_end,
+			explanation_url,
+			retry_after,
+			checked_at
+		FROM renewal_info WHERE domain = $1
+	`
+	err := r.db.QueryRow(ctx, query, domain).Scan(
+		&info.Domain,
+		&info.CertID,
+		&info.WindowStart,
+		&info.WindowEnd,
+		&info.ExplanationURL,
+		&info.RetryAfter,
+		&info.CheckedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type routingRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewRouting(db *pgxpool.Pool, log logger.Logger) models.RoutingStorageI {
+	return &routingRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// CreateRoutingRule appends the rule to the end of the user's rules
+func (r *routingRepo) CreateRoutingRule(ctx context.Context, rule *models.RoutingRule) error {
+	query := `
+		INSERT INTO routing_rules (
+			user_id,
+			name,
+			position,
+			domains,
+			tags,
+			alert_types,
+			severities,
+			channels,
+			integration_ids
+		) VALUES (
+			$1,
+			$2,
+			COALESCE((SELECT MAX(position) FROM routing_rules WHERE user_id = $1), 0) + 1,
+			$3, $4, $5, $6, $7, $8
+		)
+		RETURNING id, position, created_at
+	`
+	return r.db.QueryRow(
+		ctx,
+		query,
+		rule.UserID,
+		rule.Name,
+		rule.Domains,
+		rule.Tags,
+		rule.AlertTypes,
+		rule.Severities,
+		rule.Channels,
+		rule.IntegrationIDs,
+	).Scan(&rule.ID, &rule.Position, &rule.CreatedAt)
+}
+
+// GetRoutingRulesByUserID returns the rules of the user in the order they are tried
+func (r *routingRepo) GetRoutingRulesByUserID(ctx context.Context, userID int64) ([]*models.RoutingRule, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			position,
+			domains,
+			tags,
+			alert_types,
+			severities,
+			channels,
+			integration_ids,
+			created_at
+		FROM routing_rules
+		WHERE user_id = $1
+		ORDER BY position, id
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]*models.RoutingRule, 0)
+	for rows.Next() {
+		var rule models.RoutingRule
+		err := rows.Scan(
+			&rule.ID,
+			&rule.UserID,
+			&rule.Name,
+			&rule.Position,
+			&rule.Domains,
+			&rule.Tags,
+			&rule.AlertTypes,
+			&rule.Severities,
+			&rule.Channels,
+			&rule.IntegrationIDs,
+			&rule.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (r *routingRepo) DeleteRoutingRule(ctx context.Context, userID int64, id int64) error {
+	query := `DELETE FROM routing_rules WHERE id = $1 AND user_id = $2`
+	_, err := r.db.Exec(ctx, query, id, userID)
+	return err
+}
+
+// MoveRoutingRule swaps the rule with the one before it, or after it, so it is tried earlier or later.
+// The first rule moved up and the last one moved down stay where they are.
+func (r *routingRepo) MoveRoutingRule(ctx context.Context, userID int64, id int64, up bool) error {
+	query := `
+		WITH rule AS (
+			SELECT id, position FROM routing_rules WHERE id = $1 AND user_id = $2
+		), neighbour AS (
+			SELECT n.id, n.position FROM routing_rules n, rule
+			WHERE n.user_id = $2 AND CASE WHEN $3 THEN n.position < rule.position ELSE n.position > rule.position END
+			ORDER BY CASE WHEN $3 THEN -n.position ELSE n.position END
+			LIMIT 1
+		)
+		UPDATE routing_rules r SET position = CASE WHEN r.id = rule.id THEN neighbour.position ELSE rule.position END
+		FROM rule, neighbour
+		WHERE r.id IN (rule.id, neighbour.id)
+	`
+	_, err := r.db.Exec(ctx, query, id, userID, up)
+	return err
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/SaidovZohid/certalert.info/pkg/logger"
+	"github.com/SaidovZohid/certalert.info/storage/models"
+)
+
+type smsRepo struct {
+	db  *pgxpool.Pool
+	log logger.Logger
+}
+
+func NewSMS(db *pgxpool.Pool, log logger.Logger) models.SMSStorageI {
+	return &smsRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// ReserveSMS counts the message in one statement, so two instances sending at once can't go over the limit together
+func (s *smsRepo) ReserveSMS(ctx context.Context, userID int64, limit int) (bool, error) {
+	if limit <= 0 {
+		return false, nil
+	}
+	query := `
+		INSERT INTO sms_usage (
+			user_id,
+			month,
+			sent
+		) VALUES ($1, date_trunc('month', now() AT TIME ZONE 'UTC')::date, 1)
+		ON CONFLICT (user_id, month) DO UPDATE SET sent = sms_usage.sent + 1
+		WHERE sms_usage.sent < $2
+		RETURNING sent
+	`
+	var sent int
+	if err := s.db.QueryRow(ctx, query, userID, limit).Scan(&sent); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *smsRepo) ReleaseSMS(ctx context.Context, userID int64) error {
+	query := `
+		UPDATE sms_usage SET sent = sent - 1
+		WHERE user_id = $1 AND month = date_trunc('month', now() AT TIME ZONE 'UTC')::date AND sent > 0
+	`
+	if _, err := s.db.Exec(ctx, query, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *smsRepo) GetSMSSentThisMonth(ctx context.Context, userID int64) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(sent), 0) FROM sms_usage
+		WHERE user_id = $1 AND month = date_trunc('month', now() AT TIME ZONE 'UTC')::date
+	`
+	var sent int
+	if err := s.db.QueryRow(ctx, query, userID).Scan(&sent); err != nil {
+		return 0, err
+	}
+	return sent, nil
+}
@@ -98,7 +98,10 @@ func (d *domainRepo) GetDomainWithUserIDAndDomainName(ctx context.Context, domai
 			last_poll_at,
 			latency,
 			error,
-			last_alert_time
+			last_alert_time,
+			reminder_days,
+			tags,
+			snoozed_until
 		FROM tracking_domains WHERE user_id=$1 AND domain=$2
 	`
 	err := d.db.QueryRow(ctx, query, domain.UserID, domain.DomainName).Scan(
@@ -120,6 +123,9 @@ func (d *domainRepo) GetDomainWithUserIDAndDomainName(ctx context.Context, domai
 		&domain.Latency,
 		&domain.Error,
 		&domain.LastAlertTime,
+		&domain.ReminderDays,
+		&domain.Tags,
+		&domain.SnoozedUntil,
 	)
 	if err != nil {
 		return nil, err
@@ -263,7 +269,12 @@ func (d *domainRepo) GetDomainWithUserIDAndDomainID(ctx context.Context, userID
 			status,
 			last_poll_at,
 			latency,
-			error
+			error,
+			check_interval,
+			priority,
+			reminder_days,
+			tags,
+			snoozed_until
 		FROM tracking_domains WHERE user_id=$1 AND id=$2
 	`
 	err := d.db.QueryRow(ctx, query, userID, domainID).Scan(
@@ -285,6 +296,11 @@ func (d *domainRepo) GetDomainWithUserIDAndDomainID(ctx context.Context, userID
 		&domain.LastPollAt,
 		&domain.Latency,
 		&domain.Error,
+		&domain.CheckInterval,
+		&domain.Priority,
+		&domain.ReminderDays,
+		&domain.Tags,
+		&domain.SnoozedUntil,
 	)
 	if err != nil {
 		return nil, err
@@ -293,6 +309,58 @@ func (d *domainRepo) GetDomainWithUserIDAndDomainID(ctx context.Context, userID
 	return &domain, nil
 }
 
+// GetDomainInfoByName returns the last polled info of the domain name, from the most recently polled tracking row
+func (d *domainRepo) GetDomainInfoByName(ctx context.Context, domain string) (*ssl.DomainTracking, error) {
+	query := `
+		SELECT 
+			domain, 
+			remote_address,
+			issuer,
+			signature_algo,
+			public_key_algo,
+			encoded_pem,
+			public_key,
+			signature,
+			dns_names,
+			key_usage,
+			ext_key_usages,
+			issued,
+			expires,
+			status,
+			last_poll_at,
+			latency,
+			error
+		FROM tracking_domains WHERE domain=$1
+		ORDER BY last_poll_at DESC NULLS LAST
+		LIMIT 1
+	`
+	var domainInfo ssl.DomainTracking
+	err := d.db.QueryRow(ctx, query, domain).Scan(
+		&domainInfo.DomainName,
+		&domainInfo.RemoteAddr,
+		&domainInfo.Issuer,
+		&domainInfo.SignatureAlgo,
+		&domainInfo.PublicKeyAlgo,
+		&domainInfo.EncodedPEM,
+		&domainInfo.PublicKey,
+		&domainInfo.Signature,
+		&domainInfo.DNSNames,
+		&domainInfo.KeyUsage,
+		&domainInfo.ExtKeyUsages,
+		&domainInfo.Issued,
+		&domainInfo.Expires,
+		&domainInfo.Status,
+		&domainInfo.LastPollAt,
+		&domainInfo.Latency,
+		&domainInfo.Error,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domainInfo, nil
+}
+
 func (d *domainRepo) GetListofDomainsThatExists(ctx context.Context) ([]*ssl.DomainTracking, error) {
 	query := `
 		SELECT DISTINCT 
@@ -354,6 +422,11 @@ func (d *domainRepo) GetListofDomainsThatExists(ctx context.Context) ([]*ssl.Dom
 }
 
 func (d *domainRepo) UpdateAllTheSameDomainsInfo(ctx context.Context, domainInfo *ssl.DomainTracking) error {
+	return updateAllTheSameDomainsInfo(ctx, d.db, domainInfo)
+}
+
+// updateAllTheSameDomainsInfo is shared with the writes that have to happen in one transaction with it
+func updateAllTheSameDomainsInfo(ctx context.Context, db querier, domainInfo *ssl.DomainTracking) error {
 	query := `UPDATE tracking_domains SET 
 		remote_address = $1,
 		issuer = $2,
@@ -373,7 +446,7 @@ func (d *domainRepo) UpdateAllTheSameDomainsInfo(ctx context.Context, domainInfo
 		issued = $16
 	WHERE domain = $17
 	`
-	_, err := d.db.Exec(ctx, query, domainInfo.RemoteAddr, domainInfo.Issuer, domainInfo.SignatureAlgo, domainInfo.PublicKeyAlgo, domainInfo.EncodedPEM, domainInfo.PublicKey, domainInfo.Signature, domainInfo.DNSNames, domainInfo.KeyUsage, domainInfo.ExtKeyUsages, domainInfo.Expires, domainInfo.Status, domainInfo.LastPollAt, domainInfo.Latency, domainInfo.Error, domainInfo.Issued, domainInfo.DomainName)
+	_, err := db.Exec(ctx, query, domainInfo.RemoteAddr, domainInfo.Issuer, domainInfo.SignatureAlgo, domainInfo.PublicKeyAlgo, domainInfo.EncodedPEM, domainInfo.PublicKey, domainInfo.Signature, domainInfo.DNSNames, domainInfo.KeyUsage, domainInfo.ExtKeyUsages, domainInfo.Expires, domainInfo.Status, domainInfo.LastPollAt, domainInfo.Latency, domainInfo.Error, domainInfo.Issued, domainInfo.DomainName)
 	if err != nil {
 		return err
 	}
@@ -410,13 +483,112 @@ func (d *domainRepo) GetListofUsersThatDomainExists(ctx context.Context, domain
 }
 
 func (d *domainRepo) UpdateTheLastAlertTime(ctx context.Context, userID int64, domain string) error {
+	return updateTheLastAlertTime(ctx, d.db, userID, domain)
+}
+
+func updateTheLastAlertTime(ctx context.Context, db querier, userID int64, domain string) error {
 	query := `
 		UPDATE tracking_domains
 		SET last_alert_time = $1 WHERE user_id = $2 AND domain = $3
 	`
-	if _, err := d.db.Exec(ctx, query, time.Now(), userID, domain); err != nil {
+	if _, err := db.Exec(ctx, query, time.Now(), userID, domain); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *domainRepo) UpdateDomainSchedule(ctx context.Context, userID int64, domainID int64, checkInterval *int, priority string) error {
+	query := `
+		UPDATE tracking_domains
+		SET check_interval = $1, priority = $2 WHERE user_id = $3 AND id = $4
+	`
+	if _, err := d.db.Exec(ctx, query, checkInterval, priority, userID, domainID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateDomainReminderDays saves the reminder schedule of the tracked domain, nil days bring back the owner's schedule
+func (d *domainRepo) UpdateDomainReminderDays(ctx context.Context, userID int64, domainID int64, days []int) error {
+	query := `
+		UPDATE tracking_domains
+		SET reminder_days = $1 WHERE user_id = $2 AND id = $3
+	`
+	if _, err := d.db.Exec(ctx, query, days, userID, domainID); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// UpdateDomainTags replaces the tags of the tracked domain
+func (d *domainRepo) UpdateDomainTags(ctx context.Context, userID int64, domainID int64, tags []string) error {
+	query := `
+		UPDATE tracking_domains
+		SET tags = $1 WHERE user_id = $2 AND id = $3
+	`
+	if _, err := d.db.Exec(ctx, query, tags, userID, domainID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateDomainSnooze snoozes the alerts of the tracked domain until the time, nil wakes them up
+func (d *domainRepo) UpdateDomainSnooze(ctx context.Context, userID int64, domainID int64, until *time.Time) error {
+	query := `
+		UPDATE tracking_domains
+		SET snoozed_until = $1 WHERE user_id = $2 AND id = $3
+	`
+	if _, err := d.db.Exec(ctx, query, until, userID, domainID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetDomainSchedules returns the polling settings of every tracking row, a domain tracked by several users has several rows.
+func (d *domainRepo) GetDomainSchedules(ctx context.Context) ([]*models.DomainSchedule, error) {
+	query := `
+		SELECT
+			td.domain,
+			td.check_interval,
+			td.priority,
+			u.min_check_interval,
+			td.last_poll_at,
+			td.status,
+			td.expires,
+			td.remote_address
+		FROM tracking_domains td
+		JOIN users u ON u.id = td.user_id
+	`
+	res, err := d.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	response := make([]*models.DomainSchedule, 0)
+	for res.Next() {
+		var schedule models.DomainSchedule
+		err := res.Scan(
+			&schedule.DomainName,
+			&schedule.CheckInterval,
+			&schedule.Priority,
+			&schedule.MinCheckInterval,
+			&schedule.LastPollAt,
+			&schedule.Status,
+			&schedule.Expires,
+			&schedule.RemoteAddr,
+		)
+		if err != nil {
+			d.log.Error(err)
+			continue
+		}
+		response = append(response, &schedule)
+	}
+
+	return response, nil
+}
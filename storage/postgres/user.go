@@ -66,6 +66,8 @@ func (u *userRepo) GetUserByEmail(ctx context.Context, email string) (*models.Us
 			password,
 			domains_last_check,
 			max_domains_tracking,
+			min_check_interval,
+			max_sms_per_month,
 			created_at
 		FROM users WHERE email = $1
 	`
@@ -81,6 +83,8 @@ func (u *userRepo) GetUserByEmail(ctx context.Context, email string) (*models.Us
 		&result.Password,
 		&result.LastPollAt,
 		&result.MaxDomainsTracking,
+		&result.MinCheckInterval,
+		&result.MaxSMSPerMonth,
 		&result.CreatedAt,
 	)
 	if err != nil {
@@ -104,6 +108,8 @@ func (u *userRepo) GetUserByID(ctx context.Context, id int64) (*models.User, err
 			password,
 			domains_last_check,
 			max_domains_tracking,
+			min_check_interval,
+			max_sms_per_month,
 			created_at
 		FROM users WHERE id = $1
 	`
@@ -119,6 +125,8 @@ func (u *userRepo) GetUserByID(ctx context.Context, id int64) (*models.User, err
 		&result.Password,
 		&result.LastPollAt,
 		&result.MaxDomainsTracking,
+		&result.MinCheckInterval,
+		&result.MaxSMSPerMonth,
 		&result.CreatedAt,
 	)
 	if err != nil {
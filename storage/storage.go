@@ -13,6 +13,18 @@ type StorageI interface {
 	Domain() models.DomainStorageI
 	Integrations() models.IntegrationsStorageI
 	Notifications() models.NotificationStorageI
+	RenewalInfo() models.RenewalInfoStorageI
+	Certificates() models.CertificateStorageI
+	History() models.HistoryStorageI
+	Changes() models.ChangeStorageI
+	PollJobs() models.PollJobStorageI
+	Alerts() models.AlertStorageI
+	Incidents() models.IncidentStorageI
+	Reminders() models.ReminderStorageI
+	Escalations() models.EscalationStorageI
+	Maintenance() models.MaintenanceStorageI
+	SMS() models.SMSStorageI
+	Routing() models.RoutingStorageI
 }
 
 type StoragePg struct {
@@ -21,6 +33,18 @@ type StoragePg struct {
 	domainRepo    models.DomainStorageI
 	integrations  models.IntegrationsStorageI
 	notifications models.NotificationStorageI
+	renewalInfo   models.RenewalInfoStorageI
+	certificates  models.CertificateStorageI
+	history       models.HistoryStorageI
+	changes       models.ChangeStorageI
+	pollJobs      models.PollJobStorageI
+	alerts        models.AlertStorageI
+	incidents     models.IncidentStorageI
+	reminders     models.ReminderStorageI
+	escalations   models.EscalationStorageI
+	maintenance   models.MaintenanceStorageI
+	sms           models.SMSStorageI
+	routing       models.RoutingStorageI
 }
 
 func NewStoragePg(db *pgxpool.Pool, log logger.Logger) StorageI {
@@ -30,6 +54,18 @@ func NewStoragePg(db *pgxpool.Pool, log logger.Logger) StorageI {
 		domainRepo:    postgres.NewDomain(db, log),
 		integrations:  postgres.NewIntegrations(db, log),
 		notifications: postgres.NewNotifications(db, log),
+		renewalInfo:   postgres.NewRenewalInfo(db, log),
+		certificates:  postgres.NewCertificates(db, log),
+		history:       postgres.NewHistory(db, log),
+		changes:       postgres.NewChanges(db, log),
+		pollJobs:      postgres.NewPollJobs(db, log),
+		alerts:        postgres.NewAlerts(db, log),
+		incidents:     postgres.NewIncidents(db, log),
+		reminders:     postgres.NewReminders(db, log),
+		escalations:   postgres.NewEscalations(db, log),
+		maintenance:   postgres.NewMaintenance(db, log),
+		sms:           postgres.NewSMS(db, log),
+		routing:       postgres.NewRouting(db, log),
 	}
 }
 
@@ -52,3 +88,51 @@ func (s *StoragePg) Integrations() models.IntegrationsStorageI {
 func (s *StoragePg) Notifications() models.NotificationStorageI {
 	return s.notifications
 }
+
+func (s *StoragePg) RenewalInfo() models.RenewalInfoStorageI {
+	return s.renewalInfo
+}
+
+func (s *StoragePg) Certificates() models.CertificateStorageI {
+	return s.certificates
+}
+
+func (s *StoragePg) History() models.HistoryStorageI {
+	return s.history
+}
+
+func (s *StoragePg) Changes() models.ChangeStorageI {
+	return s.changes
+}
+
+func (s *StoragePg) PollJobs() models.PollJobStorageI {
+	return s.pollJobs
+}
+
+func (s *StoragePg) Alerts() models.AlertStorageI {
+	return s.alerts
+}
+
+func (s *StoragePg) Incidents() models.IncidentStorageI {
+	return s.incidents
+}
+
+func (s *StoragePg) Reminders() models.ReminderStorageI {
+	return s.reminders
+}
+
+func (s *StoragePg) Escalations() models.EscalationStorageI {
+	return s.escalations
+}
+
+func (s *StoragePg) Maintenance() models.MaintenanceStorageI {
+	return s.maintenance
+}
+
+func (s *StoragePg) SMS() models.SMSStorageI {
+	return s.sms
+}
+
+func (s *StoragePg) Routing() models.RoutingStorageI {
+	return s.routing
+}
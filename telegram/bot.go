@@ -1,7 +1,11 @@
 package telegram
 
 import (
+	"context"
+	"time"
+
 	"github.com/SaidovZohid/certalert.info/config"
+	"github.com/SaidovZohid/certalert.info/pkg/leader"
 	"github.com/SaidovZohid/certalert.info/pkg/logger"
 	"github.com/SaidovZohid/certalert.info/storage"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -25,18 +29,61 @@ func NewBot(bot *tgbotapi.BotAPI, appLogger *logger.Logger, cfg *config.Config,
 	}
 }
 
-func (b *Bot) Start() error {
+// Start long polls the bot updates until ctx is canceled. Only one instance may long poll at a time,
+// Telegram answers a getUpdates request with 409 Conflict while another one is in flight, so Start returns
+// only once the last long poll is over and the next leader can start its own.
+func (b *Bot) Start(ctx context.Context) error {
 	b.appLogger.Infof("Authired on Account: %v", b.bot.Self.UserName)
 
-	updates := b.initUpdatesChannel()
+	updates := b.initUpdatesChannel(ctx)
+
+	b.handleUpdates(ctx, updates)
 
-	b.handleUpdates(updates)
+	// the channel is closed when the long poll in flight is over
+	for range updates {
+	}
 
 	return nil
 }
 
-func (b *Bot) handleUpdates(updates tgbotapi.UpdatesChannel) {
-	for update := range updates {
+// updatesTimeout is how long a getUpdates long poll waits for updates, in seconds. The request does not stop
+// when ctx is canceled, so it is kept shorter than the leader election retry: the long poll of an instance that
+// lost the leadership with its database session is over before another instance can take it.
+func (b *Bot) updatesTimeout() int {
+	retry := b.cfg.LeaderElectionInterval
+	if retry <= 0 {
+		retry = leader.DefaultRetryInterval
+	}
+	timeout := int(retry / 2 / time.Second)
+	if timeout < 1 {
+		return 1
+	}
+	if timeout > 60 {
+		return 60
+	}
+	return timeout
+}
+
+// handleUpdates handles the updates until the channel is closed or ctx is canceled. Updates the long poll
+// in flight brings after ctx is canceled are not confirmed and come again.
+func (b *Bot) handleUpdates(ctx context.Context, updates tgbotapi.UpdatesChannel) {
+	for {
+		var update tgbotapi.Update
+		select {
+		case <-ctx.Done():
+			b.appLogger.Info("Stopped receiving telegram updates")
+			return
+		case u, ok := <-updates:
+			if !ok {
+				return
+			}
+			update = u
+		}
+
+		if update.CallbackQuery != nil {
+			b.handleCallbackQuery(update)
+			continue
+		}
 		// Check if the update contains the start command
 		if update.Message == nil {
 			continue
@@ -50,11 +97,46 @@ func (b *Bot) handleUpdates(updates tgbotapi.UpdatesChannel) {
 	}
 }
 
-func (b *Bot) initUpdatesChannel() tgbotapi.UpdatesChannel {
+// initUpdatesChannel works like BotAPI.GetUpdatesChan, but stops when ctx is canceled instead of StopReceivingUpdates,
+// which can only be called once, so the long polling can start again when this instance becomes the leader again.
+// The channel is closed once the long poll in flight returns.
+func (b *Bot) initUpdatesChannel(ctx context.Context) tgbotapi.UpdatesChannel {
 	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	u.Timeout = b.updatesTimeout()
+
+	ch := make(chan tgbotapi.Update, b.bot.Buffer)
+
+	go func() {
+		defer close(ch)
+		for ctx.Err() == nil {
+			updates, err := b.bot.GetUpdates(u)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				b.appLogger.Errorf("Failed to get telegram updates: %v", err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(3 * time.Second):
+				}
+				continue
+			}
+
+			for _, update := range updates {
+				if update.UpdateID >= u.Offset {
+					u.Offset = update.UpdateID + 1
+					select {
+					case ch <- update:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
 
-	return b.bot.GetUpdatesChan(u)
+	return ch
 }
 
 func (b *Bot) handleSendTextMessage(chatID int64, message string) {
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"strings"
 
+	"github.com/SaidovZohid/certalert.info/pkg/utils"
 	"github.com/SaidovZohid/certalert.info/storage/models"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/jackc/pgx/v4"
@@ -60,3 +62,41 @@ func (b *Bot) handleCommandsStart(update tgbotapi.Update) bool {
 	}
 	return true
 }
+
+// handleCallbackQuery handles the buttons under the alert messages
+func (b *Bot) handleCallbackQuery(update tgbotapi.Update) {
+	query := update.CallbackQuery
+	if !strings.HasPrefix(query.Data, utils.AckCallbackPrefix) {
+		return
+	}
+
+	answer := engInternalErrorMsg
+	incident, err := b.strg.Incidents().AcknowledgeIncidentByToken(context.Background(), strings.TrimPrefix(query.Data, utils.AckCallbackPrefix))
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		answer = engIncidentNotFound
+	case err != nil:
+		b.appLogger.Errorf("Failed to acknowledge incident %s", err.Error())
+	default:
+		answer = engIncidentAcknowledged
+		if tgUser, err := b.strg.Integrations().GetFromTelegramByUserID(context.Background(), incident.UserID); err == nil {
+			switch tgUser.Lang {
+			case langUz:
+				answer = uzIncidentAcknowledged
+			case langRu:
+				answer = ruIncidentAcknowledged
+			}
+		}
+		// the button has done its job
+		if query.Message != nil {
+			edit := tgbotapi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+			if _, err := b.bot.Request(edit); err != nil {
+				b.appLogger.Errorf("Failed to remove acknowledge button %s", err.Error())
+			}
+		}
+	}
+
+	if _, err := b.bot.Request(tgbotapi.NewCallback(query.ID, answer)); err != nil {
+		b.appLogger.Errorf("Failed to answer callback query %s", err.Error())
+	}
+}
@@ -35,4 +35,9 @@ var (
 	engNotFoundUserID             = "🔍 Sorry, the user was not found. Please try again with a valid user ID. 🔄"
 	engInternalErrorMsg           = "🛑 Apologies, an unexpected error occurred. Please try again later or contact support for assistance. If this issue persists, please reach out to support at @zohid_0212. 🚀"
 	engAlreadyLinkedToThisAccount = "🔗 Your account is already linked. To explore further commands, please use other available options or type /help for assistance. 🚀"
+
+	uzIncidentAcknowledged  = "✅ Qabul qilindi, bu muammo bo'yicha eslatmalar to'xtatildi."
+	ruIncidentAcknowledged  = "✅ Принято, напоминания об этой проблеме остановлены."
+	engIncidentAcknowledged = "✅ Acknowledged, reminders about this problem are stopped."
+	engIncidentNotFound     = "🔍 Sorry, the incident was not found."
 )